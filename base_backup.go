@@ -0,0 +1,538 @@
+package pgx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/ronaldslc/pgx/internal/pgversion"
+	"github.com/ronaldslc/pgx/internal/sanitize"
+	"github.com/ronaldslc/pgx/pgproto3"
+	"github.com/ronaldslc/pgx/pgtype"
+)
+
+// BaseBackupOptions are the options of a BASE_BACKUP command. The zero value
+// takes a backup with the server defaults.
+type BaseBackupOptions struct {
+	Label             string // backup label, the server default is "base backup"
+	Progress          bool   // request the approximate tablespace sizes and, on PostgreSQL 15 and later, progress reports
+	Fast              bool   // request a fast checkpoint instead of a spread one
+	WAL               bool   // include the WAL needed to restore the backup in the main tar stream
+	NoWait            bool   // do not wait for the WAL to be archived
+	MaxRate           int    // maximum transfer rate in kB per second; 0 for no limit
+	TablespaceMap     bool   // include a tablespace_map file instead of symbolic links
+	NoVerifyChecksums bool   // do not verify data checksums (PostgreSQL 11 and later)
+	Manifest          bool   // send a backup manifest after the archives (PostgreSQL 13 and later)
+}
+
+// sql returns the BASE_BACKUP command for a server of major version
+// serverMajor. PostgreSQL 15 replaced the options with a parenthesized list.
+func (opts *BaseBackupOptions) sql(serverMajor int) string {
+	if serverMajor < 15 {
+		return opts.legacySQL()
+	}
+
+	var options []string
+	if opts.Label != "" {
+		options = append(options, "LABEL "+sanitize.QuoteString(opts.Label))
+	}
+	if opts.Progress {
+		options = append(options, "PROGRESS")
+	}
+	if opts.Fast {
+		options = append(options, "CHECKPOINT 'fast'")
+	}
+	if opts.WAL {
+		options = append(options, "WAL")
+	}
+	if opts.NoWait {
+		options = append(options, "WAIT false")
+	}
+	if opts.MaxRate > 0 {
+		options = append(options, fmt.Sprintf("MAX_RATE %d", opts.MaxRate))
+	}
+	if opts.TablespaceMap {
+		options = append(options, "TABLESPACE_MAP")
+	}
+	if opts.NoVerifyChecksums {
+		options = append(options, "VERIFY_CHECKSUMS false")
+	}
+	if opts.Manifest {
+		options = append(options, "MANIFEST 'yes'")
+	}
+
+	if len(options) == 0 {
+		return "BASE_BACKUP"
+	}
+	return "BASE_BACKUP (" + strings.Join(options, ", ") + ")"
+}
+
+func (opts *BaseBackupOptions) legacySQL() string {
+	buf := &bytes.Buffer{}
+	buf.WriteString("BASE_BACKUP")
+
+	if opts.Label != "" {
+		fmt.Fprintf(buf, " LABEL %s", sanitize.QuoteString(opts.Label))
+	}
+	if opts.Progress {
+		buf.WriteString(" PROGRESS")
+	}
+	if opts.Fast {
+		buf.WriteString(" FAST")
+	}
+	if opts.WAL {
+		buf.WriteString(" WAL")
+	}
+	if opts.NoWait {
+		buf.WriteString(" NOWAIT")
+	}
+	if opts.MaxRate > 0 {
+		fmt.Fprintf(buf, " MAX_RATE %d", opts.MaxRate)
+	}
+	if opts.TablespaceMap {
+		buf.WriteString(" TABLESPACE_MAP")
+	}
+	if opts.NoVerifyChecksums {
+		buf.WriteString(" NOVERIFY_CHECKSUMS")
+	}
+	if opts.Manifest {
+		buf.WriteString(" MANIFEST 'yes'")
+	}
+
+	return buf.String()
+}
+
+// BaseBackupTablespace describes a tablespace included in a base backup.
+type BaseBackupTablespace struct {
+	OID      pgtype.OID // 0 for the main data directory
+	Location string     // empty for the main data directory
+	Size     int64      // approximate size in kB, or -1 unless Progress was requested
+}
+
+// BaseBackup is a base backup being streamed from the server. The contents of
+// each tablespace are sent as a tar archive, followed by the backup manifest
+// if it was requested. Use Next to advance to the archive of the next
+// tablespace or the manifest and Read to read it:
+//
+//	bb, err := rc.BaseBackup(&pgx.BaseBackupOptions{Label: "nightly", Fast: true})
+//	if err != nil {
+//		return err
+//	}
+//	defer bb.Close()
+//
+//	for bb.Next() {
+//		if bb.IsManifest() {
+//			// write bb to backup_manifest
+//		}
+//		ts := bb.Tablespace()
+//		// write bb to a tar file named after ts.OID
+//		if _, err := io.Copy(w, bb); err != nil {
+//			return err
+//		}
+//	}
+//	if bb.Err() != nil {
+//		return bb.Err()
+//	}
+//	// bb.EndLSN is the WAL position the backup is consistent at
+//
+// The connection is busy until Next returns false or Close is called.
+type BaseBackup struct {
+	StartLSN      uint64
+	StartTimeline int64
+	Tablespaces   []BaseBackupTablespace
+
+	// EndLSN and EndTimeline are set once Next has returned false without
+	// error.
+	EndLSN      uint64
+	EndTimeline int64
+
+	// Progress is the number of bytes of the backup sent so far as last
+	// reported by the server. It is only reported by PostgreSQL 15 and later
+	// when Progress was requested.
+	Progress int64
+
+	rc           *ReplicationConn
+	stream       bool // the archives are multiplexed in one copy stream (PostgreSQL 15 and later)
+	wantManifest bool
+	idx          int
+	manifest     bool
+	inCopy       bool
+	inArchive    bool
+	pending      []byte
+	next         []byte // copy stream message starting the next archive, read before Next was called
+	err          error
+	closed       bool
+}
+
+// BaseBackup starts a base backup of the server with a BASE_BACKUP command as
+// documented here:
+// https://www.postgresql.org/docs/current/protocol-replication.html
+//
+// If options is nil the server defaults are used. PostgreSQL 14 and earlier
+// send each archive in its own COPY; PostgreSQL 15 and later send all of them
+// in a single COPY. BaseBackup uses the format of the server version.
+func (rc *ReplicationConn) BaseBackup(options *BaseBackupOptions) (*BaseBackup, error) {
+	if options == nil {
+		options = &BaseBackupOptions{}
+	}
+
+	major, err := pgversion.Major(rc.ServerVersion())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := rc.c.lock(); err != nil {
+		return nil, err
+	}
+
+	bb := &BaseBackup{rc: rc, stream: major >= 15, wantManifest: options.Manifest, idx: -1}
+
+	if err := rc.c.sendSimpleQuery(options.sql(major)); err != nil {
+		bb.fatal(err)
+		return nil, err
+	}
+
+	rows, err := bb.readResultSet()
+	if err != nil {
+		bb.fatal(err)
+		return nil, err
+	}
+	if len(rows) != 1 || len(rows[0]) < 2 {
+		err = errors.New("unexpected BASE_BACKUP start position result")
+		rc.c.die(err)
+		bb.fatal(err)
+		return nil, err
+	}
+	if bb.StartLSN, bb.StartTimeline, err = parseLSNTimeline(rows[0]); err != nil {
+		rc.c.die(err)
+		bb.fatal(err)
+		return nil, err
+	}
+
+	rows, err = bb.readResultSet()
+	if err != nil {
+		bb.fatal(err)
+		return nil, err
+	}
+	bb.Tablespaces = make([]BaseBackupTablespace, len(rows))
+	for i, row := range rows {
+		if len(row) < 3 {
+			err = errors.New("unexpected BASE_BACKUP tablespace result")
+			rc.c.die(err)
+			bb.fatal(err)
+			return nil, err
+		}
+		if err = parseBaseBackupTablespace(row, &bb.Tablespaces[i]); err != nil {
+			rc.c.die(err)
+			bb.fatal(err)
+			return nil, err
+		}
+	}
+
+	return bb, nil
+}
+
+func parseLSNTimeline(row [][]byte) (lsn uint64, timeline int64, err error) {
+	if lsn, err = ParseLSN(string(row[0])); err != nil {
+		return 0, 0, err
+	}
+	if timeline, err = strconv.ParseInt(string(row[1]), 10, 64); err != nil {
+		return 0, 0, errors.Wrap(err, "invalid timeline")
+	}
+	return lsn, timeline, nil
+}
+
+func parseBaseBackupTablespace(row [][]byte, ts *BaseBackupTablespace) error {
+	if row[0] != nil {
+		oid, err := strconv.ParseUint(string(row[0]), 10, 32)
+		if err != nil {
+			return errors.Wrap(err, "invalid tablespace oid")
+		}
+		ts.OID = pgtype.OID(oid)
+	}
+
+	ts.Location = string(row[1])
+
+	ts.Size = -1
+	if row[2] != nil {
+		size, err := strconv.ParseInt(string(row[2]), 10, 64)
+		if err != nil {
+			return errors.Wrap(err, "invalid tablespace size")
+		}
+		ts.Size = size
+	}
+
+	return nil
+}
+
+// readResultSet reads the rows of a result set up to its CommandComplete.
+func (bb *BaseBackup) readResultSet() ([][][]byte, error) {
+	var rows [][][]byte
+
+	for {
+		msg, err := bb.rc.c.rxMsg()
+		if err != nil {
+			return nil, err
+		}
+
+		switch msg := msg.(type) {
+		case *pgproto3.RowDescription:
+		case *pgproto3.DataRow:
+			// msg and the values it points to are reused by the next message.
+			row := make([][]byte, len(msg.Values))
+			for i, v := range msg.Values {
+				if v != nil {
+					row[i] = append([]byte{}, v...)
+				}
+			}
+			rows = append(rows, row)
+		case *pgproto3.CommandComplete:
+			return rows, nil
+		case *pgproto3.ReadyForQuery:
+			bb.rc.c.rxReadyForQuery(msg)
+			return nil, errors.New("BASE_BACKUP ended unexpectedly")
+		default:
+			if err := bb.rc.c.processContextFreeMsg(msg); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// fatal records err and releases the connection. Any messages of the backup
+// that were not read yet are discarded before the next query.
+func (bb *BaseBackup) fatal(err error) {
+	if bb.closed {
+		return
+	}
+
+	if bb.err == nil {
+		bb.err = err
+	}
+	bb.inCopy = false
+	bb.inArchive = false
+	bb.closed = true
+	bb.rc.c.unlock()
+}
+
+// Next advances to the tar archive of the next tablespace or to the backup
+// manifest. It returns false when all archives have been read or an error
+// occurred. Any unread data of the current archive is discarded.
+func (bb *BaseBackup) Next() bool {
+	if bb.closed {
+		return false
+	}
+
+	if bb.inArchive {
+		bb.pending = nil
+		if _, err := io.Copy(ioutil.Discard, bb); err != nil {
+			return false
+		}
+	}
+
+	if bb.next != nil {
+		next := bb.next
+		bb.next = nil
+		if _, err := bb.copyStreamData(next); err != nil {
+			bb.rc.c.die(err)
+			bb.fatal(err)
+			return false
+		}
+		return true
+	}
+
+	for {
+		msg, err := bb.rc.c.rxMsg()
+		if err != nil {
+			bb.fatal(err)
+			return false
+		}
+
+		switch msg := msg.(type) {
+		case *pgproto3.CopyOutResponse:
+			bb.inCopy = true
+			if bb.stream {
+				continue
+			}
+
+			if bb.idx+1 < len(bb.Tablespaces) {
+				bb.idx++
+			} else if bb.wantManifest && !bb.manifest {
+				bb.manifest = true
+			} else {
+				err := errors.New("BASE_BACKUP sent more archives than tablespaces")
+				bb.rc.c.die(err)
+				bb.fatal(err)
+				return false
+			}
+			bb.inArchive = true
+			return true
+		case *pgproto3.CopyData:
+			if !bb.stream {
+				err := errors.New("unexpected BASE_BACKUP CopyData outside of an archive")
+				bb.rc.c.die(err)
+				bb.fatal(err)
+				return false
+			}
+			archive, err := bb.copyStreamData(msg.Data)
+			if err != nil {
+				bb.rc.c.die(err)
+				bb.fatal(err)
+				return false
+			}
+			if archive {
+				return true
+			}
+		case *pgproto3.CopyDone:
+			bb.inCopy = false
+		case *pgproto3.RowDescription, *pgproto3.CommandComplete:
+		case *pgproto3.DataRow:
+			if len(msg.Values) < 2 {
+				err := errors.New("unexpected BASE_BACKUP end position result")
+				bb.rc.c.die(err)
+				bb.fatal(err)
+				return false
+			}
+			if bb.EndLSN, bb.EndTimeline, err = parseLSNTimeline(msg.Values); err != nil {
+				bb.rc.c.die(err)
+				bb.fatal(err)
+				return false
+			}
+		case *pgproto3.ReadyForQuery:
+			bb.rc.c.rxReadyForQuery(msg)
+			bb.fatal(nil)
+			return false
+		default:
+			if err := bb.rc.c.processContextFreeMsg(msg); err != nil {
+				bb.fatal(err)
+				return false
+			}
+		}
+	}
+}
+
+// copyStreamData handles a message of the copy stream of PostgreSQL 15 and
+// later. The first byte is the type of the message: 'n' starts the archive of
+// the next tablespace, 'm' starts the backup manifest, 'd' is data of the
+// current archive or manifest and 'p' reports progress. It returns true if
+// the message starts an archive or the manifest.
+func (bb *BaseBackup) copyStreamData(data []byte) (bool, error) {
+	if len(data) == 0 {
+		return false, errors.New("empty BASE_BACKUP copy stream message")
+	}
+
+	switch data[0] {
+	case 'n':
+		bb.idx++
+		if bb.idx >= len(bb.Tablespaces) || bb.manifest {
+			return false, errors.New("BASE_BACKUP sent more archives than tablespaces")
+		}
+		bb.inArchive = true
+		return true, nil
+	case 'm':
+		if bb.manifest {
+			return false, errors.New("BASE_BACKUP sent more than one manifest")
+		}
+		bb.manifest = true
+		bb.inArchive = true
+		return true, nil
+	case 'd':
+		if !bb.inArchive {
+			return false, errors.New("BASE_BACKUP sent data outside of an archive")
+		}
+		bb.pending = data[1:]
+		return false, nil
+	case 'p':
+		if len(data) != 9 {
+			return false, errors.New("invalid BASE_BACKUP progress message")
+		}
+		bb.Progress = int64(binary.BigEndian.Uint64(data[1:]))
+		return false, nil
+	default:
+		return false, errors.Errorf("unknown BASE_BACKUP copy stream message type: %c", data[0])
+	}
+}
+
+// Tablespace returns the tablespace of the current archive. It returns the
+// zero value while the manifest is read.
+func (bb *BaseBackup) Tablespace() BaseBackupTablespace {
+	if bb.manifest || bb.idx < 0 || bb.idx >= len(bb.Tablespaces) {
+		return BaseBackupTablespace{}
+	}
+	return bb.Tablespaces[bb.idx]
+}
+
+// IsManifest returns true if Read reads the backup manifest instead of the
+// archive of a tablespace.
+func (bb *BaseBackup) IsManifest() bool {
+	return bb.manifest
+}
+
+// Read reads the tar archive of the current tablespace or the manifest. It
+// returns io.EOF at the end of the archive.
+func (bb *BaseBackup) Read(p []byte) (int, error) {
+	for len(bb.pending) == 0 {
+		if !bb.inArchive {
+			if bb.err != nil {
+				return 0, bb.err
+			}
+			return 0, io.EOF
+		}
+
+		msg, err := bb.rc.c.rxMsg()
+		if err != nil {
+			bb.fatal(err)
+			return 0, err
+		}
+
+		switch msg := msg.(type) {
+		case *pgproto3.CopyData:
+			if !bb.stream {
+				bb.pending = msg.Data
+				continue
+			}
+
+			if len(msg.Data) > 0 && (msg.Data[0] == 'n' || msg.Data[0] == 'm') {
+				// The current archive ended. msg is reused by the next message.
+				bb.next = append([]byte{}, msg.Data...)
+				bb.inArchive = false
+				continue
+			}
+			if _, err := bb.copyStreamData(msg.Data); err != nil {
+				bb.rc.c.die(err)
+				bb.fatal(err)
+				return 0, err
+			}
+		case *pgproto3.CopyDone:
+			bb.inCopy = false
+			bb.inArchive = false
+		default:
+			if err := bb.rc.c.processContextFreeMsg(msg); err != nil {
+				bb.fatal(err)
+				return 0, err
+			}
+		}
+	}
+
+	n := copy(p, bb.pending)
+	bb.pending = bb.pending[n:]
+	return n, nil
+}
+
+// Err returns any error that occurred while streaming the backup.
+func (bb *BaseBackup) Err() error {
+	return bb.err
+}
+
+// Close discards the remainder of the backup and releases the connection.
+func (bb *BaseBackup) Close() error {
+	for bb.Next() {
+	}
+	return bb.err
+}
@@ -23,6 +23,7 @@ type Batch struct {
 	sent        bool
 	ctx         context.Context
 	err         error
+	traceCtx    context.Context // set while the batch is traced
 }
 
 // BeginBatch returns a *Batch query for c.
@@ -55,6 +56,15 @@ func (b *Batch) Send(ctx context.Context, txOptions *TxOptions) error {
 		return b.err
 	}
 
+	if tracer, ok := b.conn.config.Tracer.(BatchTracer); ok {
+		queries := make([]TraceQueryStartData, len(b.items))
+		for i, bi := range b.items {
+			queries[i] = TraceQueryStartData{SQL: bi.query, Args: bi.arguments}
+		}
+		ctx = tracer.TraceBatchStart(ctx, b.conn, TraceBatchStartData{Queries: queries})
+		b.traceCtx = ctx
+	}
+
 	b.ctx = ctx
 
 	err := b.conn.waitForPreviousCancelQuery(ctx)
@@ -82,7 +92,7 @@ func (b *Batch) Send(ctx context.Context, txOptions *TxOptions) error {
 			psParameterOIDs = ps.ParameterOIDs
 		} else {
 			if st, ok := b.conn.config.LazyPreparedStatements[bi.query]; ok {
-				ps, err := b.conn.prepareEx(bi.query, st, nil)
+				ps, err := b.conn.prepareEx(ctx, bi.query, st, nil)
 				if err != nil {
 					return err
 				}
@@ -167,6 +177,10 @@ func (b *Batch) ExecResults() (CommandTag, error) {
 			return CommandTag(msg.CommandTag), nil
 		default:
 			if err := b.conn.processContextFreeMsg(msg); err != nil {
+				if b.resultsRead <= len(b.items) {
+					item := b.items[b.resultsRead-1]
+					err = b.conn.addErrorContext(err, item.query, item.arguments)
+				}
 				return "", err
 			}
 		}
@@ -176,18 +190,27 @@ func (b *Batch) ExecResults() (CommandTag, error) {
 // QueryResults reads the results from the next query in the batch as if the
 // query has been sent with Query.
 func (b *Batch) QueryResults() (*Rows, error) {
-	rows := b.conn.getRows(0, "batch query", nil)
+	sql, args := "batch query", []interface{}(nil)
+	if b.resultsRead < len(b.items) {
+		sql, args = b.items[b.resultsRead].query, b.items[b.resultsRead].arguments
+	}
+	rows := b.conn.getRows(0, sql, args)
+	if b.ctx != nil {
+		rows.ctx = b.ctx
+	}
 
+	// rows.err has the SQL and arguments of the query added to the error, so
+	// it is returned instead of the error itself.
 	if b.err != nil {
 		rows.fatal(b.err)
-		return rows, b.err
+		return rows, rows.err
 	}
 
 	select {
 	case <-b.ctx.Done():
 		b.die(b.ctx.Err())
 		rows.fatal(b.err)
-		return rows, b.ctx.Err()
+		return rows, rows.err
 	default:
 	}
 
@@ -197,7 +220,7 @@ func (b *Batch) QueryResults() (*Rows, error) {
 	if err != nil {
 		b.die(err)
 		rows.fatal(b.err)
-		return rows, err
+		return rows, rows.err
 	}
 
 	rows.batch = b
@@ -223,6 +246,7 @@ func (b *Batch) Close() (err error) {
 
 	defer func() {
 		err = b.conn.termContext(err)
+		b.traceEnd(err)
 		if b.conn != nil && b.connPool != nil {
 			b.connPool.Release(b.conn)
 		}
@@ -248,8 +272,20 @@ func (b *Batch) die(err error) {
 
 	b.err = err
 	b.conn.die(err)
+	b.traceEnd(err)
 
 	if b.conn != nil && b.connPool != nil {
 		b.connPool.Release(b.conn)
 	}
 }
+
+// traceEnd notifies the BatchTracer that the batch has ended. Only the first
+// call has an effect.
+func (b *Batch) traceEnd(err error) {
+	if b.traceCtx == nil {
+		return
+	}
+
+	b.conn.config.Tracer.(BatchTracer).TraceBatchEnd(b.traceCtx, b.conn, TraceBatchEndData{Err: err})
+	b.traceCtx = nil
+}
@@ -0,0 +1,149 @@
+package pgx
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"io"
+	"net"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// cancelRequestCode is the protocol version number of a CancelRequest.
+const cancelRequestCode = 80877102
+
+// CancelKey identifies a backend to the server for the purpose of canceling
+// its current query. It is the backend PID and the secret key the server sent
+// at connection startup. A CancelKey can be stored or passed to another
+// process and used with its Cancel method to cancel whatever the backend is
+// running at that time, as long as the connection is open.
+//
+// The SecretKey should be treated like a password. Anybody with the key and
+// network access to the server can cancel the queries of the backend.
+type CancelKey struct {
+	PID       uint32
+	SecretKey uint32
+}
+
+// CancelKey returns the key that can be used to cancel queries running on c.
+func (c *Conn) CancelKey() CancelKey {
+	return CancelKey{PID: c.pid, SecretKey: c.secretKey}
+}
+
+// CancelRequest asks the server to cancel the query currently running on c.
+// Unlike other methods of Conn it is safe to call from another goroutine
+// while c is in use. The request is sent on a separate connection. The
+// query method running on c then fails with a PgError with the code 57014
+// (query_canceled) and c remains usable.
+//
+// It returns an error if unable to deliver the cancel request, but lack of an
+// error does not ensure that the query was canceled. The server may have
+// finished the query before it received the request, or it may cancel a
+// later query if the request arrives late. See
+// https://www.postgresql.org/docs/current/static/protocol-flow.html#AEN112861
+func (c *Conn) CancelRequest(ctx context.Context) error {
+	if !c.IsAlive() {
+		return ErrDeadConn
+	}
+
+	return sendCancelRequest(ctx, &c.config, c.CancelKey())
+}
+
+// Cancel asks the server described by config to cancel the query currently
+// running on the backend identified by k. config is only used to establish a
+// connection to the server, so Database, User and Password do not matter. See
+// Conn.CancelRequest for the guarantees of a cancel request.
+func (k CancelKey) Cancel(ctx context.Context, config ConnConfig) error {
+	if config.Port == 0 {
+		config.Port = 5432
+	}
+	if config.Dial == nil {
+		config.Dial = (&net.Dialer{KeepAlive: 5 * time.Minute}).Dial
+	}
+
+	return sendCancelRequest(ctx, &config, k)
+}
+
+// defaultCancelRequestTimeout is used when ConnConfig.CancelRequestTimeout is
+// not set.
+const defaultCancelRequestTimeout = 15 * time.Second
+
+// sendCancelRequest sends a CancelRequest for key to the server of config.
+// The cancel connection negotiates TLS the same way as a regular connection,
+// including the fallback to FallbackTLSConfig.
+func sendCancelRequest(ctx context.Context, config *ConnConfig, key CancelKey) error {
+	// If server doesn't process cancellation request in bounded time then abort.
+	timeout := config.CancelRequestTimeout
+	if timeout == 0 {
+		timeout = defaultCancelRequestTimeout
+	}
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+
+	err := sendCancelRequestTLS(ctx, config, config.TLSConfig, deadline, key)
+	if err != nil && config.UseFallbackTLS && ctx.Err() == nil {
+		err = sendCancelRequestTLS(ctx, config, config.FallbackTLSConfig, deadline, key)
+	}
+	return err
+}
+
+func sendCancelRequestTLS(ctx context.Context, config *ConnConfig, tlsConfig *tls.Config, deadline time.Time, key CancelKey) error {
+	network, address := config.networkAddress()
+	netConn, err := config.Dial(network, address)
+	if err != nil {
+		return err
+	}
+	defer netConn.Close()
+
+	if err := netConn.SetDeadline(deadline); err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			netConn.SetDeadline(time.Now())
+		case <-done:
+		}
+	}()
+
+	cancelConn := netConn
+	if tlsConfig != nil {
+		tlsConn, err := negotiateTLS(netConn, tlsConfig)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		defer tlsConn.Close()
+		cancelConn = tlsConn
+	}
+
+	buf := make([]byte, 16)
+	binary.BigEndian.PutUint32(buf[0:4], 16)
+	binary.BigEndian.PutUint32(buf[4:8], cancelRequestCode)
+	binary.BigEndian.PutUint32(buf[8:12], key.PID)
+	binary.BigEndian.PutUint32(buf[12:16], key.SecretKey)
+	if _, err := cancelConn.Write(buf); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+
+	if _, err := cancelConn.Read(buf); err != io.EOF {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return errors.Errorf("Server failed to close connection after cancel query request: %v %v", err, buf)
+	}
+
+	return nil
+}
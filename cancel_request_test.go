@@ -0,0 +1,198 @@
+package pgx_test
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ronaldslc/pgx"
+)
+
+func TestConnCancelRequest(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnect(t, *defaultConnConfig)
+	defer closeConn(t, conn)
+
+	errChan := make(chan error, 1)
+	go func() {
+		time.Sleep(500 * time.Millisecond)
+		errChan <- conn.CancelRequest(context.Background())
+	}()
+
+	_, err := conn.Exec("select pg_sleep(60)")
+	if pgErr, ok := err.(pgx.PgError); !ok || pgErr.Code != "57014" {
+		t.Fatalf("Expected query_canceled error, got %v", err)
+	}
+
+	if err := <-errChan; err != nil {
+		t.Fatal(err)
+	}
+
+	ensureConnValid(t, conn)
+}
+
+func TestCancelKeyCancel(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnect(t, *defaultConnConfig)
+	defer closeConn(t, conn)
+
+	key := conn.CancelKey()
+	if key.PID != conn.PID() {
+		t.Fatalf("Expected PID %d, got %d", conn.PID(), key.PID)
+	}
+
+	// The key is used without the connection, as another process would.
+	errChan := make(chan error, 1)
+	go func() {
+		time.Sleep(500 * time.Millisecond)
+		errChan <- key.Cancel(context.Background(), *defaultConnConfig)
+	}()
+
+	_, err := conn.Exec("select pg_sleep(60)")
+	if pgErr, ok := err.(pgx.PgError); !ok || pgErr.Code != "57014" {
+		t.Fatalf("Expected query_canceled error, got %v", err)
+	}
+
+	if err := <-errChan; err != nil {
+		t.Fatal(err)
+	}
+
+	ensureConnValid(t, conn)
+}
+
+func TestConnCancelRequestDeadConn(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnect(t, *defaultConnConfig)
+	closeConn(t, conn)
+
+	if err := conn.CancelRequest(context.Background()); err != pgx.ErrDeadConn {
+		t.Fatalf("Expected ErrDeadConn, got %v", err)
+	}
+}
+
+// acceptCancelRequests accepts connections on ln. If refuseTLS is set the
+// first connection must be an SSLRequest, which is refused. The cancel key of
+// the following CancelRequest is sent on the returned channel.
+func acceptCancelRequests(t *testing.T, ln net.Listener, refuseTLS bool) <-chan pgx.CancelKey {
+	keys := make(chan pgx.CancelKey, 1)
+
+	go func() {
+		if refuseTLS {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			buf := make([]byte, 8)
+			if _, err := io.ReadFull(conn, buf); err != nil || binary.BigEndian.Uint32(buf[4:8]) != 80877103 {
+				t.Errorf("Expected SSLRequest, got %v %v", buf, err)
+			}
+			conn.Write([]byte{'N'})
+			conn.Close()
+		}
+
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		buf := make([]byte, 16)
+		if _, err := io.ReadFull(conn, buf); err != nil || binary.BigEndian.Uint32(buf[4:8]) != 80877102 {
+			t.Errorf("Expected CancelRequest, got %v %v", buf, err)
+			return
+		}
+		keys <- pgx.CancelKey{PID: binary.BigEndian.Uint32(buf[8:12]), SecretKey: binary.BigEndian.Uint32(buf[12:16])}
+	}()
+
+	return keys
+}
+
+func listenerConnConfig(ln net.Listener) pgx.ConnConfig {
+	addr := ln.Addr().(*net.TCPAddr)
+	return pgx.ConnConfig{Host: addr.IP.String(), Port: uint16(addr.Port)}
+}
+
+func TestCancelKeyCancelFallbackTLS(t *testing.T) {
+	t.Parallel()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	keys := acceptCancelRequests(t, ln, true)
+
+	config := listenerConnConfig(ln)
+	config.TLSConfig = &tls.Config{InsecureSkipVerify: true}
+	config.UseFallbackTLS = true
+
+	key := pgx.CancelKey{PID: 42, SecretKey: 1234}
+	if err := key.Cancel(context.Background(), config); err != nil {
+		t.Fatal(err)
+	}
+
+	if received := <-keys; received != key {
+		t.Errorf("Expected %v, got %v", key, received)
+	}
+}
+
+func TestCancelKeyCancelTLSRefused(t *testing.T) {
+	t.Parallel()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	acceptCancelRequests(t, ln, true)
+
+	config := listenerConnConfig(ln)
+	config.TLSConfig = &tls.Config{InsecureSkipVerify: true}
+
+	key := pgx.CancelKey{PID: 42, SecretKey: 1234}
+	if err := key.Cancel(context.Background(), config); err != pgx.ErrTLSRefused {
+		t.Fatalf("Expected ErrTLSRefused, got %v", err)
+	}
+}
+
+func TestCancelKeyCancelTimeout(t *testing.T) {
+	t.Parallel()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	// Accept the connection but never close it.
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		io.Copy(ioutil.Discard, conn)
+	}()
+
+	config := listenerConnConfig(ln)
+	config.CancelRequestTimeout = 100 * time.Millisecond
+
+	startTime := time.Now()
+	key := pgx.CancelKey{PID: 42, SecretKey: 1234}
+	if err := key.Cancel(context.Background(), config); err == nil {
+		t.Fatal("Expected timeout error")
+	}
+	if elapsed := time.Since(startTime); elapsed > 5*time.Second {
+		t.Errorf("Expected cancel request to time out quickly, took %v", elapsed)
+	}
+}
@@ -0,0 +1,142 @@
+package pgx
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// CheckpointStore persists the WAL position a replication consumer has
+// processed so it can resume from there after a restart.
+type CheckpointStore interface {
+	// Load returns the last saved position or 0 if none was saved yet.
+	Load() (uint64, error)
+
+	// Save durably records lsn as processed.
+	Save(lsn uint64) error
+}
+
+// FileCheckpointStore is a CheckpointStore that keeps the position in a file.
+// The file is replaced atomically on each Save.
+type FileCheckpointStore struct {
+	path string
+}
+
+// NewFileCheckpointStore returns a FileCheckpointStore that keeps the
+// position in the file at path.
+func NewFileCheckpointStore(path string) *FileCheckpointStore {
+	return &FileCheckpointStore{path: path}
+}
+
+// Load reads the position from the file. It returns 0 if the file does not
+// exist.
+func (s *FileCheckpointStore) Load() (uint64, error) {
+	buf, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	lsn, err := ParseLSN(strings.TrimSpace(string(buf)))
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid checkpoint in %s", s.path)
+	}
+	return lsn, nil
+}
+
+// Save writes lsn to a temporary file, syncs it and renames it over the
+// checkpoint file.
+func (s *FileCheckpointStore) Save(lsn uint64) error {
+	tmpPath := s.path + ".tmp"
+
+	f, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintln(f, FormatLSN(lsn)); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		return err
+	}
+
+	// Sync the directory so the rename survives a crash. This is not possible
+	// on all platforms so errors are ignored.
+	if dir, err := os.Open(filepath.Dir(s.path)); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+
+	return nil
+}
+
+// TableCheckpointStore is a CheckpointStore that keeps the position in a
+// table with one row per replication slot. Keeping the position in the same
+// database as the consumer's side effects allows both to be written in one
+// transaction with SaveIn, so that the saved position never runs ahead of or
+// behind the changes that were applied. Replication still delivers at least
+// once, see ReplicationConn.Consume.
+//
+// The table is created by CreateTable and has the columns slot_name and lsn.
+// TableCheckpointStore requires PostgreSQL 9.5 or later.
+type TableCheckpointStore struct {
+	conn      IConn
+	tableName Identifier
+	slotName  string
+}
+
+// NewTableCheckpointStore returns a TableCheckpointStore that keeps the
+// position of slotName in tableName. conn is used by Load and Save. It must be
+// a regular connection, not a replication connection.
+func NewTableCheckpointStore(conn IConn, tableName Identifier, slotName string) *TableCheckpointStore {
+	return &TableCheckpointStore{conn: conn, tableName: tableName, slotName: slotName}
+}
+
+// CreateTable creates the checkpoint table if it does not exist.
+func (s *TableCheckpointStore) CreateTable() error {
+	_, err := s.conn.Exec(fmt.Sprintf("create table if not exists %s (slot_name text primary key, lsn text not null)", s.tableName.Sanitize()))
+	return err
+}
+
+// Load returns the position saved for the slot or 0 if none was saved.
+func (s *TableCheckpointStore) Load() (uint64, error) {
+	var lsn string
+	err := s.conn.QueryRow(fmt.Sprintf("select lsn from %s where slot_name = $1", s.tableName.Sanitize()), s.slotName).Scan(&lsn)
+	if err == ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return ParseLSN(lsn)
+}
+
+// Save records lsn for the slot with the conn of the store.
+func (s *TableCheckpointStore) Save(lsn uint64) error {
+	return s.SaveIn(s.conn, lsn)
+}
+
+// SaveIn records lsn for the slot with conn, which is usually the *Tx that
+// also applies the changes up to lsn.
+func (s *TableCheckpointStore) SaveIn(conn IConn, lsn uint64) error {
+	_, err := conn.Exec(fmt.Sprintf(`insert into %s (slot_name, lsn) values ($1, $2)
+on conflict (slot_name) do update set lsn = excluded.lsn`, s.tableName.Sanitize()), s.slotName, FormatLSN(lsn))
+	return err
+}
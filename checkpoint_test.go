@@ -0,0 +1,117 @@
+package pgx_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ronaldslc/pgx"
+)
+
+func TestFileCheckpointStore(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "pgx_checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := pgx.NewFileCheckpointStore(filepath.Join(dir, "checkpoint"))
+
+	lsn, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if lsn != 0 {
+		t.Errorf("Expected 0 before first Save, but got %s", pgx.FormatLSN(lsn))
+	}
+
+	for _, expected := range []uint64{0x16B3748, 0x1000016B3748} {
+		if err := store.Save(expected); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+
+		lsn, err = pgx.NewFileCheckpointStore(filepath.Join(dir, "checkpoint")).Load()
+		if err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+		if lsn != expected {
+			t.Errorf("Expected %s, but got %s", pgx.FormatLSN(expected), pgx.FormatLSN(lsn))
+		}
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "checkpoint"), []byte("garbage"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Load(); err == nil {
+		t.Error("Expected error loading invalid checkpoint")
+	}
+}
+
+func TestTableCheckpointStore(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnect(t, *defaultConnConfig)
+	defer closeConn(t, conn)
+
+	mustExec(t, conn, "drop table if exists pgx_checkpoint_test")
+	defer mustExec(t, conn, "drop table pgx_checkpoint_test")
+
+	store := pgx.NewTableCheckpointStore(conn, pgx.Identifier{"pgx_checkpoint_test"}, "slot_a")
+	if err := store.CreateTable(); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+
+	lsn, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if lsn != 0 {
+		t.Errorf("Expected 0 before first Save, but got %s", pgx.FormatLSN(lsn))
+	}
+
+	if err := store.Save(0x1000); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	// A checkpoint saved in a transaction that is rolled back is not kept
+	tx, err := conn.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveIn(tx, 0x2000); err != nil {
+		t.Fatalf("SaveIn failed: %v", err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+
+	if lsn, err = store.Load(); err != nil || lsn != 0x1000 {
+		t.Errorf("Expected 0/1000 after rollback, but got %s %v", pgx.FormatLSN(lsn), err)
+	}
+
+	tx, err = conn.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveIn(tx, 0x3000); err != nil {
+		t.Fatalf("SaveIn failed: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	if lsn, err = store.Load(); err != nil || lsn != 0x3000 {
+		t.Errorf("Expected 0/3000 after commit, but got %s %v", pgx.FormatLSN(lsn), err)
+	}
+
+	// Slots are independent
+	other := pgx.NewTableCheckpointStore(conn, pgx.Identifier{"pgx_checkpoint_test"}, "slot_b")
+	if lsn, err = other.Load(); err != nil || lsn != 0 {
+		t.Errorf("Expected 0 for other slot, but got %s %v", pgx.FormatLSN(lsn), err)
+	}
+
+	ensureConnValid(t, conn)
+}
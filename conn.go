@@ -81,11 +81,23 @@ type ConnConfig struct {
 	UseFallbackTLS         bool        // Try FallbackTLSConfig if connecting with TLSConfig fails. Used for preferring TLS, but allowing unencrypted, or vice-versa
 	FallbackTLSConfig      *tls.Config // config for fallback TLS connection (only used if UseFallBackTLS is true)-- nil disables TLS
 	Logger                 Logger
+	ContextLogger          ContextLogger // takes precedence over Logger
 	LogLevel               int
+	LogArgs                *LogArgsPolicy       // how query SQL and arguments are logged -- nil logs all arguments truncated to 64 bytes
+	SlowQueryThreshold     time.Duration        // queries that take at least this long are logged at LogLevelWarn instead of LogLevelInfo -- 0 disables
+	QueryStats             *QueryStatsCollector // collects per statement latency statistics -- nil disables
 	Dial                   DialFunc
 	RuntimeParams          map[string]string // Run-time parameters to set on connection as session default values (e.g. search_path or application_name)
 	OnNotice               NoticeHandler     // Callback function called when a notice response is received.
 	LazyPreparedStatements map[string]string // a map of lazy prepared statements
+
+	// Tracer is notified of the start and end of queries and other operations.
+	// See Tracer for details.
+	Tracer Tracer
+
+	// CancelRequestTimeout bounds the time a cancel request waits for the
+	// server to process it and close the cancel connection. default: 15s
+	CancelRequestTimeout time.Duration
 }
 
 func (cc *ConnConfig) networkAddress() (network, address string) {
@@ -123,6 +135,7 @@ type Conn struct {
 	channels               map[string]struct{}
 	notifications          []*Notification
 	logger                 Logger
+	contextLogger          ContextLogger
 	logLevel               int
 	fp                     *fastpath
 	poolResetCount         int
@@ -239,10 +252,24 @@ func (e ProtocolError) Error() string {
 // config.Host must be specified. config.User will default to the OS user name.
 // Other config fields are optional.
 func Connect(config ConnConfig) (c *Conn, err error) {
-	return connect(config, minimalConnInfo)
+	return connect(context.Background(), config, minimalConnInfo)
+}
+
+// ConnectEx is the same as Connect, but passes ctx to the ConnectTracer and
+// ContextLogger of config. ctx does not cancel the connection attempt, use
+// config.Dial with a timeout for that.
+func ConnectEx(ctx context.Context, config ConnConfig) (c *Conn, err error) {
+	return connect(ctx, config, minimalConnInfo)
 }
 
-func connect(config ConnConfig, connInfo *pgtype.ConnInfo) (c *Conn, err error) {
+func connect(ctx context.Context, config ConnConfig, connInfo *pgtype.ConnInfo) (c *Conn, err error) {
+	if tracer, ok := config.Tracer.(ConnectTracer); ok {
+		ctx = tracer.TraceConnectStart(ctx, TraceConnectStartData{ConnConfig: &config})
+		defer func() {
+			tracer.TraceConnectEnd(ctx, TraceConnectEndData{Conn: c, Err: err})
+		}()
+	}
+
 	c = new(Conn)
 
 	c.config = config
@@ -255,6 +282,7 @@ func connect(config ConnConfig, connInfo *pgtype.ConnInfo) (c *Conn, err error)
 		c.logLevel = LogLevelDebug
 	}
 	c.logger = c.config.Logger
+	c.contextLogger = c.config.ContextLogger
 
 	if c.config.User == "" {
 		user, err := user.Current()
@@ -265,7 +293,7 @@ func connect(config ConnConfig, connInfo *pgtype.ConnInfo) (c *Conn, err error)
 		if c.shouldLog(LogLevelDebug) {
 			var ld LogData
 			ld.Add("User", c.config.User)
-			c.log(LogLevelDebug, "Using default connection config", ld)
+			c.log(ctx, LogLevelDebug, "Using default connection config", ld)
 		}
 	}
 
@@ -274,7 +302,7 @@ func connect(config ConnConfig, connInfo *pgtype.ConnInfo) (c *Conn, err error)
 		if c.shouldLog(LogLevelDebug) {
 			var ld LogData
 			ld.Add("Port", c.config.Port)
-			c.log(LogLevelDebug, "Using default connection config", ld)
+			c.log(ctx, LogLevelDebug, "Using default connection config", ld)
 		}
 	}
 
@@ -289,23 +317,23 @@ func connect(config ConnConfig, connInfo *pgtype.ConnInfo) (c *Conn, err error)
 		var ld LogData
 		ld.Add("network", network)
 		ld.Add("address", address)
-		c.log(LogLevelInfo, "Dialing PostgreSQL server", ld)
+		c.log(ctx, LogLevelInfo, "Dialing PostgreSQL server", ld)
 	}
-	err = c.connect(config, network, address, config.TLSConfig)
+	err = c.connect(ctx, config, network, address, config.TLSConfig)
 	if err != nil && config.UseFallbackTLS {
 		if c.shouldLog(LogLevelInfo) {
 			var ld LogData
 			ld.Add("err", err)
-			c.log(LogLevelInfo, "connect with TLSConfig failed, trying FallbackTLSConfig", ld)
+			c.log(ctx, LogLevelInfo, "connect with TLSConfig failed, trying FallbackTLSConfig", ld)
 		}
-		err = c.connect(config, network, address, config.FallbackTLSConfig)
+		err = c.connect(ctx, config, network, address, config.FallbackTLSConfig)
 	}
 
 	if err != nil {
 		if c.shouldLog(LogLevelError) {
 			var ld LogData
 			ld.Add("err", err)
-			c.log(LogLevelError, "connect failed", ld)
+			c.log(ctx, LogLevelError, "connect failed", ld)
 		}
 		return nil, err
 	}
@@ -313,7 +341,7 @@ func connect(config ConnConfig, connInfo *pgtype.ConnInfo) (c *Conn, err error)
 	return c, nil
 }
 
-func (c *Conn) connect(config ConnConfig, network, address string, tlsConfig *tls.Config) (err error) {
+func (c *Conn) connect(ctx context.Context, config ConnConfig, network, address string, tlsConfig *tls.Config) (err error) {
 	c.conn, err = c.config.Dial(network, address)
 	if err != nil {
 		return err
@@ -349,7 +377,7 @@ func (c *Conn) connect(config ConnConfig, network, address string, tlsConfig *tl
 
 	if tlsConfig != nil {
 		if c.shouldLog(LogLevelDebug) {
-			c.log(LogLevelDebug, "starting TLS handshake", nil)
+			c.log(ctx, LogLevelDebug, "starting TLS handshake", nil)
 		}
 		if err := c.startTLS(tlsConfig); err != nil {
 			return err
@@ -406,7 +434,7 @@ func (c *Conn) connect(config ConnConfig, network, address string, tlsConfig *tl
 		case *pgproto3.ReadyForQuery:
 			c.rxReadyForQuery(msg)
 			if c.shouldLog(LogLevelInfo) {
-				c.log(LogLevelInfo, "connection established", nil)
+				c.log(ctx, LogLevelInfo, "connection established", nil)
 			}
 
 			// Replication connections can't execute the queries to
@@ -484,7 +512,7 @@ func (c *Conn) Close() (err error) {
 		c.conn.Close()
 		c.causeOfDeath = errors.New("Closed")
 		if c.shouldLog(LogLevelInfo) {
-			c.log(LogLevelInfo, "closed connection", nil)
+			c.log(context.Background(), LogLevelInfo, "closed connection", nil)
 		}
 	}()
 
@@ -492,7 +520,7 @@ func (c *Conn) Close() (err error) {
 	if err != nil && c.shouldLog(LogLevelWarn) {
 		var ld LogData
 		ld.Add("err", err)
-		c.log(LogLevelWarn, "failed to clear deadlines to send close message", ld)
+		c.log(context.Background(), LogLevelWarn, "failed to clear deadlines to send close message", ld)
 		return err
 	}
 
@@ -500,7 +528,7 @@ func (c *Conn) Close() (err error) {
 	if err != nil && c.shouldLog(LogLevelWarn) {
 		var ld LogData
 		ld.Add("err", err)
-		c.log(LogLevelWarn, "failed to send terminate message", ld)
+		c.log(context.Background(), LogLevelWarn, "failed to send terminate message", ld)
 		return err
 	}
 
@@ -508,7 +536,7 @@ func (c *Conn) Close() (err error) {
 	if err != nil && c.shouldLog(LogLevelWarn) {
 		var ld LogData
 		ld.Add("err", err)
-		c.log(LogLevelWarn, "failed to set read deadline to finish closing", ld)
+		c.log(context.Background(), LogLevelWarn, "failed to set read deadline to finish closing", ld)
 		return err
 	}
 
@@ -555,14 +583,34 @@ func (old ConnConfig) Merge(other ConnConfig) ConnConfig {
 	if other.Logger != nil {
 		cc.Logger = other.Logger
 	}
+	if other.ContextLogger != nil {
+		cc.ContextLogger = other.ContextLogger
+	}
 	if other.LogLevel != 0 {
 		cc.LogLevel = other.LogLevel
 	}
+	if other.LogArgs != nil {
+		cc.LogArgs = other.LogArgs
+	}
+	if other.SlowQueryThreshold != 0 {
+		cc.SlowQueryThreshold = other.SlowQueryThreshold
+	}
+	if other.QueryStats != nil {
+		cc.QueryStats = other.QueryStats
+	}
 
 	if other.Dial != nil {
 		cc.Dial = other.Dial
 	}
 
+	if other.Tracer != nil {
+		cc.Tracer = other.Tracer
+	}
+
+	if other.CancelRequestTimeout != 0 {
+		cc.CancelRequestTimeout = other.CancelRequestTimeout
+	}
+
 	cc.RuntimeParams = make(map[string]string)
 	for k, v := range old.RuntimeParams {
 		cc.RuntimeParams[k] = v
@@ -798,6 +846,13 @@ func (c *Conn) Prepare(name, sql string) (ps *PreparedStatement, err error) {
 // name and sql arguments. This allows a code path to PrepareEx and Query/Exec without
 // concern for if the statement has already been prepared.
 func (c *Conn) PrepareEx(ctx context.Context, name, sql string, opts *PrepareExOptions) (ps *PreparedStatement, err error) {
+	if tracer, ok := c.config.Tracer.(PrepareTracer); ok {
+		ctx = tracer.TracePrepareStart(ctx, c, TracePrepareStartData{Name: name, SQL: sql})
+		defer func() {
+			tracer.TracePrepareEnd(ctx, c, TracePrepareEndData{Err: err})
+		}()
+	}
+
 	err = c.waitForPreviousCancelQuery(ctx)
 	if err != nil {
 		return nil, err
@@ -808,12 +863,12 @@ func (c *Conn) PrepareEx(ctx context.Context, name, sql string, opts *PrepareExO
 		return nil, err
 	}
 
-	ps, err = c.prepareEx(name, sql, opts)
+	ps, err = c.prepareEx(ctx, name, sql, opts)
 	err = c.termContext(err)
 	return ps, err
 }
 
-func (c *Conn) prepareEx(name, sql string, opts *PrepareExOptions) (ps *PreparedStatement, err error) {
+func (c *Conn) prepareEx(ctx context.Context, name, sql string, opts *PrepareExOptions) (ps *PreparedStatement, err error) {
 	var id string
 	if name != "" {
 		if ps, ok := c.preparedStatements[name]; ok && ps.SQL == sql {
@@ -833,8 +888,8 @@ func (c *Conn) prepareEx(name, sql string, opts *PrepareExOptions) (ps *Prepared
 				var ld LogData
 				ld.Add("err", err)
 				ld.Add("name", name)
-				ld.Add("sql", sql)
-				c.log(LogLevelError, "prepareEx failed", ld)
+				ld.Add("sql", c.config.LogArgs.logSQL(sql))
+				c.log(ctx, LogLevelError, "prepareEx failed", ld)
 			}
 		}()
 	}
@@ -1065,13 +1120,13 @@ func (c *Conn) CauseOfDeath() error {
 	return c.causeOfDeath
 }
 
-func (c *Conn) sendQuery(sql string, arguments ...interface{}) (err error) {
+func (c *Conn) sendQuery(ctx context.Context, sql string, arguments ...interface{}) (err error) {
 	if ps, present := c.preparedStatements[sql]; present {
 		return c.sendPreparedQuery(ps, arguments...)
 	}
 
 	if st, ok := c.config.LazyPreparedStatements[sql]; ok {
-		ps, err := c.prepareEx(sql, st, nil)
+		ps, err := c.prepareEx(ctx, sql, st, nil)
 		if err != nil {
 			return err
 		}
@@ -1262,6 +1317,33 @@ func (c *Conn) rxErrorResponse(msg *pgproto3.ErrorResponse) PgError {
 	return err
 }
 
+// addErrorContext sets the SQL and Args of err to sql and a summary of args if
+// err is a PgError that does not have them yet. sql may be the name of a
+// prepared statement. Errors are often reported outside the application, so
+// without a LogArgsPolicy only the types of the arguments are included.
+func (c *Conn) addErrorContext(err error, sql string, args []interface{}) error {
+	pgErr, ok := err.(PgError)
+	if !ok || pgErr.SQL != "" {
+		return err
+	}
+
+	if ps, ok := c.preparedStatements[sql]; ok {
+		sql = ps.SQL
+	}
+	pgErr.SQL = sql
+	if len(args) > 0 {
+		policy := c.config.LogArgs
+		if policy == nil {
+			policy = &LogArgsPolicy{Mode: LogArgsTypes}
+		}
+		if logArgs, ok := policy.logArgs(sql, args); ok {
+			pgErr.Args = fmt.Sprint(logArgs)
+		}
+	}
+
+	return pgErr
+}
+
 func (c *Conn) rxNoticeResponse(msg *pgproto3.NoticeResponse) {
 	if c.onNotice == nil {
 		return
@@ -1331,32 +1413,43 @@ func (c *Conn) rxNotificationResponse(msg *pgproto3.NotificationResponse) {
 }
 
 func (c *Conn) startTLS(tlsConfig *tls.Config) (err error) {
-	err = binary.Write(c.conn, binary.BigEndian, []int32{8, 80877103})
+	// use blocking Read() to call ReadFull and Handshake() to validate
+	c.wrapConn.ToBlockingRead()
+	tlsConn, err := negotiateTLS(c.conn, tlsConfig)
 	if err != nil {
-		return
+		return err
+	}
+	// reset to non-blocking read
+	c.wrapConn.ToNonBlockingRead()
+
+	c.conn = tlsConn
+
+	return nil
+}
+
+// negotiateTLS sends an SSLRequest on conn and performs the TLS handshake if
+// the server accepts it.
+func negotiateTLS(conn net.Conn, tlsConfig *tls.Config) (*tls.Conn, error) {
+	err := binary.Write(conn, binary.BigEndian, []int32{8, 80877103})
+	if err != nil {
+		return nil, err
 	}
 
-	// use blocking Read() to call ReadFull and Handshake() to validate
-	c.wrapConn.ToBlockingRead()
 	response := make([]byte, 1)
-	if _, err = io.ReadFull(c.conn, response); err != nil {
-		return
+	if _, err = io.ReadFull(conn, response); err != nil {
+		return nil, err
 	}
 
 	if response[0] != 'S' {
-		return ErrTLSRefused
+		return nil, ErrTLSRefused
 	}
 
-	tlsConn := tls.Client(c.conn, tlsConfig)
+	tlsConn := tls.Client(conn, tlsConfig)
 	if err = tlsConn.Handshake(); err != nil {
-		return
+		return nil, err
 	}
-	// reset to non-blocking read
-	c.wrapConn.ToNonBlockingRead()
-
-	c.conn = tlsConn
 
-	return nil
+	return tlsConn, nil
 }
 
 func (c *Conn) txPasswordMessage(password string) (err error) {
@@ -1411,15 +1504,27 @@ func (c *Conn) unlock() error {
 }
 
 func (c *Conn) shouldLog(lvl int) bool {
-	return c.logger != nil && c.logLevel >= lvl
+	return (c.logger != nil || c.contextLogger != nil) && c.logLevel >= lvl
+}
+
+// queryLogLevel returns the level a successful query that took d is logged at.
+func (c *Conn) queryLogLevel(d time.Duration) int {
+	if c.config.SlowQueryThreshold > 0 && d >= c.config.SlowQueryThreshold {
+		return LogLevelWarn
+	}
+	return LogLevelInfo
 }
 
-func (c *Conn) log(lvl LogLevel, msg string, ld LogData) {
+func (c *Conn) log(ctx context.Context, lvl LogLevel, msg string, ld LogData) {
 	if c.pid != 0 {
 		// add pid to the front
 		ld = append([]KV{{Key: "pid", Value: c.pid}}, ld...)
 	}
 
+	if c.contextLogger != nil {
+		c.contextLogger.Log(ctx, lvl, msg, ld)
+		return
+	}
 	c.logger.Log(lvl, msg, ld)
 }
 
@@ -1430,6 +1535,14 @@ func (c *Conn) SetLogger(logger Logger) Logger {
 	return oldLogger
 }
 
+// SetContextLogger replaces the current context logger and returns the
+// previous context logger. A nil ContextLogger falls back to the Logger.
+func (c *Conn) SetContextLogger(logger ContextLogger) ContextLogger {
+	oldLogger := c.contextLogger
+	c.contextLogger = logger
+	return oldLogger
+}
+
 // SetLogLevel replaces the current log level and returns the previous log
 // level.
 func (c *Conn) SetLogLevel(lvl int) (int, error) {
@@ -1462,40 +1575,8 @@ func (c *Conn) cancelQuery() {
 		return
 	}
 
-	doCancel := func() error {
-		network, address := c.config.networkAddress()
-		cancelConn, err := c.config.Dial(network, address)
-		if err != nil {
-			return err
-		}
-		defer cancelConn.Close()
-
-		// If server doesn't process cancellation request in bounded time then abort.
-		err = cancelConn.SetDeadline(time.Now().Add(15 * time.Second))
-		if err != nil {
-			return err
-		}
-
-		buf := make([]byte, 16)
-		binary.BigEndian.PutUint32(buf[0:4], 16)
-		binary.BigEndian.PutUint32(buf[4:8], 80877102)
-		binary.BigEndian.PutUint32(buf[8:12], uint32(c.pid))
-		binary.BigEndian.PutUint32(buf[12:16], uint32(c.secretKey))
-		_, err = cancelConn.Write(buf)
-		if err != nil {
-			return err
-		}
-
-		_, err = cancelConn.Read(buf)
-		if err != io.EOF {
-			return errors.Errorf("Server failed to close connection after cancel query request: %v %v", err, buf)
-		}
-
-		return nil
-	}
-
 	go func() {
-		err := doCancel()
+		err := sendCancelRequest(context.Background(), &c.config, c.CancelKey())
 		if err != nil {
 			c.Close() // Something is very wrong. Terminate the connection.
 		}
@@ -1508,8 +1589,15 @@ func (c *Conn) Ping(ctx context.Context) error {
 	return err
 }
 
-func (c *Conn) ExecEx(ctx context.Context, sql string, options *QueryExOptions, arguments ...interface{}) (CommandTag, error) {
-	err := c.waitForPreviousCancelQuery(ctx)
+func (c *Conn) ExecEx(ctx context.Context, sql string, options *QueryExOptions, arguments ...interface{}) (commandTag CommandTag, err error) {
+	if c.config.Tracer != nil {
+		ctx = c.config.Tracer.TraceQueryStart(ctx, c, TraceQueryStartData{SQL: sql, Args: arguments})
+		defer func() {
+			c.config.Tracer.TraceQueryEnd(ctx, c, TraceQueryEndData{CommandTag: commandTag, Err: err})
+		}()
+	}
+
+	err = c.waitForPreviousCancelQuery(ctx)
 	if err != nil {
 		return "", err
 	}
@@ -1522,26 +1610,28 @@ func (c *Conn) ExecEx(ctx context.Context, sql string, options *QueryExOptions,
 	startTime := time.Now()
 	c.lastActivityTime = startTime
 
-	commandTag, err := c.execEx(ctx, sql, options, arguments...)
+	commandTag, err = c.execEx(ctx, sql, options, arguments...)
+	err = c.addErrorContext(err, sql, arguments)
+	duration := time.Since(startTime)
+	c.recordQuery(sql, duration, commandTag.RowsAffected(), err)
 	if err != nil {
 		if c.shouldLog(LogLevelError) {
 			var ld LogData
-			ld.Add("sql", sql)
+			ld.Add("sql", c.config.LogArgs.logSQL(sql))
 			ld.Add("err", err)
-			ld.Add("args", logQueryArgs(arguments))
-			c.log(LogLevelError, "Exec", ld)
+			c.addLogArgs(&ld, sql, arguments)
+			c.log(ctx, LogLevelError, "Exec", ld)
 		}
 		return commandTag, err
 	}
 
-	if c.shouldLog(LogLevelInfo) {
-		endTime := time.Now()
+	if lvl := c.queryLogLevel(duration); c.shouldLog(lvl) {
 		var ld LogData
-		ld.Add("time", endTime.Sub(startTime))
+		ld.Add("time", duration)
 		ld.Add("commandTag", commandTag)
-		ld.Add("sql", sql)
-		ld.Add("args", logQueryArgs(arguments))
-		c.log(LogLevelInfo, "Exec", ld)
+		ld.Add("sql", c.config.LogArgs.logSQL(sql))
+		c.addLogArgs(&ld, sql, arguments)
+		c.log(ctx, LogLevel(lvl), "Exec", ld)
 	}
 
 	return commandTag, err
@@ -1581,12 +1671,12 @@ func (c *Conn) execEx(ctx context.Context, sql string, options *QueryExOptions,
 			if !ok {
 				var err error
 				if st, ok := c.config.LazyPreparedStatements[sql]; ok {
-					ps, err = c.prepareEx(sql, st, nil)
+					ps, err = c.prepareEx(ctx, sql, st, nil)
 					if err != nil {
 						return "", err
 					}
 				} else {
-					ps, err = c.prepareEx("", sql, nil)
+					ps, err = c.prepareEx(ctx, "", sql, nil)
 					if err != nil {
 						return "", err
 					}
@@ -1598,7 +1688,7 @@ func (c *Conn) execEx(ctx context.Context, sql string, options *QueryExOptions,
 				return "", err
 			}
 		} else {
-			if err = c.sendQuery(sql, arguments...); err != nil {
+			if err = c.sendQuery(ctx, sql, arguments...); err != nil {
 				return
 			}
 		}
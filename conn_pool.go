@@ -2,6 +2,7 @@ package pgx
 
 import (
 	"context"
+	"io"
 	"sync"
 	"time"
 
@@ -27,6 +28,7 @@ type ConnPool struct {
 	resetCount           int
 	afterConnect         func(*Conn) error
 	logger               Logger
+	contextLogger        ContextLogger
 	logLevel             int
 	closed               bool
 	preparedStatements   map[string]*PreparedStatement
@@ -70,7 +72,8 @@ func NewConnPool(config ConnPoolConfig) (p *ConnPool, err error) {
 		p.logLevel = LogLevelDebug
 	}
 	p.logger = config.Logger
-	if p.logger == nil {
+	p.contextLogger = config.ContextLogger
+	if p.logger == nil && p.contextLogger == nil {
 		p.logLevel = LogLevelNone
 	}
 
@@ -81,7 +84,7 @@ func NewConnPool(config ConnPoolConfig) (p *ConnPool, err error) {
 
 	// Initially establish one connection
 	var c *Conn
-	c, err = p.createConnection()
+	c, err = p.createConnection(context.Background())
 	if err != nil {
 		return
 	}
@@ -94,22 +97,40 @@ func NewConnPool(config ConnPoolConfig) (p *ConnPool, err error) {
 
 // Acquire takes exclusive use of a connection until it is released.
 func (p *ConnPool) Acquire() (*Conn, error) {
+	return p.AcquireEx(context.Background())
+}
+
+// AcquireEx is the same as Acquire, but stops waiting for a connection and
+// returns ctx.Err() when ctx is done. ctx is passed to the ConnectTracer and
+// ContextLogger if a new connection is established.
+func (p *ConnPool) AcquireEx(ctx context.Context) (*Conn, error) {
 	p.cond.L.Lock()
-	c, err := p.acquire(nil)
+	c, err := p.acquire(ctx, nil)
 	p.cond.L.Unlock()
 	return c, err
 }
 
+func (p *ConnPool) log(ctx context.Context, lvl LogLevel, msg string, ld LogData) {
+	if p.contextLogger != nil {
+		p.contextLogger.Log(ctx, lvl, msg, ld)
+		return
+	}
+	p.logger.Log(lvl, msg, ld)
+}
+
 // deadlinePassed returns true if the given deadline has passed.
 func (p *ConnPool) deadlinePassed(deadline *time.Time) bool {
 	return deadline != nil && time.Now().After(*deadline)
 }
 
 // acquire performs acquision assuming pool is already locked
-func (p *ConnPool) acquire(deadline *time.Time) (*Conn, error) {
+func (p *ConnPool) acquire(ctx context.Context, deadline *time.Time) (*Conn, error) {
 	if p.closed {
 		return nil, errors.New("cannot acquire from closed pool")
 	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
 	// A connection is available
 	if len(p.availableConnections) > 0 {
@@ -145,7 +166,7 @@ func (p *ConnPool) acquire(deadline *time.Time) (*Conn, error) {
 		// Create a new connection.
 		// Careful here: createConnectionUnlocked() removes the current lock,
 		// creates a connection and then locks it back.
-		c, err := p.createConnectionUnlocked()
+		c, err := p.createConnectionUnlocked(ctx)
 		if err != nil {
 			return nil, err
 		}
@@ -155,7 +176,23 @@ func (p *ConnPool) acquire(deadline *time.Time) (*Conn, error) {
 	}
 	// All connections are in use and we cannot create more
 	if p.logLevel >= LogLevelWarn {
-		p.logger.Log(LogLevelWarn, "waiting for available connection", nil)
+		p.log(ctx, LogLevelWarn, "waiting for available connection", nil)
+	}
+
+	// Wake up the waiters when ctx is done so that this one can return.
+	var stopWatching chan struct{}
+	if ctx.Done() != nil {
+		stopWatching = make(chan struct{})
+		defer close(stopWatching)
+		go func() {
+			select {
+			case <-ctx.Done():
+				p.cond.L.Lock()
+				p.cond.Broadcast()
+				p.cond.L.Unlock()
+			case <-stopWatching:
+			}
+		}()
 	}
 
 	// Wait until there is an available connection OR room to create a new connection
@@ -163,6 +200,9 @@ func (p *ConnPool) acquire(deadline *time.Time) (*Conn, error) {
 		if p.deadlinePassed(deadline) {
 			return nil, ErrAcquireTimeout
 		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		p.cond.Wait()
 	}
 
@@ -170,7 +210,7 @@ func (p *ConnPool) acquire(deadline *time.Time) (*Conn, error) {
 	if timer != nil {
 		timer.Stop()
 	}
-	return p.acquire(deadline)
+	return p.acquire(ctx, deadline)
 }
 
 // Release gives up use of a connection.
@@ -287,8 +327,8 @@ func (p *ConnPool) Stat() (s ConnPoolStat) {
 	return
 }
 
-func (p *ConnPool) createConnection() (*Conn, error) {
-	c, err := connect(p.config, p.connInfo)
+func (p *ConnPool) createConnection(ctx context.Context) (*Conn, error) {
+	c, err := connect(ctx, p.config, p.connInfo)
 	if err != nil {
 		return nil, err
 	}
@@ -307,10 +347,10 @@ func (p *ConnPool) createConnection() (*Conn, error) {
 // 3 * 20 = 60 secs.
 // To avoid this we put Connect(p.config) outside of the lock (it is thread safe)
 // what would allow us to make all the 20 connection in parallel (more or less).
-func (p *ConnPool) createConnectionUnlocked() (*Conn, error) {
+func (p *ConnPool) createConnectionUnlocked(ctx context.Context) (*Conn, error) {
 	p.inProgressConnects++
 	p.cond.L.Unlock()
-	c, err := Connect(p.config)
+	c, err := ConnectEx(ctx, p.config)
 	p.cond.L.Lock()
 	p.inProgressConnects--
 
@@ -354,7 +394,7 @@ func (p *ConnPool) Exec(sql string, arguments ...interface{}) (commandTag Comman
 
 func (p *ConnPool) ExecEx(ctx context.Context, sql string, options *QueryExOptions, arguments ...interface{}) (commandTag CommandTag, err error) {
 	var c *Conn
-	if c, err = p.Acquire(); err != nil {
+	if c, err = p.AcquireEx(ctx); err != nil {
 		return
 	}
 	defer p.Release(c)
@@ -401,7 +441,7 @@ func (p *ConnPool) QueryWithBufferSize(bufferSize int, sql string, args ...inter
 }
 
 func (p *ConnPool) QueryEx(ctx context.Context, sql string, options *QueryExOptions, args ...interface{}) (*Rows, error) {
-	c, err := p.Acquire()
+	c, err := p.AcquireEx(ctx)
 	if err != nil {
 		// Because checking for errors can be deferred to the *Rows, build one with the error
 		return &Rows{closed: true, err: err}, err
@@ -471,7 +511,7 @@ func (p *ConnPool) PrepareEx(ctx context.Context, name, sql string, opts *Prepar
 		return ps, nil
 	}
 
-	c, err := p.acquire(nil)
+	c, err := p.acquire(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -525,7 +565,7 @@ func (p *ConnPool) Deallocate(name string) (err error) {
 // connection will be automatically released.
 func (p *ConnPool) BeginEx(ctx context.Context, txOptions *TxOptions) (*Tx, error) {
 	for {
-		c, err := p.Acquire()
+		c, err := p.AcquireEx(ctx)
 		if err != nil {
 			return nil, err
 		}
@@ -561,6 +601,39 @@ func (p *ConnPool) CopyFrom(tableName Identifier, columnNames []string, rowSrc C
 	return c.CopyFrom(tableName, columnNames, rowSrc)
 }
 
+// CopyFromEx acquires a connection, delegates the call to that connection, and releases the connection
+func (p *ConnPool) CopyFromEx(ctx context.Context, tableName Identifier, columnNames []string, rowSrc CopyFromSource, options *CopyFromOptions) (int, error) {
+	c, err := p.AcquireEx(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer p.Release(c)
+
+	return c.CopyFromEx(ctx, tableName, columnNames, rowSrc, options)
+}
+
+// CopyFromReader acquires a connection, delegates the call to that connection, and releases the connection
+func (p *ConnPool) CopyFromReader(ctx context.Context, r io.Reader, sql string) (int, error) {
+	c, err := p.AcquireEx(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer p.Release(c)
+
+	return c.CopyFromReader(ctx, r, sql)
+}
+
+// UpsertFrom acquires a connection, delegates the call to that connection, and releases the connection
+func (p *ConnPool) UpsertFrom(ctx context.Context, tableName Identifier, columnNames []string, rowSrc CopyFromSource, options *UpsertOptions) (*UpsertResult, error) {
+	c, err := p.AcquireEx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer p.Release(c)
+
+	return c.UpsertFrom(ctx, tableName, columnNames, rowSrc, options)
+}
+
 // BeginBatch acquires a connection and begins a batch on that connection. When
 // *Batch is finished, the connection is released automatically.
 func (p *ConnPool) BeginBatch() *Batch {
@@ -11,6 +11,7 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/ronaldslc/pgx"
+	"github.com/ronaldslc/pgx/pgmock"
 )
 
 func createConnPool(t *testing.T, maxConnections int) *pgx.ConnPool {
@@ -315,6 +316,68 @@ func TestPoolWithoutAcquireTimeoutSet(t *testing.T) {
 	}
 }
 
+func TestPoolAcquireExContextDone(t *testing.T) {
+	t.Parallel()
+
+	script := &pgmock.Script{
+		Steps: pgmock.AcceptUnauthenticatedConnRequestSteps(),
+	}
+	script.Steps = append(script.Steps, pgmock.PgxInitSteps()...)
+	script.Steps = append(script.Steps, pgmock.WaitForClose())
+
+	server, err := pgmock.NewServer(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- server.ServeOne()
+	}()
+
+	connConfig, err := pgx.ParseURI(fmt.Sprintf("postgres://pgx_md5:secret@%s/pgx_test?sslmode=disable", server.Addr()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool, err := pgx.NewConnPool(pgx.ConnPoolConfig{ConnConfig: connConfig, MaxConnections: 1})
+	if err != nil {
+		t.Fatalf("Unable to create connection pool: %v", err)
+	}
+
+	c, err := pool.Acquire()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The only connection is in use, so AcquireEx waits until ctx is done.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	startTime := time.Now()
+	_, err = pool.AcquireEx(ctx)
+	if err != context.DeadlineExceeded {
+		t.Errorf("err => %v, want %v", err, context.DeadlineExceeded)
+	}
+	if timeTaken := time.Since(startTime); timeTaken > time.Second {
+		t.Errorf("Expected AcquireEx to return when ctx is done, but it took %v", timeTaken)
+	}
+
+	// A done ctx does not take an available connection.
+	pool.Release(c)
+	if _, err := pool.AcquireEx(ctx); err != context.DeadlineExceeded {
+		t.Errorf("err => %v, want %v", err, context.DeadlineExceeded)
+	}
+	if stat := pool.Stat(); stat.AvailableConnections != 1 {
+		t.Errorf("Expected 1 available connection, got %d", stat.AvailableConnections)
+	}
+
+	pool.Close()
+	if err := <-errChan; err != nil {
+		t.Fatalf("mock server err: %v", err)
+	}
+}
+
 func TestPoolReleaseWithTransactions(t *testing.T) {
 	t.Parallel()
 
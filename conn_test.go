@@ -7,6 +7,7 @@ import (
 	"net"
 	"os"
 	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
 	"sync"
@@ -1824,6 +1825,178 @@ func TestSetLogger(t *testing.T) {
 	}
 }
 
+type requestIDKey struct{}
+
+type testContextLogger struct {
+	requestIDs []interface{}
+	logs       []testLog
+}
+
+func (l *testContextLogger) Log(ctx context.Context, level pgx.LogLevel, msg string, ld pgx.LogData) {
+	l.requestIDs = append(l.requestIDs, ctx.Value(requestIDKey{}))
+	l.logs = append(l.logs, testLog{lvl: level, msg: msg, ld: ld})
+}
+
+func TestContextLogger(t *testing.T) {
+	t.Parallel()
+
+	logger := &testLogger{}
+	contextLogger := &testContextLogger{}
+
+	config := *defaultConnConfig
+	config.Logger = logger
+	config.ContextLogger = contextLogger
+	config.LogLevel = pgx.LogLevelInfo
+
+	conn := mustConnect(t, config)
+	defer closeConn(t, conn)
+
+	if len(logger.logs) != 0 {
+		t.Fatalf("Expected ContextLogger to take precedence over Logger, but Logger was called: %v", logger.logs)
+	}
+
+	contextLogger.requestIDs = nil
+	ctx := context.WithValue(context.Background(), requestIDKey{}, "req-42")
+
+	if _, err := conn.ExecEx(ctx, "select 1", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := conn.QueryEx(ctx, 0, "select 1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+
+	if len(contextLogger.requestIDs) != 2 {
+		t.Fatalf("Expected 2 log entries, got %d", len(contextLogger.requestIDs))
+	}
+	for i, id := range contextLogger.requestIDs {
+		if id != "req-42" {
+			t.Errorf("%d. Expected log entry with request ID req-42, got %v", i, id)
+		}
+	}
+
+	oldLogger := conn.SetContextLogger(nil)
+	if oldLogger != contextLogger {
+		t.Fatalf("Expected conn.SetContextLogger to return %v, but it was %v", contextLogger, oldLogger)
+	}
+
+	if _, err := conn.ExecEx(ctx, "select 1", nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(logger.logs) != 1 {
+		t.Fatalf("Expected Logger to be used without ContextLogger, got %v", logger.logs)
+	}
+}
+
+func TestContextLogData(t *testing.T) {
+	t.Parallel()
+
+	fields := make(pgx.LogData, 1, 4)
+	fields[0] = pgx.KV{Key: "request_id", Value: "42"}
+	fieldsFromContext := func(ctx context.Context) pgx.LogData {
+		return fields
+	}
+	ctx := context.Background()
+
+	ld1 := pgx.ContextLogData(ctx, fieldsFromContext, pgx.LogData{{Key: "sql", Value: "select 1"}})
+	ld2 := pgx.ContextLogData(ctx, fieldsFromContext, pgx.LogData{{Key: "sql", Value: "select 2"}})
+
+	expected := pgx.LogData{{Key: "request_id", Value: "42"}, {Key: "sql", Value: "select 1"}}
+	if !reflect.DeepEqual(ld1, expected) {
+		t.Errorf("Expected %v, got %v", expected, ld1)
+	}
+	if ld2[1].Value != "select 2" || len(fields) != 1 {
+		t.Errorf("Expected fields not to be shared, got %v and %v", ld2, fields)
+	}
+
+	ld := pgx.LogData{{Key: "sql", Value: "select 1"}}
+	if actual := pgx.ContextLogData(ctx, nil, ld); !reflect.DeepEqual(actual, ld) {
+		t.Errorf("Expected %v without fieldsFromContext, got %v", ld, actual)
+	}
+}
+
+func TestLogArgsPolicy(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		policy       *pgx.LogArgsPolicy
+		expectedSQL  interface{}
+		expectedArgs interface{}
+	}{
+		{
+			policy:       nil,
+			expectedSQL:  "select $1::text, $2::text",
+			expectedArgs: []interface{}{"secret", strings.Repeat("x", 64) + " (truncated 6 bytes)"},
+		},
+		{
+			policy:       &pgx.LogArgsPolicy{Mode: pgx.LogArgsNone},
+			expectedSQL:  "select $1::text, $2::text",
+			expectedArgs: nil,
+		},
+		{
+			policy:       &pgx.LogArgsPolicy{Mode: pgx.LogArgsTypes},
+			expectedSQL:  "select $1::text, $2::text",
+			expectedArgs: []string{"string", "string"},
+		},
+		{
+			policy: &pgx.LogArgsPolicy{
+				Rules:     []pgx.LogArgsRule{{SQL: regexp.MustCompile(`^select`), Args: []int{0}}},
+				MaxArgLen: 4,
+				MaxSQLLen: 6,
+			},
+			expectedSQL:  "select (truncated 19 bytes)",
+			expectedArgs: []interface{}{pgx.RedactedArg, "xxxx (truncated 66 bytes)"},
+		},
+		{
+			policy: &pgx.LogArgsPolicy{
+				Func: func(sql string, args []interface{}) interface{} { return len(args) },
+			},
+			expectedSQL:  "select $1::text, $2::text",
+			expectedArgs: 2,
+		},
+	}
+
+	for i, tt := range tests {
+		logger := &testLogger{}
+		config := *defaultConnConfig
+		config.Logger = logger
+		config.LogLevel = pgx.LogLevelInfo
+		config.LogArgs = tt.policy
+
+		conn := mustConnect(t, config)
+		logger.logs = nil
+
+		if _, err := conn.Exec("select $1::text, $2::text", "secret", strings.Repeat("x", 70)); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(logger.logs) != 1 {
+			t.Fatalf("%d. Expected 1 log entry, got %v", i, logger.logs)
+		}
+
+		var sql, args interface{}
+		for _, kv := range logger.logs[0].ld {
+			switch kv.Key {
+			case "sql":
+				sql = kv.Value
+			case "args":
+				args = kv.Value
+			}
+		}
+
+		if sql != tt.expectedSQL {
+			t.Errorf("%d. Expected sql %v, got %v", i, tt.expectedSQL, sql)
+		}
+		if !reflect.DeepEqual(args, tt.expectedArgs) {
+			t.Errorf("%d. Expected args %#v, got %#v", i, tt.expectedArgs, args)
+		}
+
+		closeConn(t, conn)
+	}
+}
+
 func TestSetLogLevel(t *testing.T) {
 	t.Parallel()
 
@@ -2,11 +2,16 @@ package pgx
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"io"
+	"net"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/ronaldslc/pgx/pgio"
 	"github.com/ronaldslc/pgx/pgproto3"
+	"github.com/ronaldslc/pgx/pgtype"
 )
 
 // CopyFromRows returns a CopyFromSource interface over the provided rows slice
@@ -48,12 +53,42 @@ type CopyFromSource interface {
 	Err() error
 }
 
+// CopyFromOptions contains the optional settings for CopyFromEx.
+type CopyFromOptions struct {
+	// ColumnOIDs are the data type OIDs of the copied columns in the same order
+	// as the column names. When provided the preparatory select used to look up
+	// the column types is skipped.
+	ColumnOIDs []pgtype.OID
+
+	// Freeze requests that the copied rows be frozen as if VACUUM FREEZE had been
+	// run. The table must have been created or truncated in the current
+	// transaction.
+	Freeze bool
+
+	// Progress, if set, is called each time a block of copy data has been sent
+	// to the server with the total number of rows and bytes sent so far.
+	Progress func(rows, bytes int64)
+
+	// AbortTimeout bounds the time taken to send CopyFail and wait for the
+	// server to acknowledge it when the context is canceled during the copy.
+	// If it is exceeded the connection is closed. The default is 15 seconds.
+	AbortTimeout time.Duration
+}
+
+const defaultCopyFromAbortTimeout = 15 * time.Second
+
 type copyFrom struct {
 	conn          *Conn
+	ctx           context.Context
 	tableName     Identifier
 	columnNames   []string
 	rowSrc        CopyFromSource
+	options       *CopyFromOptions
 	readerErrChan chan error
+	sentBytes     int64
+
+	watchDoneChan     chan struct{}
+	watchFinishedChan chan struct{}
 }
 
 func (ct *copyFrom) readUntilReadyForQuery() {
@@ -89,7 +124,142 @@ func (ct *copyFrom) waitForReaderDone() error {
 	return err
 }
 
+// watchContext starts a goroutine that interrupts a write blocked on a stalled
+// server when ct.ctx is canceled.
+func (ct *copyFrom) watchContext() {
+	if ct.ctx.Done() == nil {
+		return
+	}
+
+	ct.watchDoneChan = make(chan struct{})
+	ct.watchFinishedChan = make(chan struct{})
+
+	go func() {
+		defer close(ct.watchFinishedChan)
+
+		select {
+		case <-ct.ctx.Done():
+			ct.conn.conn.SetWriteDeadline(time.Now())
+		case <-ct.watchDoneChan:
+		}
+	}()
+}
+
+// stopWatchingContext stops the goroutine started by watchContext and clears
+// any write deadline it may have set. It is safe to call multiple times.
+func (ct *copyFrom) stopWatchingContext() error {
+	if ct.watchDoneChan == nil {
+		return nil
+	}
+
+	close(ct.watchDoneChan)
+	<-ct.watchFinishedChan
+	ct.watchDoneChan = nil
+
+	return ct.conn.conn.SetWriteDeadline(time.Time{})
+}
+
+// write sends buf to the server. If the write is interrupted by the context
+// before any data was sent the copy is aborted, otherwise a failed write is
+// fatal to the connection.
+func (ct *copyFrom) write(buf []byte) error {
+	n, err := ct.conn.conn.Write(buf)
+	if err != nil {
+		if n == 0 && ct.ctx.Err() != nil {
+			return ct.abort()
+		}
+		ct.conn.die(err)
+		return err
+	}
+
+	ct.sentBytes += int64(n)
+	return nil
+}
+
+// abort cancels the copy in progress because ct.ctx is done. It sends a
+// CopyFail message and waits for the server to acknowledge it. Both are
+// bounded by options.AbortTimeout so a stalled server can not block abort; the
+// connection is closed if it is exceeded.
+func (ct *copyFrom) abort() error {
+	if err := ct.stopWatchingContext(); err != nil {
+		ct.conn.die(err)
+		return ct.ctx.Err()
+	}
+
+	abortTimeout := ct.options.AbortTimeout
+	if abortTimeout <= 0 {
+		abortTimeout = defaultCopyFromAbortTimeout
+	}
+	if err := ct.conn.conn.SetDeadline(time.Now().Add(abortTimeout)); err != nil {
+		ct.conn.die(err)
+		return ct.ctx.Err()
+	}
+
+	if err := ct.conn.cancelCopyIn(); err != nil {
+		return ct.ctx.Err()
+	}
+
+	err := ct.waitForReaderDone()
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		ct.conn.die(err)
+		return ct.ctx.Err()
+	}
+
+	if err := ct.conn.conn.SetDeadline(time.Time{}); err != nil {
+		ct.conn.die(err)
+	}
+
+	return ct.ctx.Err()
+}
+
+func (ct *copyFrom) reportProgress(rowCount int) {
+	if ct.options.Progress != nil {
+		ct.options.Progress(int64(rowCount), ct.sentBytes)
+	}
+}
+
+func (ct *copyFrom) columnOIDs(quotedTableName, quotedColumnNames string) ([]pgtype.OID, error) {
+	if len(ct.options.ColumnOIDs) > 0 {
+		if len(ct.options.ColumnOIDs) != len(ct.columnNames) {
+			return nil, errors.Errorf("mismatched number of columns (%d) and options.ColumnOIDs (%d)", len(ct.columnNames), len(ct.options.ColumnOIDs))
+		}
+		return ct.options.ColumnOIDs, nil
+	}
+
+	ps, err := ct.conn.PrepareEx(ct.ctx, "", fmt.Sprintf("select %s from %s", quotedColumnNames, quotedTableName), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	oids := make([]pgtype.OID, len(ps.FieldDescriptions))
+	for i, fd := range ps.FieldDescriptions {
+		oids[i] = fd.DataType
+	}
+	return oids, nil
+}
+
+func (ct *copyFrom) startCopyIn(copySQL string) (err error) {
+	err = ct.conn.initContext(ct.ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = ct.conn.termContext(err)
+	}()
+
+	err = ct.conn.sendSimpleQuery(copySQL)
+	if err != nil {
+		return err
+	}
+
+	return ct.conn.readUntilCopyInResponse()
+}
+
 func (ct *copyFrom) run() (int, error) {
+	if ct.options == nil {
+		ct.options = &CopyFromOptions{}
+	}
+
 	quotedTableName := ct.tableName.Sanitize()
 	cbuf := &bytes.Buffer{}
 	for i, cn := range ct.columnNames {
@@ -100,17 +270,22 @@ func (ct *copyFrom) run() (int, error) {
 	}
 	quotedColumnNames := cbuf.String()
 
-	ps, err := ct.conn.Prepare("", fmt.Sprintf("select %s from %s", quotedColumnNames, quotedTableName))
+	err := ct.conn.waitForPreviousCancelQuery(ct.ctx)
 	if err != nil {
 		return 0, err
 	}
 
-	err = ct.conn.sendSimpleQuery(fmt.Sprintf("copy %s ( %s ) from stdin binary;", quotedTableName, quotedColumnNames))
+	columnOIDs, err := ct.columnOIDs(quotedTableName, quotedColumnNames)
 	if err != nil {
 		return 0, err
 	}
 
-	err = ct.conn.readUntilCopyInResponse()
+	copySQL := fmt.Sprintf("copy %s ( %s ) from stdin binary;", quotedTableName, quotedColumnNames)
+	if ct.options.Freeze {
+		copySQL = fmt.Sprintf("copy %s ( %s ) from stdin with (format binary, freeze);", quotedTableName, quotedColumnNames)
+	}
+
+	err = ct.startCopyIn(copySQL)
 	if err != nil {
 		return 0, err
 	}
@@ -118,6 +293,9 @@ func (ct *copyFrom) run() (int, error) {
 	go ct.readUntilReadyForQuery()
 	defer ct.waitForReaderDone()
 
+	ct.watchContext()
+	defer ct.stopWatchingContext()
+
 	buf := ct.conn.wbuf
 	buf = append(buf, copyData)
 	sp := len(buf)
@@ -133,16 +311,18 @@ func (ct *copyFrom) run() (int, error) {
 		select {
 		case err = <-ct.readerErrChan:
 			return 0, err
+		case <-ct.ctx.Done():
+			return 0, ct.abort()
 		default:
 		}
 
 		if len(buf) > 65536 {
 			pgio.SetInt32(buf[sp:], int32(len(buf[sp:])))
-			_, err = ct.conn.conn.Write(buf)
+			err = ct.write(buf)
 			if err != nil {
-				ct.conn.die(err)
 				return 0, err
 			}
+			ct.reportProgress(sentCount)
 
 			// Directly manipulate wbuf to reset to reuse the same buffer
 			buf = buf[0:5]
@@ -162,7 +342,7 @@ func (ct *copyFrom) run() (int, error) {
 
 		buf = pgio.AppendInt16(buf, int16(len(ct.columnNames)))
 		for i, val := range values {
-			buf, err = encodePreparedStatementArgument(ct.conn.ConnInfo, buf, ps.FieldDescriptions[i].DataType, val)
+			buf, err = encodePreparedStatementArgument(ct.conn.ConnInfo, buf, columnOIDs[i], val)
 			if err != nil {
 				ct.cancelCopyIn()
 				return 0, err
@@ -182,19 +362,39 @@ func (ct *copyFrom) run() (int, error) {
 	buf = append(buf, copyDone)
 	buf = pgio.AppendInt32(buf, 4)
 
-	_, err = ct.conn.conn.Write(buf)
+	err = ct.write(buf)
 	if err != nil {
-		ct.conn.die(err)
 		return 0, err
 	}
+	ct.reportProgress(sentCount)
 
-	err = ct.waitForReaderDone()
+	err = ct.waitForCopyComplete()
 	if err != nil {
 		return 0, err
 	}
 	return sentCount, nil
 }
 
+// waitForCopyComplete waits for the server to finish processing the copy data
+// after CopyDone has been sent. The copy can no longer be aborted with
+// CopyFail at this point so if ct.ctx is canceled the query is canceled instead.
+func (ct *copyFrom) waitForCopyComplete() error {
+	if err := ct.stopWatchingContext(); err != nil {
+		ct.conn.die(err)
+		return err
+	}
+
+	if err := ct.conn.initContext(ct.ctx); err != nil {
+		if ct.ctx.Err() != nil {
+			ct.conn.cancelQuery()
+			ct.waitForReaderDone()
+		}
+		return err
+	}
+
+	return ct.conn.termContext(ct.waitForReaderDone())
+}
+
 func (c *Conn) readUntilCopyInResponse() error {
 	for {
 		msg, err := c.rxMsg()
@@ -215,7 +415,11 @@ func (c *Conn) readUntilCopyInResponse() error {
 }
 
 func (ct *copyFrom) cancelCopyIn() error {
-	buf := ct.conn.wbuf
+	return ct.conn.cancelCopyIn()
+}
+
+func (c *Conn) cancelCopyIn() error {
+	buf := c.wbuf
 	buf = append(buf, copyFail)
 	sp := len(buf)
 	buf = pgio.AppendInt32(buf, -1)
@@ -223,9 +427,9 @@ func (ct *copyFrom) cancelCopyIn() error {
 	buf = append(buf, 0)
 	pgio.SetInt32(buf[sp:], int32(len(buf[sp:])))
 
-	_, err := ct.conn.conn.Write(buf)
+	_, err := c.conn.Write(buf)
 	if err != nil {
-		ct.conn.die(err)
+		c.die(err)
 		return err
 	}
 
@@ -239,13 +443,147 @@ func (ct *copyFrom) cancelCopyIn() error {
 // implemented by pgx use the binary format by default. Types implementing
 // Encoder can only be used if they encode to the binary format.
 func (c *Conn) CopyFrom(tableName Identifier, columnNames []string, rowSrc CopyFromSource) (int, error) {
+	return c.CopyFromEx(context.Background(), tableName, columnNames, rowSrc, nil)
+}
+
+// CopyFromEx is CopyFrom with a context and options. options may be nil.
+//
+// If ctx is canceled while the copy data is being sent the copy is aborted with
+// a CopyFail message and ctx.Err() is returned. No rows are copied in that case.
+func (c *Conn) CopyFromEx(ctx context.Context, tableName Identifier, columnNames []string, rowSrc CopyFromSource, options *CopyFromOptions) (rowCount int, err error) {
+	if tracer, ok := c.config.Tracer.(CopyFromTracer); ok {
+		ctx = tracer.TraceCopyFromStart(ctx, c, TraceCopyFromStartData{TableName: tableName, ColumnNames: columnNames})
+		defer func() {
+			tracer.TraceCopyFromEnd(ctx, c, TraceCopyFromEndData{RowCount: rowCount, Err: err})
+		}()
+	}
+
 	ct := &copyFrom{
 		conn:          c,
+		ctx:           ctx,
 		tableName:     tableName,
 		columnNames:   columnNames,
 		rowSrc:        rowSrc,
+		options:       options,
 		readerErrChan: make(chan error),
 	}
 
 	return ct.run()
 }
+
+// CopyFromReader uses the PostgreSQL copy protocol to stream the contents of r
+// to the server. sql must be a COPY ... FROM STDIN statement and may specify
+// any options the server supports such as FORMAT csv, HEADER or DELIMITER. The
+// data read from r is sent as is, so it must already be in the format sql
+// describes. It returns the number of rows copied as reported by the server.
+//
+// If reading from r fails the copy is aborted and the read error is returned.
+func (c *Conn) CopyFromReader(ctx context.Context, r io.Reader, sql string) (rowCount int, err error) {
+	if tracer, ok := c.config.Tracer.(CopyFromTracer); ok {
+		ctx = tracer.TraceCopyFromStart(ctx, c, TraceCopyFromStartData{SQL: sql})
+		defer func() {
+			tracer.TraceCopyFromEnd(ctx, c, TraceCopyFromEndData{RowCount: rowCount, Err: err})
+		}()
+	}
+
+	err = c.waitForPreviousCancelQuery(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := c.lock(); err != nil {
+		return 0, err
+	}
+	defer c.unlock()
+
+	c.lastActivityTime = time.Now()
+
+	commandTag, err := c.copyFromReader(ctx, r, sql)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(commandTag.RowsAffected()), nil
+}
+
+func (c *Conn) copyFromReader(ctx context.Context, r io.Reader, sql string) (commandTag CommandTag, err error) {
+	err = c.initContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		err = c.termContext(err)
+	}()
+
+	if err = c.sendSimpleQuery(sql); err != nil {
+		return "", err
+	}
+
+	if err = c.readUntilCopyInResponse(); err != nil {
+		return "", err
+	}
+
+	buf := make([]byte, 5, 65536)
+	buf[0] = copyData
+
+	var readErr error
+	for {
+		n, err := r.Read(buf[5:cap(buf)])
+		if n > 0 {
+			buf = buf[0 : n+5]
+			pgio.SetInt32(buf[1:], int32(n+4))
+
+			_, err := c.conn.Write(buf)
+			if err != nil {
+				c.die(err)
+				return "", err
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			readErr = err
+			if err := c.cancelCopyIn(); err != nil {
+				return "", err
+			}
+			break
+		}
+	}
+
+	if readErr == nil {
+		buf = buf[:0]
+		buf = append(buf, copyDone)
+		buf = pgio.AppendInt32(buf, 4)
+
+		_, err = c.conn.Write(buf)
+		if err != nil {
+			c.die(err)
+			return "", err
+		}
+	}
+
+	var softErr error
+
+	for {
+		msg, err := c.rxMsg()
+		if err != nil {
+			return commandTag, err
+		}
+
+		switch msg := msg.(type) {
+		case *pgproto3.ReadyForQuery:
+			c.rxReadyForQuery(msg)
+			if readErr != nil {
+				return "", readErr
+			}
+			return commandTag, softErr
+		case *pgproto3.CommandComplete:
+			commandTag = CommandTag(msg.CommandTag)
+		default:
+			if e := c.processContextFreeMsg(msg); e != nil && softErr == nil {
+				softErr = e
+			}
+		}
+	}
+}
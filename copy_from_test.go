@@ -1,12 +1,18 @@
 package pgx_test
 
 import (
+	"context"
+	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/pkg/errors"
 	"github.com/ronaldslc/pgx"
+	"github.com/ronaldslc/pgx/pgmock"
+	"github.com/ronaldslc/pgx/pgproto3"
+	"github.com/ronaldslc/pgx/pgtype"
 )
 
 func TestConnCopyFromSmall(t *testing.T) {
@@ -426,3 +432,338 @@ func TestConnCopyFromCopyFromSourceErrorEnd(t *testing.T) {
 
 	ensureConnValid(t, conn)
 }
+
+func TestConnCopyFromExColumnOIDsAndProgress(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnect(t, *defaultConnConfig)
+	defer closeConn(t, conn)
+
+	mustExec(t, conn, `create temporary table foo(
+		a int4,
+		b bytea
+	)`)
+
+	var inputRows [][]interface{}
+	for i := 0; i < 1000; i++ {
+		inputRows = append(inputRows, []interface{}{int32(i), make([]byte, 1000)})
+	}
+
+	var progressCalls int
+	var lastRows, lastBytes int64
+	options := &pgx.CopyFromOptions{
+		ColumnOIDs: []pgtype.OID{pgtype.Int4OID, pgtype.ByteaOID},
+		Progress: func(rows, bytes int64) {
+			progressCalls++
+			if rows < lastRows || bytes <= lastBytes {
+				t.Errorf("Expected progress to increase, but got rows %d -> %d and bytes %d -> %d", lastRows, rows, lastBytes, bytes)
+			}
+			lastRows, lastBytes = rows, bytes
+		},
+	}
+
+	copyCount, err := conn.CopyFromEx(context.Background(), pgx.Identifier{"foo"}, []string{"a", "b"}, pgx.CopyFromRows(inputRows), options)
+	if err != nil {
+		t.Errorf("Unexpected error for CopyFromEx: %v", err)
+	}
+	if copyCount != len(inputRows) {
+		t.Errorf("Expected CopyFromEx to return %d copied rows, but got %d", len(inputRows), copyCount)
+	}
+	if progressCalls < 2 {
+		t.Errorf("Expected Progress to be called more than once, but it was called %d times", progressCalls)
+	}
+	if lastRows != int64(len(inputRows)) {
+		t.Errorf("Expected final progress to report %d rows, but got %d", len(inputRows), lastRows)
+	}
+	if lastBytes < 1000*1000 {
+		t.Errorf("Expected final progress to report at least %d bytes, but got %d", 1000*1000, lastBytes)
+	}
+
+	var n int64
+	if err := conn.QueryRow("select count(*) from foo").Scan(&n); err != nil {
+		t.Fatalf("Unexpected error for QueryRow: %v", err)
+	}
+	if n != int64(len(inputRows)) {
+		t.Errorf("Expected %d rows, but got %d", len(inputRows), n)
+	}
+
+	ensureConnValid(t, conn)
+}
+
+func TestConnCopyFromExFreeze(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnect(t, *defaultConnConfig)
+	defer closeConn(t, conn)
+
+	tx, err := conn.Begin()
+	if err != nil {
+		t.Fatalf("conn.Begin failed: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("create temporary table foo(a int4)"); err != nil {
+		t.Fatalf("tx.Exec failed: %v", err)
+	}
+
+	inputRows := [][]interface{}{{int32(1)}, {int32(2)}}
+
+	copyCount, err := tx.CopyFromEx(context.Background(), pgx.Identifier{"foo"}, []string{"a"}, pgx.CopyFromRows(inputRows), &pgx.CopyFromOptions{Freeze: true})
+	if err != nil {
+		t.Errorf("Unexpected error for CopyFromEx: %v", err)
+	}
+	if copyCount != len(inputRows) {
+		t.Errorf("Expected CopyFromEx to return %d copied rows, but got %d", len(inputRows), copyCount)
+	}
+}
+
+// slowSource is a CopyFromSource of valid rows that takes about a second to
+// produce all of them.
+type slowSource struct {
+	count int
+}
+
+func (ss *slowSource) Next() bool {
+	time.Sleep(time.Millisecond * 10)
+	ss.count++
+	return ss.count < 100
+}
+
+func (ss *slowSource) Values() ([]interface{}, error) {
+	return []interface{}{make([]byte, 100000)}, nil
+}
+
+func (ss *slowSource) Err() error {
+	return nil
+}
+
+func TestConnCopyFromExContextCancelMidway(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnect(t, *defaultConnConfig)
+	defer closeConn(t, conn)
+
+	mustExec(t, conn, `create temporary table foo(
+		a bytea not null
+	)`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 250*time.Millisecond)
+	defer cancel()
+
+	copyCount, err := conn.CopyFromEx(ctx, pgx.Identifier{"foo"}, []string{"a"}, &slowSource{}, nil)
+	if err != context.DeadlineExceeded {
+		t.Errorf("Expected CopyFromEx return context.DeadlineExceeded, but instead it returned: %v", err)
+	}
+	if copyCount != 0 {
+		t.Errorf("Expected CopyFromEx to return 0 copied rows, but got %d", copyCount)
+	}
+
+	var n int64
+	if err := conn.QueryRow("select count(*) from foo").Scan(&n); err != nil {
+		t.Fatalf("Unexpected error for QueryRow: %v", err)
+	}
+	if n != 0 {
+		t.Errorf("Expected 0 rows, but got %d", n)
+	}
+
+	ensureConnValid(t, conn)
+}
+
+// ctxSource is a CopyFromSource that produces one row and then blocks until ctx
+// is done.
+type ctxSource struct {
+	ctx   context.Context
+	count int
+}
+
+func (cs *ctxSource) Next() bool {
+	if cs.count > 0 {
+		<-cs.ctx.Done()
+	}
+	cs.count++
+	return true
+}
+
+func (cs *ctxSource) Values() ([]interface{}, error) {
+	return []interface{}{[]byte("abc")}, nil
+}
+
+func (cs *ctxSource) Err() error {
+	return nil
+}
+
+func TestConnCopyFromExAbortTimeout(t *testing.T) {
+	t.Parallel()
+
+	script := &pgmock.Script{
+		Steps: pgmock.AcceptUnauthenticatedConnRequestSteps(),
+	}
+	script.Steps = append(script.Steps, pgmock.PgxInitSteps()...)
+	script.Steps = append(script.Steps,
+		pgmock.ExpectMessage(&pgproto3.Query{String: `copy "foo" ( "a" ) from stdin binary;`}),
+		pgmock.SendMessage(&pgproto3.CopyInResponse{OverallFormat: 1, ColumnFormatCodes: []uint16{1}}),
+		pgmock.WaitForClose(), // never acknowledges the CopyFail
+	)
+
+	server, err := pgmock.NewServer(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- server.ServeOne()
+	}()
+
+	mockConfig, err := pgx.ParseURI(fmt.Sprintf("postgres://pgx_md5:secret@%s/pgx_test?sslmode=disable", server.Addr()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn := mustConnect(t, mockConfig)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	startTime := time.Now()
+
+	options := &pgx.CopyFromOptions{ColumnOIDs: []pgtype.OID{pgtype.ByteaOID}, AbortTimeout: 100 * time.Millisecond}
+	_, err = conn.CopyFromEx(ctx, pgx.Identifier{"foo"}, []string{"a"}, &ctxSource{ctx: ctx}, options)
+	if err != context.DeadlineExceeded {
+		t.Errorf("err => %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	if copyTime := time.Since(startTime); copyTime > time.Second {
+		t.Errorf("Aborted CopyFromEx should have given up after AbortTimeout, but took %v", copyTime)
+	}
+
+	if conn.IsAlive() {
+		t.Error("expected conn to be dead after the abort timed out")
+	}
+
+	if err := <-errChan; err != nil {
+		t.Errorf("mock server err: %v", err)
+	}
+}
+
+func TestConnCopyFromReaderCSV(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnect(t, *defaultConnConfig)
+	defer closeConn(t, conn)
+
+	mustExec(t, conn, `create temporary table foo(
+		a int4,
+		b varchar
+	)`)
+
+	input := "a;b\n1;abc\n2;\"d;ef\"\n3;\n"
+
+	copyCount, err := conn.CopyFromReader(context.Background(), strings.NewReader(input), "copy foo (a, b) from stdin with (format csv, header, delimiter ';')")
+	if err != nil {
+		t.Errorf("Unexpected error for CopyFromReader: %v", err)
+	}
+	if copyCount != 3 {
+		t.Errorf("Expected CopyFromReader to return 3 copied rows, but got %d", copyCount)
+	}
+
+	rows, err := conn.Query("select a, b from foo order by a")
+	if err != nil {
+		t.Errorf("Unexpected error for Query: %v", err)
+	}
+
+	var outputRows [][]interface{}
+	for rows.Next() {
+		row, err := rows.Values()
+		if err != nil {
+			t.Errorf("Unexpected error for rows.Values(): %v", err)
+		}
+		outputRows = append(outputRows, row)
+	}
+
+	if rows.Err() != nil {
+		t.Errorf("Unexpected error for rows.Err(): %v", rows.Err())
+	}
+
+	expectedRows := [][]interface{}{
+		{int32(1), "abc"},
+		{int32(2), "d;ef"},
+		{int32(3), nil},
+	}
+	if !reflect.DeepEqual(expectedRows, outputRows) {
+		t.Errorf("Expected rows and output rows do not equal: %v -> %v", expectedRows, outputRows)
+	}
+
+	ensureConnValid(t, conn)
+}
+
+func TestConnCopyFromReaderFailServerSide(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnect(t, *defaultConnConfig)
+	defer closeConn(t, conn)
+
+	mustExec(t, conn, `create temporary table foo(
+		a int4 not null
+	)`)
+
+	copyCount, err := conn.CopyFromReader(context.Background(), strings.NewReader("1\nabc\n3\n"), "copy foo (a) from stdin with (format csv)")
+	if _, ok := err.(pgx.PgError); !ok {
+		t.Errorf("Expected CopyFromReader return pgx.PgError, but instead it returned: %v", err)
+	}
+	if copyCount != 0 {
+		t.Errorf("Expected CopyFromReader to return 0 copied rows, but got %d", copyCount)
+	}
+
+	var n int64
+	if err := conn.QueryRow("select count(*) from foo").Scan(&n); err != nil {
+		t.Fatalf("Unexpected error for QueryRow: %v", err)
+	}
+	if n != 0 {
+		t.Errorf("Expected 0 rows, but got %d", n)
+	}
+
+	ensureConnValid(t, conn)
+}
+
+type failReader struct {
+	n int
+}
+
+func (fr *failReader) Read(p []byte) (int, error) {
+	if fr.n > 2 {
+		return 0, errors.Errorf("read failed")
+	}
+	fr.n++
+	return copy(p, "1\n"), nil
+}
+
+func TestConnCopyFromReaderReadErrorMidway(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnect(t, *defaultConnConfig)
+	defer closeConn(t, conn)
+
+	mustExec(t, conn, `create temporary table foo(
+		a int4
+	)`)
+
+	copyCount, err := conn.CopyFromReader(context.Background(), &failReader{}, "copy foo (a) from stdin with (format csv)")
+	if err == nil || err.Error() != "read failed" {
+		t.Errorf("Expected CopyFromReader return read error, but instead it returned: %v", err)
+	}
+	if copyCount != 0 {
+		t.Errorf("Expected CopyFromReader to return 0 copied rows, but got %d", copyCount)
+	}
+
+	var n int64
+	if err := conn.QueryRow("select count(*) from foo").Scan(&n); err != nil {
+		t.Fatalf("Unexpected error for QueryRow: %v", err)
+	}
+	if n != 0 {
+		t.Errorf("Expected 0 rows, but got %d", n)
+	}
+
+	ensureConnValid(t, conn)
+}
@@ -207,6 +207,21 @@ implement CopyFromSource to avoid buffering the entire data set in memory.
 
 CopyFrom can be faster than an insert with as few as 5 rows.
 
+CopyFromEx additionally accepts a context.Context and a *CopyFromOptions.
+Canceling the context aborts the copy. The options can supply the column OIDs
+to skip the round trip used to look up the column types, request FREEZE, and
+register a callback that reports progress.
+
+Use CopyFromReader when the data is already in a format the server understands
+such as CSV. The COPY statement and its options are supplied by the caller and
+the contents of the io.Reader are streamed to the server unmodified.
+
+    copyCount, err := conn.CopyFromReader(
+        context.Background(),
+        f,
+        "copy people (first_name, last_name, age) from stdin with (format csv, header)",
+    )
+
 Listen and Notify
 
 pgx can listen to the PostgreSQL notification system with the
@@ -233,7 +248,22 @@ Logging
 
 pgx defines a simple logger interface. Connections optionally accept a logger
 that satisfies this interface. Set LogLevel to control logging verbosity.
-Adapters for github.com/inconshreveable/log15, github.com/sirupsen/logrus, and
-the testing log are provided in the log directory.
+Adapters for github.com/inconshreveable/log15, github.com/sirupsen/logrus,
+go.uber.org/zap, github.com/rs/zerolog, github.com/go-kit/log, the standard
+library log package and the testing log are provided in the log directory.
+
+A ContextLogger receives the context.Context passed to methods such as QueryEx
+and ExecEx in addition to the log entry. Use it to add request scoped fields
+such as request IDs to pgx log entries. Each adapter in the log directory
+provides a ContextLogger that takes a function to extract such fields.
+
+Successful queries are logged at LogLevelInfo. Set ConnConfig.SlowQueryThreshold
+to log queries that take at least that long at LogLevelWarn instead, so a
+LogLevel of LogLevelWarn logs only slow queries.
+
+Set ConnConfig.QueryStats to a QueryStatsCollector to aggregate the count,
+latency and rows of queries per normalized SQL fingerprint. It is available
+from Conn.QueryStats and ConnPool.QueryStats, e.g. for a debug endpoint. A
+collector keeps a bounded number of fingerprints, see SetMaxFingerprints.
 */
 package pgx
@@ -9,12 +9,22 @@ import (
 )
 
 func newFastpath(cn *Conn) *fastpath {
-	return &fastpath{cn: cn, fns: make(map[string]pgtype.OID)}
+	return &fastpath{
+		cn:          cn,
+		fns:         make(map[string]pgtype.OID),
+		procsByName: make(map[string][]*fpProc),
+		procsByOID:  make(map[pgtype.OID]*fpProc),
+	}
 }
 
 type fastpath struct {
 	cn  *Conn
 	fns map[string]pgtype.OID
+
+	// procsByName and procsByOID cache the functions looked up by
+	// Conn.FunctionCall.
+	procsByName map[string][]*fpProc
+	procsByOID  map[pgtype.OID]*fpProc
 }
 
 func (f *fastpath) functionOID(name string) pgtype.OID {
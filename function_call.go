@@ -0,0 +1,358 @@
+package pgx
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ronaldslc/pgx/pgproto3"
+	"github.com/ronaldslc/pgx/pgtype"
+)
+
+// fpProc is a function that can be called with FunctionCall.
+type fpProc struct {
+	oid       pgtype.OID
+	name      string
+	argOIDs   []pgtype.OID
+	resultOID pgtype.OID
+}
+
+const fpProcColumns = `select p.oid, p.proname, array_to_string(p.proargtypes, ' '), p.prorettype
+from pg_catalog.pg_proc p `
+
+// FunctionResult is the result of FunctionCall. Scan must be called to learn
+// about an error that occurred while calling the function.
+type FunctionResult struct {
+	conn      *Conn
+	resultOID pgtype.OID
+	result    []byte
+	err       error
+}
+
+// Err returns any error that occurred while calling the function.
+func (r *FunctionResult) Err() error {
+	return r.err
+}
+
+// Scan decodes the result of the function into dest. It works the same as
+// (*Rows Scan) for a single column. A NULL result can be scanned into the
+// same destinations as a NULL column.
+func (r *FunctionResult) Scan(dest interface{}) error {
+	if r.err != nil {
+		return r.err
+	}
+
+	if dest == nil {
+		return nil
+	}
+
+	ci := r.conn.ConnInfo
+
+	if d, ok := dest.(pgtype.BinaryDecoder); ok {
+		return d.DecodeBinary(ci, r.result)
+	}
+
+	dt, ok := ci.DataTypeForOID(r.resultOID)
+	if !ok {
+		return errors.Errorf("unknown oid: %v", r.resultOID)
+	}
+
+	value := dt.Value
+	binaryDecoder, ok := value.(pgtype.BinaryDecoder)
+	if !ok {
+		return errors.Errorf("%T is not a pgtype.BinaryDecoder", value)
+	}
+	if err := binaryDecoder.DecodeBinary(ci, r.result); err != nil {
+		return err
+	}
+
+	if scanner, ok := dest.(sql.Scanner); ok {
+		sqlSrc, err := pgtype.DatabaseSQLValue(ci, value)
+		if err != nil {
+			return err
+		}
+		return scanner.Scan(sqlSrc)
+	}
+
+	return value.AssignTo(dest)
+}
+
+// FunctionCall calls a function with the fastpath interface of the protocol
+// and returns its result. fn is either the pgtype.OID of the function or its
+// name as a string. A name may be schema qualified and is otherwise resolved
+// with the search_path. The arguments are encoded the same as query
+// arguments, using the argument types of the function.
+//
+// The function OID and argument types are looked up in pg_proc on first use
+// and cached on the connection. When several functions of the same name take
+// len(args) arguments the one whose argument types match the Go types of args
+// is used. If that is ambiguous fn can include the argument types, e.g.
+// "lo_lseek64(integer, bigint, integer)".
+//
+//	var oid pgtype.OID
+//	err := conn.FunctionCall(ctx, "lo_creat", -1).Scan(&oid)
+//
+// Set returning functions can not be called.
+func (c *Conn) FunctionCall(ctx context.Context, fn interface{}, args ...interface{}) *FunctionResult {
+	r := &FunctionResult{conn: c}
+
+	proc, err := c.lookupFunction(ctx, fn, args)
+	if err != nil {
+		r.err = err
+		return r
+	}
+	r.resultOID = proc.resultOID
+
+	startTime := time.Now()
+	r.result, r.err = c.functionCall(ctx, proc, args)
+	if r.err != nil {
+		if c.shouldLog(LogLevelError) {
+			var ld LogData
+			ld.Add("function", proc.name)
+			ld.Add("err", r.err)
+			c.addLogArgs(&ld, proc.name, args)
+			c.log(ctx, LogLevelError, "FunctionCall", ld)
+		}
+		return r
+	}
+
+	if c.shouldLog(LogLevelInfo) {
+		var ld LogData
+		ld.Add("time", time.Now().Sub(startTime))
+		ld.Add("function", proc.name)
+		c.addLogArgs(&ld, proc.name, args)
+		c.log(ctx, LogLevelInfo, "FunctionCall", ld)
+	}
+
+	return r
+}
+
+func (c *Conn) functionCall(ctx context.Context, proc *fpProc, args []interface{}) (result []byte, err error) {
+	err = c.waitForPreviousCancelQuery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.lock(); err != nil {
+		return nil, err
+	}
+	defer c.unlock()
+
+	c.lastActivityTime = time.Now()
+
+	if err := c.ensureConnectionReadyForQuery(); err != nil {
+		return nil, err
+	}
+
+	err = c.initContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err = c.termContext(err)
+	}()
+
+	buf, err := appendFunctionCall(c.wbuf, proc.oid, c.ConnInfo, proc.argOIDs, args)
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := c.conn.Write(buf)
+	if err != nil && fatalWriteErr(n, err) {
+		c.die(err)
+		return nil, err
+	}
+	c.pendingReadyForQueryCount++
+
+	var softErr error
+	for {
+		msg, err := c.rxMsg()
+		if err != nil {
+			return nil, err
+		}
+
+		switch msg := msg.(type) {
+		case *pgproto3.FunctionCallResponse:
+			if msg.Result != nil {
+				result = make([]byte, len(msg.Result))
+				copy(result, msg.Result)
+			}
+		case *pgproto3.ReadyForQuery:
+			c.rxReadyForQuery(msg)
+			return result, softErr
+		default:
+			if err := c.processContextFreeMsg(msg); err != nil && softErr == nil {
+				softErr = err
+			}
+		}
+	}
+}
+
+func (c *Conn) lookupFunction(ctx context.Context, fn interface{}, args []interface{}) (*fpProc, error) {
+	if c.fp == nil {
+		c.fp = newFastpath(c)
+	}
+
+	switch fn := fn.(type) {
+	case pgtype.OID:
+		if proc, ok := c.fp.procsByOID[fn]; ok {
+			return proc, nil
+		}
+		procs, err := c.queryFunctions(ctx, fpProcColumns+"where p.oid = $1 and not p.proretset", fn)
+		if err != nil {
+			return nil, err
+		}
+		if len(procs) == 0 {
+			return nil, errors.Errorf("function %d does not exist", fn)
+		}
+		c.fp.procsByOID[fn] = procs[0]
+		return procs[0], nil
+	case string:
+		procs, ok := c.fp.procsByName[fn]
+		if !ok {
+			var err error
+			procs, err = c.queryFunctionsByName(ctx, fn)
+			if err != nil {
+				return nil, err
+			}
+			if len(procs) == 0 {
+				return nil, errors.Errorf("function %s does not exist", fn)
+			}
+			c.fp.procsByName[fn] = procs
+		}
+		return c.chooseFunction(fn, procs, args)
+	default:
+		return nil, errors.Errorf("FunctionCall requires a function name or pgtype.OID, but got %T", fn)
+	}
+}
+
+func (c *Conn) queryFunctionsByName(ctx context.Context, name string) ([]*fpProc, error) {
+	if strings.Contains(name, "(") {
+		return c.queryFunctions(ctx, fpProcColumns+"where p.oid = $1::text::regprocedure and not p.proretset", name)
+	}
+
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		return c.queryFunctions(ctx, fpProcColumns+`join pg_catalog.pg_namespace n on n.oid = p.pronamespace
+where n.nspname = $1 and p.proname = $2 and not p.proretset`, name[:i], name[i+1:])
+	}
+
+	return c.queryFunctions(ctx, fpProcColumns+"where p.proname = $1 and pg_catalog.pg_function_is_visible(p.oid) and not p.proretset", name)
+}
+
+func (c *Conn) queryFunctions(ctx context.Context, sql string, args ...interface{}) ([]*fpProc, error) {
+	rows, err := c.QueryEx(ctx, 0, sql, nil, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var procs []*fpProc
+	for rows.Next() {
+		proc := &fpProc{}
+		var argTypes string
+		if err := rows.Scan(&proc.oid, &proc.name, &argTypes, &proc.resultOID); err != nil {
+			return nil, err
+		}
+
+		for _, s := range strings.Fields(argTypes) {
+			oid, err := strconv.ParseUint(s, 10, 32)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid argument types of function %s", proc.name)
+			}
+			proc.argOIDs = append(proc.argOIDs, pgtype.OID(oid))
+		}
+
+		procs = append(procs, proc)
+	}
+
+	return procs, rows.Err()
+}
+
+// chooseFunction returns the function of procs that takes args. If several do
+// the one with the most argument types matching the Go types of args is
+// chosen. Arguments of unknown Go type match any argument type.
+func (c *Conn) chooseFunction(name string, procs []*fpProc, args []interface{}) (*fpProc, error) {
+	var candidates []*fpProc
+	for _, proc := range procs {
+		if len(proc.argOIDs) == len(args) {
+			candidates = append(candidates, proc)
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		return nil, errors.Errorf("function %s does not take %d arguments", name, len(args))
+	case 1:
+		return candidates[0], nil
+	}
+
+	argOIDs := make([]pgtype.OID, len(args))
+	for i, arg := range args {
+		argOIDs[i] = c.goTypeOID(arg)
+	}
+
+	var best *fpProc
+	bestScore, ties := -1, 0
+	for _, proc := range candidates {
+		score := 0
+		for i, oid := range proc.argOIDs {
+			if argOIDs[i] == 0 {
+				continue
+			}
+			if argOIDs[i] == oid {
+				score++
+			} else {
+				score = -1
+				break
+			}
+		}
+
+		if score > bestScore {
+			best, bestScore, ties = proc, score, 0
+		} else if score == bestScore {
+			ties++
+		}
+	}
+
+	if best == nil || bestScore < 0 || ties > 0 {
+		return nil, errors.Errorf("function %s with %d arguments is ambiguous, include the argument types in the name, e.g. %s(integer)", name, len(args), name)
+	}
+
+	return best, nil
+}
+
+// goTypeOID returns the OID of the data type arg naturally maps to or 0 if
+// unknown.
+func (c *Conn) goTypeOID(arg interface{}) pgtype.OID {
+	switch arg.(type) {
+	case bool:
+		return pgtype.BoolOID
+	case int16:
+		return pgtype.Int2OID
+	case int32:
+		return pgtype.Int4OID
+	case int, int64:
+		return pgtype.Int8OID
+	case float32:
+		return pgtype.Float4OID
+	case float64:
+		return pgtype.Float8OID
+	case string:
+		return pgtype.TextOID
+	case []byte:
+		return pgtype.ByteaOID
+	case time.Time:
+		return pgtype.TimestamptzOID
+	case pgtype.OID:
+		return pgtype.OIDOID
+	case pgtype.Value:
+		if dt, ok := c.ConnInfo.DataTypeForValue(arg.(pgtype.Value)); ok {
+			return dt.OID
+		}
+	}
+	return 0
+}
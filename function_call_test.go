@@ -0,0 +1,132 @@
+package pgx_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ronaldslc/pgx"
+	"github.com/ronaldslc/pgx/pgtype"
+)
+
+func TestConnFunctionCall(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnect(t, *defaultConnConfig)
+	defer closeConn(t, conn)
+
+	ctx := context.Background()
+
+	var s string
+	if err := conn.FunctionCall(ctx, "lower", "ABC").Scan(&s); err != nil {
+		t.Fatal(err)
+	}
+	if s != "abc" {
+		t.Errorf("Expected %q, got %q", "abc", s)
+	}
+
+	var n int64
+	if err := conn.FunctionCall(ctx, "pg_catalog.int8pl", int64(40), int64(2)).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 42 {
+		t.Errorf("Expected 42, got %d", n)
+	}
+
+	// abs has an overload per numeric type, chosen by the Go type of the argument
+	var f float64
+	if err := conn.FunctionCall(ctx, "abs", float64(-1.5)).Scan(&f); err != nil {
+		t.Fatal(err)
+	}
+	if f != 1.5 {
+		t.Errorf("Expected 1.5, got %v", f)
+	}
+
+	var i int32
+	if err := conn.FunctionCall(ctx, "abs(integer)", "-7").Scan(&i); err != nil {
+		t.Fatal(err)
+	}
+	if i != 7 {
+		t.Errorf("Expected 7, got %d", i)
+	}
+
+	var oid pgtype.OID
+	if err := conn.QueryRow("select 'upper(text)'::regprocedure::oid").Scan(&oid); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.FunctionCall(ctx, oid, "abc").Scan(&s); err != nil {
+		t.Fatal(err)
+	}
+	if s != "ABC" {
+		t.Errorf("Expected %q, got %q", "ABC", s)
+	}
+
+	var text pgtype.Text
+	if err := conn.FunctionCall(ctx, "lower(text)", nil).Scan(&text); err != nil {
+		t.Fatal(err)
+	}
+	if text.Status != pgtype.Null {
+		t.Errorf("Expected NULL, got %v", text)
+	}
+
+	ensureConnValid(t, conn)
+}
+
+func TestConnFunctionCallErrors(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnect(t, *defaultConnConfig)
+	defer closeConn(t, conn)
+
+	ctx := context.Background()
+
+	var s string
+	err := conn.FunctionCall(ctx, "pgx_no_such_function", "a").Scan(&s)
+	if err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("Expected does not exist error, got %v", err)
+	}
+
+	err = conn.FunctionCall(ctx, "lower").Scan(&s)
+	if err == nil || !strings.Contains(err.Error(), "does not take 0 arguments") {
+		t.Errorf("Expected argument count error, got %v", err)
+	}
+
+	var n int32
+	err = conn.FunctionCall(ctx, "int4div", int32(1), int32(0)).Scan(&n)
+	if pgErr, ok := err.(pgx.PgError); !ok || pgErr.Code != "22012" {
+		t.Errorf("Expected division_by_zero, got %v", err)
+	}
+
+	ensureConnValid(t, conn)
+}
+
+func TestTxFunctionCall(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnect(t, *defaultConnConfig)
+	defer closeConn(t, conn)
+
+	tx, err := conn.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var s string
+	if err := tx.FunctionCall(context.Background(), "upper", "abc").Scan(&s); err != nil {
+		t.Fatal(err)
+	}
+	if s != "ABC" {
+		t.Errorf("Expected %q, got %q", "ABC", s)
+	}
+
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+
+	err = tx.FunctionCall(context.Background(), "upper", "abc").Scan(&s)
+	if err != pgx.ErrTxClosed {
+		t.Errorf("Expected ErrTxClosed, got %v", err)
+	}
+
+	ensureConnValid(t, conn)
+}
@@ -0,0 +1,24 @@
+// Package pgversion parses the server_version reported by PostgreSQL.
+package pgversion
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Major returns the major version of serverVersion, for example 14 for
+// "14.5", 16 for "16beta1" or 9 for "9.6.3".
+func Major(serverVersion string) (int, error) {
+	end := strings.IndexFunc(serverVersion, func(r rune) bool { return r < '0' || r > '9' })
+	if end == -1 {
+		end = len(serverVersion)
+	}
+
+	major, err := strconv.Atoi(serverVersion[:end])
+	if err != nil {
+		return 0, errors.Errorf("invalid server version: %q", serverVersion)
+	}
+	return major, nil
+}
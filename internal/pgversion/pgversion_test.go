@@ -0,0 +1,37 @@
+package pgversion_test
+
+import (
+	"testing"
+
+	"github.com/ronaldslc/pgx/internal/pgversion"
+)
+
+func TestMajor(t *testing.T) {
+	tests := []struct {
+		serverVersion string
+		major         int
+	}{
+		{"9.6.3", 9},
+		{"14.5", 14},
+		{"13.11 (Debian 13.11-1.pgdg110+1)", 13},
+		{"16beta1", 16},
+		{"17", 17},
+	}
+
+	for _, tt := range tests {
+		major, err := pgversion.Major(tt.serverVersion)
+		if err != nil {
+			t.Errorf("%q: %v", tt.serverVersion, err)
+			continue
+		}
+		if major != tt.major {
+			t.Errorf("%q: expected %d, but got %d", tt.serverVersion, tt.major, major)
+		}
+	}
+
+	for _, serverVersion := range []string{"", "devel"} {
+		if _, err := pgversion.Major(serverVersion); err == nil {
+			t.Errorf("%q: expected error but got none", serverVersion)
+		}
+	}
+}
@@ -1,7 +1,9 @@
 package pgx
 
 import (
+	"context"
 	"io"
+	"sync"
 
 	"github.com/ronaldslc/pgx/pgtype"
 )
@@ -53,6 +55,100 @@ func (tx *Tx) LargeObjects() (*LargeObjects, error) {
 	return lo, nil
 }
 
+// WithLargeObjects acquires a connection, begins a transaction and calls f
+// with the LargeObjects of the transaction. The transaction is committed if f
+// returns nil and rolled back otherwise. The connection is released before
+// WithLargeObjects returns.
+func (p *ConnPool) WithLargeObjects(ctx context.Context, f func(*LargeObjects) error) error {
+	tx, err := p.BeginEx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	lo, err := tx.LargeObjects()
+	if err != nil {
+		return err
+	}
+
+	if err := f(lo); err != nil {
+		return err
+	}
+
+	return tx.CommitEx(ctx)
+}
+
+// OpenReader opens the large object oid for reading in a read only
+// transaction on a pool connection. The connection is held until the returned
+// LargeObjectReader is closed.
+func (p *ConnPool) OpenReader(oid pgtype.OID) (*LargeObjectReader, error) {
+	tx, err := p.BeginEx(context.Background(), &TxOptions{AccessMode: ReadOnly})
+	if err != nil {
+		return nil, err
+	}
+
+	lo, err := tx.LargeObjects()
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	obj, err := lo.Open(oid, LargeObjectModeRead)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	return &LargeObjectReader{tx: tx, obj: obj}, nil
+}
+
+// LargeObjectReader is a large object opened by ConnPool.OpenReader. It
+// implements these interfaces:
+//
+//    io.Reader
+//    io.Seeker
+//    io.Closer
+//    io.ReaderAt
+//    io.WriterTo
+//
+// ReadAt may be called in parallel. The other methods are not safe for
+// concurrent usage.
+type LargeObjectReader struct {
+	tx  *Tx
+	obj *LargeObject
+}
+
+// Read reads up to len(p) bytes into p returning the number of bytes read.
+func (r *LargeObjectReader) Read(p []byte) (int, error) {
+	return r.obj.Read(p)
+}
+
+// Seek moves the current location pointer to the new location specified by
+// offset.
+func (r *LargeObjectReader) Seek(offset int64, whence int) (int64, error) {
+	return r.obj.Seek(offset, whence)
+}
+
+// ReadAt reads len(p) bytes starting at offset off. See LargeObject.ReadAt.
+func (r *LargeObjectReader) ReadAt(p []byte, off int64) (int, error) {
+	return r.obj.ReadAt(p, off)
+}
+
+// WriteTo writes the large object from the current location to its end to w.
+func (r *LargeObjectReader) WriteTo(w io.Writer) (int64, error) {
+	return r.obj.WriteTo(w)
+}
+
+// Close closes the large object, ends the transaction and releases the
+// connection back to the pool.
+func (r *LargeObjectReader) Close() error {
+	err := r.obj.Close()
+	if rollbackErr := r.tx.Rollback(); rollbackErr != nil && err == nil {
+		err = rollbackErr
+	}
+	return err
+}
+
 type LargeObjectMode int32
 
 const (
@@ -79,6 +175,49 @@ func (o *LargeObjects) Unlink(oid pgtype.OID) error {
 	return err
 }
 
+// Import creates a new large object with the contents of r and returns its
+// OID. r is streamed to the server in chunks.
+func (o *LargeObjects) Import(r io.Reader) (pgtype.OID, error) {
+	oid, err := o.Create(0)
+	if err != nil {
+		return 0, err
+	}
+
+	obj, err := o.Open(oid, LargeObjectModeWrite)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := obj.ReadFrom(r); err != nil {
+		obj.Close()
+		return 0, err
+	}
+
+	if err := obj.Close(); err != nil {
+		return 0, err
+	}
+
+	return oid, nil
+}
+
+// Export writes the contents of the large object oid to w and returns the
+// number of bytes written. The large object is streamed from the server in
+// chunks.
+func (o *LargeObjects) Export(oid pgtype.OID, w io.Writer) (int64, error) {
+	obj, err := o.Open(oid, LargeObjectModeRead)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := obj.WriteTo(w)
+	if err != nil {
+		obj.Close()
+		return n, err
+	}
+
+	return n, obj.Close()
+}
+
 // A LargeObject is a large object stored on the server. It is only valid within
 // the transaction that it was initialized in. It implements these interfaces:
 //
@@ -86,29 +225,61 @@ func (o *LargeObjects) Unlink(oid pgtype.OID) error {
 //    io.Reader
 //    io.Seeker
 //    io.Closer
+//    io.ReaderAt
+//    io.WriterAt
+//    io.ReaderFrom
+//    io.WriterTo
+//
+// The methods of a LargeObject are serialized, so ReadAt and WriteAt may be
+// called in parallel as io.ReaderAt and io.WriterAt require. The connection
+// must not be used for anything else in the meantime.
 type LargeObject struct {
 	fd int32
 	lo *LargeObjects
+
+	mux sync.Mutex
+	pos int64 // current location, tracked so ReadAt and WriteAt can restore it without lo_tell
 }
 
 // Write writes p to the large object and returns the number of bytes written
 // and an error if not all of p was written.
 func (o *LargeObject) Write(p []byte) (int, error) {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+	return o.write(p)
+}
+
+func (o *LargeObject) write(p []byte) (int, error) {
 	n, err := fpInt32(o.lo.fp.CallFn("lowrite", []fpArg{fpIntArg(o.fd), p}))
+	o.pos += int64(n)
 	return int(n), err
 }
 
 // Read reads up to len(p) bytes into p returning the number of bytes read.
 func (o *LargeObject) Read(p []byte) (int, error) {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+	return o.read(p)
+}
+
+func (o *LargeObject) read(p []byte) (int, error) {
 	res, err := o.lo.fp.CallFn("loread", []fpArg{fpIntArg(o.fd), fpIntArg(int32(len(p)))})
 	if len(res) < len(p) {
 		err = io.EOF
 	}
-	return copy(p, res), err
+	n := copy(p, res)
+	o.pos += int64(n)
+	return n, err
 }
 
 // Seek moves the current location pointer to the new location specified by offset.
 func (o *LargeObject) Seek(offset int64, whence int) (n int64, err error) {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+	return o.seek(offset, whence)
+}
+
+func (o *LargeObject) seek(offset int64, whence int) (n int64, err error) {
 	if o.lo.Has64 {
 		n, err = fpInt64(o.lo.fp.CallFn("lo_lseek64", []fpArg{fpIntArg(o.fd), fpInt64Arg(offset), fpIntArg(int32(whence))}))
 	} else {
@@ -116,12 +287,18 @@ func (o *LargeObject) Seek(offset int64, whence int) (n int64, err error) {
 		n32, err = fpInt32(o.lo.fp.CallFn("lo_lseek", []fpArg{fpIntArg(o.fd), fpIntArg(int32(offset)), fpIntArg(int32(whence))}))
 		n = int64(n32)
 	}
+	if err == nil {
+		o.pos = n
+	}
 	return
 }
 
 // Tell returns the current read or write location of the large object
 // descriptor.
 func (o *LargeObject) Tell() (n int64, err error) {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+
 	if o.lo.Has64 {
 		n, err = fpInt64(o.lo.fp.CallFn("lo_tell64", []fpArg{fpIntArg(o.fd)}))
 	} else {
@@ -129,11 +306,17 @@ func (o *LargeObject) Tell() (n int64, err error) {
 		n32, err = fpInt32(o.lo.fp.CallFn("lo_tell", []fpArg{fpIntArg(o.fd)}))
 		n = int64(n32)
 	}
+	if err == nil {
+		o.pos = n
+	}
 	return
 }
 
 // Trunctes the large object to size.
 func (o *LargeObject) Truncate(size int64) (err error) {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+
 	if o.lo.Has64 {
 		_, err = o.lo.fp.CallFn("lo_truncate64", []fpArg{fpIntArg(o.fd), fpInt64Arg(size)})
 	} else {
@@ -142,8 +325,134 @@ func (o *LargeObject) Truncate(size int64) (err error) {
 	return
 }
 
+// largeObjectChunkSize is the most data ReadAt, WriteAt, ReadFrom and WriteTo
+// transfer with a single loread or lowrite call.
+const largeObjectChunkSize = 256 * 1024
+
+// ReadAt reads len(p) bytes starting at offset off. It returns io.EOF if the
+// large object ends before p is filled. The current location is restored
+// afterwards so ReadAt does not affect Read and Write.
+//
+// ReadAt seeks to off, reads in chunks and seeks back, so it costs at least
+// three round trips. Parallel calls are serialized.
+func (o *LargeObject) ReadAt(p []byte, off int64) (n int, err error) {
+	err = o.at(off, func() error {
+		for n < len(p) {
+			end := n + largeObjectChunkSize
+			if end > len(p) {
+				end = len(p)
+			}
+
+			rn, err := o.read(p[n:end])
+			n += rn
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return n, err
+}
+
+// WriteAt writes p starting at offset off. The current location is restored
+// afterwards so WriteAt does not affect Read and Write.
+//
+// Like ReadAt it costs at least three round trips and parallel calls are
+// serialized.
+func (o *LargeObject) WriteAt(p []byte, off int64) (n int, err error) {
+	err = o.at(off, func() error {
+		for n < len(p) {
+			end := n + largeObjectChunkSize
+			if end > len(p) {
+				end = len(p)
+			}
+
+			wn, err := o.write(p[n:end])
+			n += wn
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return n, err
+}
+
+// at calls f with the current location moved to off and restores the
+// current location afterwards. o.mux is held while f is called.
+func (o *LargeObject) at(off int64, f func() error) error {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+
+	pos := o.pos
+	if _, err := o.seek(off, io.SeekStart); err != nil {
+		return err
+	}
+
+	err := f()
+
+	if _, seekErr := o.seek(pos, io.SeekStart); seekErr != nil && err == nil {
+		err = seekErr
+	}
+	return err
+}
+
+// ReadFrom writes the data read from r to the large object at the current
+// location until r returns io.EOF.
+func (o *LargeObject) ReadFrom(r io.Reader) (n int64, err error) {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+
+	buf := make([]byte, largeObjectChunkSize)
+	for {
+		rn, readErr := r.Read(buf)
+		if rn > 0 {
+			wn, err := o.write(buf[:rn])
+			n += int64(wn)
+			if err != nil {
+				return n, err
+			}
+		}
+
+		if readErr == io.EOF {
+			return n, nil
+		}
+		if readErr != nil {
+			return n, readErr
+		}
+	}
+}
+
+// WriteTo writes the large object from the current location to its end to w.
+func (o *LargeObject) WriteTo(w io.Writer) (n int64, err error) {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+
+	buf := make([]byte, largeObjectChunkSize)
+	for {
+		rn, readErr := o.read(buf)
+		if rn > 0 {
+			wn, err := w.Write(buf[:rn])
+			n += int64(wn)
+			if err != nil {
+				return n, err
+			}
+		}
+
+		if readErr == io.EOF {
+			return n, nil
+		}
+		if readErr != nil {
+			return n, readErr
+		}
+	}
+}
+
 // Close closees the large object descriptor.
 func (o *LargeObject) Close() error {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+
 	_, err := o.lo.fp.CallFn("lo_close", []fpArg{fpIntArg(o.fd)})
 	return err
 }
@@ -1,10 +1,16 @@
 package pgx_test
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"io"
+	"io/ioutil"
+	"sync"
 	"testing"
 
 	"github.com/ronaldslc/pgx"
+	"github.com/ronaldslc/pgx/pgtype"
 )
 
 func TestLargeObjects(t *testing.T) {
@@ -119,3 +125,233 @@ func TestLargeObjects(t *testing.T) {
 		t.Errorf("Expected undefined_object error (42704), got %#v", err)
 	}
 }
+
+func TestLargeObjectReadAtWriteAt(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnect(t, *defaultConnConfig)
+	defer closeConn(t, conn)
+
+	tx, err := conn.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	lo, err := tx.LargeObjects()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := lo.Create(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	obj, err := lo.Open(id, pgx.LargeObjectModeRead|pgx.LargeObjectModeWrite)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Larger than a single chunk
+	data := bytes.Repeat([]byte("0123456789"), 60000)
+	n, err := obj.WriteAt(data, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(data) {
+		t.Errorf("Expected n to be %d, got %d", len(data), n)
+	}
+
+	pos, err := obj.Tell()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pos != 0 {
+		t.Errorf("Expected WriteAt to keep pos at 0, got %d", pos)
+	}
+
+	res := make([]byte, len(data))
+	n, err = obj.ReadAt(res, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(data) || !bytes.Equal(res, data) {
+		t.Errorf("ReadAt returned %d bytes that do not match the written data", n)
+	}
+
+	res = make([]byte, 10)
+	n, err = obj.ReadAt(res, int64(len(data)))
+	if err != io.EOF {
+		t.Errorf("Expected err to be io.EOF, got %v", err)
+	}
+	if n != 5 || string(res[:n]) != "56789" {
+		t.Errorf(`Expected "56789", got %q`, res[:n])
+	}
+
+	// ReadAt may be called in parallel
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(off int64) {
+			defer wg.Done()
+			res := make([]byte, 10)
+			if _, err := obj.ReadAt(res, off); err != nil {
+				errs <- err
+			} else if string(res) != "0123456789" {
+				errs <- errors.New("parallel ReadAt returned " + string(res))
+			}
+		}(int64(5 + 10*i))
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	pos, err = obj.Tell()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pos != 0 {
+		t.Errorf("Expected ReadAt to keep pos at 0, got %d", pos)
+	}
+
+	if err := obj.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLargeObjectsImportExport(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnect(t, *defaultConnConfig)
+	defer closeConn(t, conn)
+
+	tx, err := conn.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	lo, err := tx.LargeObjects()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := bytes.Repeat([]byte("pgx large object "), 50000)
+	id, err := lo.Import(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := &bytes.Buffer{}
+	n, err := lo.Export(id, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(data)) || !bytes.Equal(buf.Bytes(), data) {
+		t.Errorf("Export returned %d bytes that do not match the imported data", n)
+	}
+
+	// WriteTo starts at the current location
+	obj, err := lo.Open(id, pgx.LargeObjectModeRead)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := obj.Seek(int64(len(data)-7), io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if _, err := obj.WriteTo(buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "object " {
+		t.Errorf(`Expected "object ", got %q`, buf.String())
+	}
+	if err := obj.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := lo.Export(id+1000000, buf); err == nil {
+		t.Error("Expected error exporting missing large object")
+	}
+}
+
+func TestConnPoolWithLargeObjects(t *testing.T) {
+	t.Parallel()
+
+	pool := createConnPool(t, 2)
+	defer pool.Close()
+
+	data := []byte("pooled large object")
+
+	var id pgtype.OID
+	err := pool.WithLargeObjects(context.Background(), func(lo *pgx.LargeObjects) error {
+		var err error
+		id, err = lo.Import(bytes.NewReader(data))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.WithLargeObjects(context.Background(), func(lo *pgx.LargeObjects) error {
+		return lo.Unlink(id)
+	})
+
+	if stat := pool.Stat(); stat.AvailableConnections != stat.CurrentConnections {
+		t.Errorf("Expected the connection to be released, but stat is %#v", stat)
+	}
+
+	// An error rolls back the transaction
+	errRollback := errors.New("rollback")
+	var rolledBackID pgtype.OID
+	err = pool.WithLargeObjects(context.Background(), func(lo *pgx.LargeObjects) error {
+		var err error
+		rolledBackID, err = lo.Create(0)
+		if err != nil {
+			return err
+		}
+		return errRollback
+	})
+	if err != errRollback {
+		t.Fatalf("Expected errRollback, got %v", err)
+	}
+
+	err = pool.WithLargeObjects(context.Background(), func(lo *pgx.LargeObjects) error {
+		_, err := lo.Open(rolledBackID, pgx.LargeObjectModeRead)
+		return err
+	})
+	if e, ok := err.(pgx.PgError); !ok || e.Code != "42704" {
+		t.Errorf("Expected undefined_object error (42704), got %#v", err)
+	}
+
+	r, err := pool.OpenReader(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stat := pool.Stat(); stat.AvailableConnections == stat.CurrentConnections {
+		t.Errorf("Expected the reader to hold a connection, but stat is %#v", stat)
+	}
+
+	if _, err := r.Seek(7, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	res, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(res) != "large object" {
+		t.Errorf(`Expected "large object", got %q`, res)
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if stat := pool.Stat(); stat.AvailableConnections != stat.CurrentConnections {
+		t.Errorf("Expected the connection to be released, but stat is %#v", stat)
+	}
+}
@@ -0,0 +1,63 @@
+// Package kitlogadapter provides a logger that writes to a github.com/go-kit/log.Logger.
+package kitlogadapter
+
+import (
+	"context"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/ronaldslc/pgx"
+)
+
+type Logger struct {
+	l kitlog.Logger
+}
+
+func NewLogger(l kitlog.Logger) *Logger {
+	return &Logger{l: l}
+}
+
+func (l *Logger) Log(level pgx.LogLevel, msg string, ld pgx.LogData) {
+	log(l.l, level, msg, ld)
+}
+
+// ContextLogger is a pgx.ContextLogger that writes to a go-kit log.Logger.
+// The fields returned by fieldsFromContext are added to each entry.
+type ContextLogger struct {
+	l                 kitlog.Logger
+	fieldsFromContext func(context.Context) pgx.LogData
+}
+
+// NewContextLogger returns a ContextLogger that writes to l. fieldsFromContext
+// may be nil.
+func NewContextLogger(l kitlog.Logger, fieldsFromContext func(context.Context) pgx.LogData) *ContextLogger {
+	return &ContextLogger{l: l, fieldsFromContext: fieldsFromContext}
+}
+
+func (l *ContextLogger) Log(ctx context.Context, level pgx.LogLevel, msg string, ld pgx.LogData) {
+	ld = pgx.ContextLogData(ctx, l.fieldsFromContext, ld)
+	log(l.l, level, msg, ld)
+}
+
+func log(l kitlog.Logger, lvl pgx.LogLevel, msg string, ld pgx.LogData) {
+	logArgs := make([]interface{}, 0, 4+2*len(ld))
+	logArgs = append(logArgs, "msg", msg)
+	for _, v := range ld {
+		logArgs = append(logArgs, v.Key, v.Value)
+	}
+
+	switch lvl {
+	case pgx.LogLevelTrace:
+		level.Debug(l).Log(append(logArgs, "PGX_LOG_LEVEL", lvl)...)
+	case pgx.LogLevelDebug:
+		level.Debug(l).Log(logArgs...)
+	case pgx.LogLevelInfo:
+		level.Info(l).Log(logArgs...)
+	case pgx.LogLevelWarn:
+		level.Warn(l).Log(logArgs...)
+	case pgx.LogLevelError:
+		level.Error(l).Log(logArgs...)
+	default:
+		level.Error(l).Log(append(logArgs, "INVALID_PGX_LOG_LEVEL", lvl)...)
+	}
+}
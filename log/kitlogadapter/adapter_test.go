@@ -0,0 +1,34 @@
+package kitlogadapter_test
+
+import (
+	"bytes"
+	"testing"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/ronaldslc/pgx"
+	"github.com/ronaldslc/pgx/log/kitlogadapter"
+)
+
+func TestLogger(t *testing.T) {
+	tests := []struct {
+		level    pgx.LogLevel
+		expected string
+	}{
+		{pgx.LogLevelTrace, "level=debug msg=Exec sql=\"select 1\" rowCount=1 PGX_LOG_LEVEL=trace\n"},
+		{pgx.LogLevelDebug, "level=debug msg=Exec sql=\"select 1\" rowCount=1\n"},
+		{pgx.LogLevelInfo, "level=info msg=Exec sql=\"select 1\" rowCount=1\n"},
+		{pgx.LogLevelWarn, "level=warn msg=Exec sql=\"select 1\" rowCount=1\n"},
+		{pgx.LogLevelError, "level=error msg=Exec sql=\"select 1\" rowCount=1\n"},
+		{pgx.LogLevel(42), "level=error msg=Exec sql=\"select 1\" rowCount=1 INVALID_PGX_LOG_LEVEL=\"invalid level 42\"\n"},
+	}
+
+	for i, tt := range tests {
+		buf := &bytes.Buffer{}
+		var logger pgx.Logger = kitlogadapter.NewLogger(kitlog.NewLogfmtLogger(buf))
+		logger.Log(tt.level, "Exec", pgx.LogData{{Key: "sql", Value: "select 1"}, {Key: "rowCount", Value: 1}})
+
+		if buf.String() != tt.expected {
+			t.Errorf("%d. Expected %q, got %q", i, tt.expected, buf.String())
+		}
+	}
+}
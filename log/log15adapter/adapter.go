@@ -3,6 +3,8 @@
 package log15adapter
 
 import (
+	"context"
+
 	"github.com/ronaldslc/pgx"
 )
 
@@ -25,6 +27,28 @@ func NewLogger(l Log15Logger) *Logger {
 }
 
 func (l *Logger) Log(level pgx.LogLevel, msg string, ld pgx.LogData) {
+	log(l.l, level, msg, ld)
+}
+
+// ContextLogger is a pgx.ContextLogger that writes to a Log15Logger. The
+// fields returned by fieldsFromContext are added to each entry.
+type ContextLogger struct {
+	l                 Log15Logger
+	fieldsFromContext func(context.Context) pgx.LogData
+}
+
+// NewContextLogger returns a ContextLogger that writes to l. fieldsFromContext
+// may be nil.
+func NewContextLogger(l Log15Logger, fieldsFromContext func(context.Context) pgx.LogData) *ContextLogger {
+	return &ContextLogger{l: l, fieldsFromContext: fieldsFromContext}
+}
+
+func (l *ContextLogger) Log(ctx context.Context, level pgx.LogLevel, msg string, ld pgx.LogData) {
+	ld = pgx.ContextLogData(ctx, l.fieldsFromContext, ld)
+	log(l.l, level, msg, ld)
+}
+
+func log(l Log15Logger, level pgx.LogLevel, msg string, ld pgx.LogData) {
 	logArgs := make([]interface{}, 0, len(ld))
 	for _, v := range ld {
 		logArgs = append(logArgs, v.Key, v.Value)
@@ -32,16 +56,16 @@ func (l *Logger) Log(level pgx.LogLevel, msg string, ld pgx.LogData) {
 
 	switch level {
 	case pgx.LogLevelTrace:
-		l.l.Debug(msg, append(logArgs, "PGX_LOG_LEVEL", level)...)
+		l.Debug(msg, append(logArgs, "PGX_LOG_LEVEL", level)...)
 	case pgx.LogLevelDebug:
-		l.l.Debug(msg, logArgs...)
+		l.Debug(msg, logArgs...)
 	case pgx.LogLevelInfo:
-		l.l.Info(msg, logArgs...)
+		l.Info(msg, logArgs...)
 	case pgx.LogLevelWarn:
-		l.l.Warn(msg, logArgs...)
+		l.Warn(msg, logArgs...)
 	case pgx.LogLevelError:
-		l.l.Error(msg, logArgs...)
+		l.Error(msg, logArgs...)
 	default:
-		l.l.Error(msg, append(logArgs, "INVALID_PGX_LOG_LEVEL", level)...)
+		l.Error(msg, append(logArgs, "INVALID_PGX_LOG_LEVEL", level)...)
 	}
 }
@@ -3,6 +3,8 @@
 package logrusadapter
 
 import (
+	"context"
+
 	"github.com/ronaldslc/pgx"
 	"github.com/sirupsen/logrus"
 )
@@ -18,15 +20,42 @@ func NewLogger(l *logrus.Logger) *Logger {
 func (l *Logger) Log(level pgx.LogLevel, msg string, ld pgx.LogData) {
 	var logger logrus.FieldLogger
 	if ld != nil {
-		f := make(logrus.Fields)
-		for _, v := range ld {
-			f[v.Key] = v.Value
-		}
-		logger = l.l.WithFields(f)
+		logger = l.l.WithFields(fields(ld))
 	} else {
 		logger = l.l
 	}
 
+	log(logger, level, msg)
+}
+
+// ContextLogger is a pgx.ContextLogger that writes to a logrus.Logger. The
+// context of each entry is set so logrus hooks can read request scoped values
+// from it. The fields returned by fieldsFromContext are added to each entry.
+type ContextLogger struct {
+	l                 *logrus.Logger
+	fieldsFromContext func(context.Context) pgx.LogData
+}
+
+// NewContextLogger returns a ContextLogger that writes to l. fieldsFromContext
+// may be nil.
+func NewContextLogger(l *logrus.Logger, fieldsFromContext func(context.Context) pgx.LogData) *ContextLogger {
+	return &ContextLogger{l: l, fieldsFromContext: fieldsFromContext}
+}
+
+func (l *ContextLogger) Log(ctx context.Context, level pgx.LogLevel, msg string, ld pgx.LogData) {
+	ld = pgx.ContextLogData(ctx, l.fieldsFromContext, ld)
+	log(l.l.WithContext(ctx).WithFields(fields(ld)), level, msg)
+}
+
+func fields(ld pgx.LogData) logrus.Fields {
+	f := make(logrus.Fields, len(ld))
+	for _, v := range ld {
+		f[v.Key] = v.Value
+	}
+	return f
+}
+
+func log(logger logrus.FieldLogger, level pgx.LogLevel, msg string) {
 	switch level {
 	case pgx.LogLevelTrace:
 		logger.WithField("PGX_LOG_LEVEL", level).Debug(msg)
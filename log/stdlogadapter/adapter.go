@@ -0,0 +1,92 @@
+// Package stdlogadapter provides a logger that writes to a standard library
+// log.Logger.
+package stdlogadapter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	stdlog "log"
+	"strconv"
+
+	"github.com/ronaldslc/pgx"
+)
+
+// Logger writes each entry as one line of the level, the message and the data
+// as key=value pairs, e.g.
+//
+//	info Exec pid=1234 sql="select 1" time=1.2ms
+//
+// Trace entries are written with the debug level and PGX_LOG_LEVEL=trace the
+// same as the other adapters.
+type Logger struct {
+	l *stdlog.Logger
+}
+
+func NewLogger(l *stdlog.Logger) *Logger {
+	return &Logger{l: l}
+}
+
+func (l *Logger) Log(level pgx.LogLevel, msg string, ld pgx.LogData) {
+	log(l.l, level, msg, ld)
+}
+
+// ContextLogger is a pgx.ContextLogger that writes to a log.Logger. The fields
+// returned by fieldsFromContext are added to each entry.
+type ContextLogger struct {
+	l                 *stdlog.Logger
+	fieldsFromContext func(context.Context) pgx.LogData
+}
+
+// NewContextLogger returns a ContextLogger that writes to l. fieldsFromContext
+// may be nil.
+func NewContextLogger(l *stdlog.Logger, fieldsFromContext func(context.Context) pgx.LogData) *ContextLogger {
+	return &ContextLogger{l: l, fieldsFromContext: fieldsFromContext}
+}
+
+func (l *ContextLogger) Log(ctx context.Context, level pgx.LogLevel, msg string, ld pgx.LogData) {
+	ld = pgx.ContextLogData(ctx, l.fieldsFromContext, ld)
+	log(l.l, level, msg, ld)
+}
+
+func log(l *stdlog.Logger, level pgx.LogLevel, msg string, ld pgx.LogData) {
+	buf := &bytes.Buffer{}
+
+	switch level {
+	case pgx.LogLevelTrace:
+		buf.WriteString("debug")
+		ld = append(ld, pgx.KV{Key: "PGX_LOG_LEVEL", Value: level})
+	case pgx.LogLevelDebug, pgx.LogLevelInfo, pgx.LogLevelWarn, pgx.LogLevelError:
+		buf.WriteString(level.String())
+	default:
+		buf.WriteString("error")
+		ld = append(ld, pgx.KV{Key: "INVALID_PGX_LOG_LEVEL", Value: level})
+	}
+
+	buf.WriteByte(' ')
+	buf.WriteString(msg)
+
+	for _, v := range ld {
+		buf.WriteByte(' ')
+		buf.WriteString(v.Key)
+		buf.WriteByte('=')
+		buf.WriteString(formatValue(v.Value))
+	}
+
+	l.Output(3, buf.String())
+}
+
+// formatValue formats v with %v and quotes it if it is empty or contains
+// spaces, quotes or '=' so that the key=value pairs of a line can be parsed.
+func formatValue(v interface{}) string {
+	s := fmt.Sprintf("%v", v)
+	if s == "" {
+		return `""`
+	}
+	for _, r := range s {
+		if r <= ' ' || r == '"' || r == '=' {
+			return strconv.Quote(s)
+		}
+	}
+	return s
+}
@@ -0,0 +1,36 @@
+package stdlogadapter_test
+
+import (
+	"bytes"
+	"log"
+	"testing"
+
+	"github.com/ronaldslc/pgx"
+	"github.com/ronaldslc/pgx/log/stdlogadapter"
+)
+
+func TestLogger(t *testing.T) {
+	tests := []struct {
+		level    pgx.LogLevel
+		msg      string
+		ld       pgx.LogData
+		expected string
+	}{
+		{pgx.LogLevelTrace, "trace", nil, "debug trace PGX_LOG_LEVEL=trace\n"},
+		{pgx.LogLevelDebug, "debug", nil, "debug debug\n"},
+		{pgx.LogLevelInfo, "Exec", pgx.LogData{{Key: "sql", Value: "select 1"}, {Key: "rowCount", Value: 1}}, "info Exec sql=\"select 1\" rowCount=1\n"},
+		{pgx.LogLevelWarn, "warn", pgx.LogData{{Key: "empty", Value: ""}}, "warn warn empty=\"\"\n"},
+		{pgx.LogLevelError, "error", pgx.LogData{{Key: "err", Value: "a=b"}}, "error error err=\"a=b\"\n"},
+		{pgx.LogLevel(42), "invalid", nil, "error invalid INVALID_PGX_LOG_LEVEL=\"invalid level 42\"\n"},
+	}
+
+	for i, tt := range tests {
+		buf := &bytes.Buffer{}
+		var logger pgx.Logger = stdlogadapter.NewLogger(log.New(buf, "", 0))
+		logger.Log(tt.level, tt.msg, tt.ld)
+
+		if buf.String() != tt.expected {
+			t.Errorf("%d. Expected %q, got %q", i, tt.expected, buf.String())
+		}
+	}
+}
@@ -3,6 +3,7 @@
 package testingadapter
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/ronaldslc/pgx"
@@ -23,10 +24,32 @@ func NewLogger(l TestingLogger) *Logger {
 }
 
 func (l *Logger) Log(level pgx.LogLevel, msg string, ld pgx.LogData) {
+	log(l.l, level, msg, ld)
+}
+
+// ContextLogger is a pgx.ContextLogger that writes to a TestingLogger. The
+// fields returned by fieldsFromContext are added to each entry.
+type ContextLogger struct {
+	l                 TestingLogger
+	fieldsFromContext func(context.Context) pgx.LogData
+}
+
+// NewContextLogger returns a ContextLogger that writes to l. fieldsFromContext
+// may be nil.
+func NewContextLogger(l TestingLogger, fieldsFromContext func(context.Context) pgx.LogData) *ContextLogger {
+	return &ContextLogger{l: l, fieldsFromContext: fieldsFromContext}
+}
+
+func (l *ContextLogger) Log(ctx context.Context, level pgx.LogLevel, msg string, ld pgx.LogData) {
+	ld = pgx.ContextLogData(ctx, l.fieldsFromContext, ld)
+	log(l.l, level, msg, ld)
+}
+
+func log(l TestingLogger, level pgx.LogLevel, msg string, ld pgx.LogData) {
 	logArgs := make([]interface{}, 0, 2+len(ld))
 	logArgs = append(logArgs, level, msg)
 	for _, v := range ld {
 		logArgs = append(logArgs, fmt.Sprintf("%s=%v", v.Key, v.Value))
 	}
-	l.l.Log(logArgs...)
+	l.Log(logArgs...)
 }
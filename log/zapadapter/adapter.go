@@ -0,0 +1,62 @@
+// Package zapadapter provides a logger that writes to a go.uber.org/zap.Logger.
+package zapadapter
+
+import (
+	"context"
+
+	"github.com/ronaldslc/pgx"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type Logger struct {
+	logger *zap.Logger
+}
+
+func NewLogger(logger *zap.Logger) *Logger {
+	return &Logger{logger: logger.WithOptions(zap.AddCallerSkip(2))}
+}
+
+func (pl *Logger) Log(level pgx.LogLevel, msg string, ld pgx.LogData) {
+	log(pl.logger, level, msg, ld)
+}
+
+// ContextLogger is a pgx.ContextLogger that writes to a zap.Logger. The fields
+// returned by fieldsFromContext are added to each entry.
+type ContextLogger struct {
+	logger            *zap.Logger
+	fieldsFromContext func(context.Context) pgx.LogData
+}
+
+// NewContextLogger returns a ContextLogger that writes to logger.
+// fieldsFromContext may be nil.
+func NewContextLogger(logger *zap.Logger, fieldsFromContext func(context.Context) pgx.LogData) *ContextLogger {
+	return &ContextLogger{logger: logger.WithOptions(zap.AddCallerSkip(2)), fieldsFromContext: fieldsFromContext}
+}
+
+func (pl *ContextLogger) Log(ctx context.Context, level pgx.LogLevel, msg string, ld pgx.LogData) {
+	ld = pgx.ContextLogData(ctx, pl.fieldsFromContext, ld)
+	log(pl.logger, level, msg, ld)
+}
+
+func log(logger *zap.Logger, level pgx.LogLevel, msg string, ld pgx.LogData) {
+	fields := make([]zapcore.Field, len(ld), len(ld)+1)
+	for i, v := range ld {
+		fields[i] = zap.Any(v.Key, v.Value)
+	}
+
+	switch level {
+	case pgx.LogLevelTrace:
+		logger.Debug(msg, append(fields, zap.Stringer("PGX_LOG_LEVEL", level))...)
+	case pgx.LogLevelDebug:
+		logger.Debug(msg, fields...)
+	case pgx.LogLevelInfo:
+		logger.Info(msg, fields...)
+	case pgx.LogLevelWarn:
+		logger.Warn(msg, fields...)
+	case pgx.LogLevelError:
+		logger.Error(msg, fields...)
+	default:
+		logger.Error(msg, append(fields, zap.Stringer("INVALID_PGX_LOG_LEVEL", level))...)
+	}
+}
@@ -0,0 +1,60 @@
+package zapadapter_test
+
+import (
+	"testing"
+
+	"github.com/ronaldslc/pgx"
+	"github.com/ronaldslc/pgx/log/zapadapter"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestLogger(t *testing.T) {
+	tests := []struct {
+		level         pgx.LogLevel
+		expectedLevel zapcore.Level
+		expectedExtra string
+	}{
+		{pgx.LogLevelTrace, zapcore.DebugLevel, "PGX_LOG_LEVEL"},
+		{pgx.LogLevelDebug, zapcore.DebugLevel, ""},
+		{pgx.LogLevelInfo, zapcore.InfoLevel, ""},
+		{pgx.LogLevelWarn, zapcore.WarnLevel, ""},
+		{pgx.LogLevelError, zapcore.ErrorLevel, ""},
+		{pgx.LogLevel(42), zapcore.ErrorLevel, "INVALID_PGX_LOG_LEVEL"},
+	}
+
+	for i, tt := range tests {
+		core, logs := observer.New(zapcore.DebugLevel)
+		var logger pgx.Logger = zapadapter.NewLogger(zap.New(core))
+		logger.Log(tt.level, "Exec", pgx.LogData{{Key: "sql", Value: "select 1"}, {Key: "rowCount", Value: 1}})
+
+		entries := logs.AllUntimed()
+		if len(entries) != 1 {
+			t.Fatalf("%d. Expected 1 entry, got %d", i, len(entries))
+		}
+		entry := entries[0]
+
+		if entry.Level != tt.expectedLevel {
+			t.Errorf("%d. Expected level %v, got %v", i, tt.expectedLevel, entry.Level)
+		}
+		if entry.Message != "Exec" {
+			t.Errorf("%d. Expected message Exec, got %v", i, entry.Message)
+		}
+
+		fields := entry.ContextMap()
+		if fields["sql"] != "select 1" {
+			t.Errorf("%d. Expected sql field, got %v", i, fields)
+		}
+		if fields["rowCount"] != int64(1) {
+			t.Errorf("%d. Expected rowCount field, got %v", i, fields)
+		}
+		if tt.expectedExtra != "" {
+			if fields[tt.expectedExtra] != tt.level.String() {
+				t.Errorf("%d. Expected %s field, got %v", i, tt.expectedExtra, fields)
+			}
+		} else if len(fields) != 2 {
+			t.Errorf("%d. Expected 2 fields, got %v", i, fields)
+		}
+	}
+}
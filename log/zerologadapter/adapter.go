@@ -0,0 +1,73 @@
+// Package zerologadapter provides a logger that writes to a github.com/rs/zerolog.Logger.
+package zerologadapter
+
+import (
+	"context"
+
+	"github.com/ronaldslc/pgx"
+	"github.com/rs/zerolog"
+)
+
+type Logger struct {
+	logger zerolog.Logger
+}
+
+func NewLogger(logger zerolog.Logger) *Logger {
+	return &Logger{logger: logger}
+}
+
+func (pl *Logger) Log(level pgx.LogLevel, msg string, ld pgx.LogData) {
+	log(pl.logger, level, msg, ld)
+}
+
+// ContextLogger is a pgx.ContextLogger that writes to a zerolog.Logger. The
+// fields returned by fieldsFromContext are added to each entry.
+type ContextLogger struct {
+	logger            zerolog.Logger
+	fieldsFromContext func(context.Context) pgx.LogData
+}
+
+// NewContextLogger returns a ContextLogger that writes to logger.
+// fieldsFromContext may be nil.
+func NewContextLogger(logger zerolog.Logger, fieldsFromContext func(context.Context) pgx.LogData) *ContextLogger {
+	return &ContextLogger{logger: logger, fieldsFromContext: fieldsFromContext}
+}
+
+func (pl *ContextLogger) Log(ctx context.Context, level pgx.LogLevel, msg string, ld pgx.LogData) {
+	ld = pgx.ContextLogData(ctx, pl.fieldsFromContext, ld)
+	log(pl.logger, level, msg, ld)
+}
+
+func log(logger zerolog.Logger, level pgx.LogLevel, msg string, ld pgx.LogData) {
+	var zlevel zerolog.Level
+	switch level {
+	case pgx.LogLevelTrace, pgx.LogLevelDebug:
+		zlevel = zerolog.DebugLevel
+	case pgx.LogLevelInfo:
+		zlevel = zerolog.InfoLevel
+	case pgx.LogLevelWarn:
+		zlevel = zerolog.WarnLevel
+	default:
+		zlevel = zerolog.ErrorLevel
+	}
+
+	event := logger.WithLevel(zlevel)
+	if event == nil {
+		// level is disabled
+		return
+	}
+
+	for _, v := range ld {
+		event = event.Interface(v.Key, v.Value)
+	}
+
+	switch level {
+	case pgx.LogLevelTrace:
+		event = event.Stringer("PGX_LOG_LEVEL", level)
+	case pgx.LogLevelDebug, pgx.LogLevelInfo, pgx.LogLevelWarn, pgx.LogLevelError:
+	default:
+		event = event.Stringer("INVALID_PGX_LOG_LEVEL", level)
+	}
+
+	event.Msg(msg)
+}
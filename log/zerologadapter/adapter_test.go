@@ -0,0 +1,63 @@
+package zerologadapter_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/ronaldslc/pgx"
+	"github.com/ronaldslc/pgx/log/zerologadapter"
+	"github.com/rs/zerolog"
+)
+
+func TestLogger(t *testing.T) {
+	tests := []struct {
+		level         pgx.LogLevel
+		expectedLevel string
+		expectedExtra string
+	}{
+		{pgx.LogLevelTrace, "debug", "PGX_LOG_LEVEL"},
+		{pgx.LogLevelDebug, "debug", ""},
+		{pgx.LogLevelInfo, "info", ""},
+		{pgx.LogLevelWarn, "warn", ""},
+		{pgx.LogLevelError, "error", ""},
+		{pgx.LogLevel(42), "error", "INVALID_PGX_LOG_LEVEL"},
+	}
+
+	for i, tt := range tests {
+		buf := &bytes.Buffer{}
+		var logger pgx.Logger = zerologadapter.NewLogger(zerolog.New(buf))
+		logger.Log(tt.level, "Exec", pgx.LogData{{Key: "sql", Value: "select 1"}, {Key: "rowCount", Value: 1}})
+
+		var entry map[string]interface{}
+		if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+			t.Fatalf("%d. %v: %s", i, err, buf.String())
+		}
+
+		if entry["level"] != tt.expectedLevel {
+			t.Errorf("%d. Expected level %v, got %v", i, tt.expectedLevel, entry["level"])
+		}
+		if entry["message"] != "Exec" {
+			t.Errorf("%d. Expected message Exec, got %v", i, entry["message"])
+		}
+		if entry["sql"] != "select 1" {
+			t.Errorf("%d. Expected sql field, got %v", i, entry)
+		}
+		if entry["rowCount"] != float64(1) {
+			t.Errorf("%d. Expected rowCount field, got %v", i, entry)
+		}
+		if tt.expectedExtra != "" && entry[tt.expectedExtra] != tt.level.String() {
+			t.Errorf("%d. Expected %s field, got %v", i, tt.expectedExtra, entry)
+		}
+	}
+}
+
+func TestLoggerDisabledLevel(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := zerologadapter.NewLogger(zerolog.New(buf).Level(zerolog.WarnLevel))
+	logger.Log(pgx.LogLevelInfo, "Exec", nil)
+
+	if buf.Len() != 0 {
+		t.Errorf("Expected nothing to be logged, got %s", buf.String())
+	}
+}
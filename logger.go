@@ -1,8 +1,10 @@
 package pgx
 
 import (
+	"context"
 	"encoding/hex"
 	"fmt"
+	"regexp"
 	uuid "github.com/ronaldslc/go.uuid"
 
 	"github.com/pkg/errors"
@@ -59,6 +61,34 @@ type Logger interface {
 	Log(level LogLevel, msg string, ld LogData)
 }
 
+// ContextLogger is the interface used to get logging from pgx internals with
+// the context.Context of the operation that logged. This allows an adapter to
+// add request scoped fields such as request IDs to the log entry. When the
+// operation has no context, such as Conn.Close, context.Background() is used.
+//
+// A ContextLogger is set with ConnConfig.ContextLogger and takes precedence
+// over ConnConfig.Logger.
+type ContextLogger interface {
+	// Log a message at the given level with data key/value pairs. data may be nil.
+	Log(ctx context.Context, level LogLevel, msg string, ld LogData)
+}
+
+// ContextLogData returns the fields fieldsFromContext returns for ctx followed
+// by ld. The result is a new LogData, so neither the fields nor ld are
+// modified even if they have spare capacity. If fieldsFromContext is nil ld is
+// returned. It is used by ContextLogger adapters that add fields from the
+// context to each entry.
+func ContextLogData(ctx context.Context, fieldsFromContext func(context.Context) LogData, ld LogData) LogData {
+	if fieldsFromContext == nil {
+		return ld
+	}
+
+	fields := fieldsFromContext(ctx)
+	merged := make(LogData, 0, len(fields)+len(ld))
+	merged = append(merged, fields...)
+	return append(merged, ld...)
+}
+
 // LogLevelFromString converts log level string to constant
 //
 // Valid levels:
@@ -87,24 +117,138 @@ func LogLevelFromString(s string) (LogLevel, error) {
 	}
 }
 
-func logQueryArgs(args []interface{}) []interface{} {
+// Modes of LogArgsPolicy
+const (
+	LogArgsAll   = iota // log arguments truncated to MaxArgLen
+	LogArgsNone         // never log arguments
+	LogArgsTypes        // log only the Go type of each argument
+)
+
+// RedactedArg replaces arguments masked by a LogArgsRule in logs.
+const RedactedArg = "[REDACTED]"
+
+// defaultLogArgMaxLen is the maximum length of a logged argument when
+// LogArgsPolicy.MaxArgLen is not set.
+const defaultLogArgMaxLen = 64
+
+// LogArgsPolicy controls how the SQL and arguments of queries are logged. It
+// is set with ConnConfig.LogArgs. A nil policy logs all arguments truncated to
+// 64 bytes and the full SQL.
+type LogArgsPolicy struct {
+	// Mode is one of the LogArgs* constants.
+	Mode int
+
+	// Rules mask arguments of matching SQL when Mode is LogArgsAll. The
+	// arguments masked by all matching rules are replaced by RedactedArg.
+	Rules []LogArgsRule
+
+	// Func, if set, replaces Mode and Rules. It returns the value logged as
+	// the arguments of sql. It must not modify args.
+	Func func(sql string, args []interface{}) interface{}
+
+	// MaxArgLen is the maximum length in bytes of each logged argument.
+	// Longer arguments are truncated. default: 64
+	MaxArgLen int
+
+	// MaxSQLLen is the maximum length in bytes of the logged SQL. Longer SQL
+	// is truncated. 0 means no limit.
+	MaxSQLLen int
+}
+
+// LogArgsRule masks arguments of the queries whose SQL matches SQL. For
+// FunctionCall the function name is matched instead.
+type LogArgsRule struct {
+	SQL *regexp.Regexp
+
+	// Args are the zero-based indexes of the arguments to mask. If empty all
+	// arguments are masked.
+	Args []int
+}
+
+// logSQL returns sql truncated according to the policy.
+func (p *LogArgsPolicy) logSQL(sql string) string {
+	if p == nil || p.MaxSQLLen <= 0 || len(sql) <= p.MaxSQLLen {
+		return sql
+	}
+	return fmt.Sprintf("%s (truncated %d bytes)", sql[:p.MaxSQLLen], len(sql)-p.MaxSQLLen)
+}
+
+// logArgs returns the value to log as the arguments of sql and false if no
+// arguments should be logged.
+func (p *LogArgsPolicy) logArgs(sql string, args []interface{}) (interface{}, bool) {
+	if p == nil {
+		return logQueryArgs(args, defaultLogArgMaxLen), true
+	}
+
+	if p.Func != nil {
+		return p.Func(sql, args), true
+	}
+
+	switch p.Mode {
+	case LogArgsNone:
+		return nil, false
+	case LogArgsTypes:
+		types := make([]string, len(args))
+		for i, a := range args {
+			types[i] = fmt.Sprintf("%T", a)
+		}
+		return types, true
+	}
+
+	maxLen := p.MaxArgLen
+	if maxLen <= 0 {
+		maxLen = defaultLogArgMaxLen
+	}
+	logArgs := logQueryArgs(args, maxLen)
+
+	for _, rule := range p.Rules {
+		if !rule.SQL.MatchString(sql) {
+			continue
+		}
+
+		if len(rule.Args) == 0 {
+			for i := range logArgs {
+				logArgs[i] = RedactedArg
+			}
+			continue
+		}
+
+		for _, i := range rule.Args {
+			if i >= 0 && i < len(logArgs) {
+				logArgs[i] = RedactedArg
+			}
+		}
+	}
+
+	return logArgs, true
+}
+
+// addLogArgs adds the arguments of sql to ld according to the LogArgsPolicy
+// of c.
+func (c *Conn) addLogArgs(ld *LogData, sql string, args []interface{}) {
+	if logArgs, ok := c.config.LogArgs.logArgs(sql, args); ok {
+		ld.Add("args", logArgs)
+	}
+}
+
+func logQueryArgs(args []interface{}, maxLen int) []interface{} {
 	logArgs := make([]interface{}, 0, len(args))
 
 	for _, a := range args {
 		switch v := a.(type) {
 		case []byte:
-			if len(v) < 64 {
+			if len(v) < maxLen {
 				a = hex.EncodeToString(v)
 			} else {
-				a = fmt.Sprintf("%x (truncated %d bytes)", v[:64], len(v)-64)
+				a = fmt.Sprintf("%x (truncated %d bytes)", v[:maxLen], len(v)-maxLen)
 			}
 		case string:
-			if len(v) > 64 {
-				a = fmt.Sprintf("%s (truncated %d bytes)", v[:64], len(v)-64)
+			if len(v) > maxLen {
+				a = fmt.Sprintf("%s (truncated %d bytes)", v[:maxLen], len(v)-maxLen)
 			}
 		case *string:
-			if len(*v) > 64 {
-				a = fmt.Sprintf("%s (truncated %d bytes)", (*v)[:64], len(*v)-64)
+			if len(*v) > maxLen {
+				a = fmt.Sprintf("%s (truncated %d bytes)", (*v)[:maxLen], len(*v)-maxLen)
 			} else {
 				a = fmt.Sprintf("%s", *v)
 			}
@@ -118,8 +262,8 @@ func logQueryArgs(args []interface{}) []interface{} {
 		default:
 			if v, ok := a.(fmt.Stringer); ok {
 				vstr := v.String()
-				if len(v.String()) > 64 {
-					a = fmt.Sprintf("%s (truncated %d bytes)", vstr[:64], len(vstr)-64)
+				if len(vstr) > maxLen {
+					a = fmt.Sprintf("%s (truncated %d bytes)", vstr[:maxLen], len(vstr)-maxLen)
 				} else {
 					a = vstr
 				}
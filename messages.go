@@ -1,6 +1,11 @@
 package pgx
 
 import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/ronaldslc/pgx/pgerrcode"
 	"github.com/ronaldslc/pgx/pgio"
 	"github.com/ronaldslc/pgx/pgtype"
 )
@@ -43,12 +48,227 @@ type PgError struct {
 	File             string
 	Line             int32
 	Routine          string
+
+	// SQL and Args are set by Query, Exec, batches and the methods that
+	// delegate to them to the SQL of the failed statement and a summary of its
+	// arguments. Args only has the Go types of the arguments unless
+	// ConnConfig.LogArgs is set, in which case it has the arguments as they
+	// would be logged (see LogArgsPolicy). They are not part of Error(). With
+	// QueryExOptions.SimpleProtocol the arguments are interpolated into the SQL
+	// sent to the server, so Position may not match SQL.
+	SQL  string
+	Args string
 }
 
 func (pe PgError) Error() string {
 	return pe.Severity + ": " + pe.Message + " (SQLSTATE " + pe.Code + ")"
 }
 
+// Verbose formats the error like psql with VERBOSITY verbose. If SQL is set and
+// the error has a Position the line of SQL that contains the error is shown
+// with a caret under the position. The same is done for InternalQuery and
+// InternalPosition. Detail, Hint, Where, the location in the server source and
+// Args follow. If SQL is set but Position is not SQL is shown as the
+// statement.
+//
+//	ERROR:  42703: column "nme" does not exist
+//	LINE 1: select nme from users where id = $1
+//	               ^
+//	HINT:  Perhaps you meant to reference the column "users.name".
+//	LOCATION:  errorMissingColumn, parse_relation.c:3294
+//	ARGUMENTS:  [42]
+func (pe PgError) Verbose() string {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "%s:  %s: %s\n", pe.Severity, pe.Code, pe.Message)
+	if pe.SQL != "" && pe.Position > 0 {
+		buf.WriteString(positionExcerpt("", pe.SQL, int(pe.Position), true))
+	}
+	if pe.Detail != "" {
+		fmt.Fprintf(&buf, "DETAIL:  %s\n", pe.Detail)
+	}
+	if pe.Hint != "" {
+		fmt.Fprintf(&buf, "HINT:  %s\n", pe.Hint)
+	}
+	if pe.InternalQuery != "" {
+		if pe.InternalPosition > 0 {
+			buf.WriteString(positionExcerpt("QUERY:  ", pe.InternalQuery, int(pe.InternalPosition), false))
+		} else {
+			fmt.Fprintf(&buf, "QUERY:  %s\n", pe.InternalQuery)
+		}
+	}
+	if pe.Where != "" {
+		fmt.Fprintf(&buf, "CONTEXT:  %s\n", pe.Where)
+	}
+	if pe.Routine != "" || pe.File != "" {
+		fmt.Fprintf(&buf, "LOCATION:  %s, %s:%d\n", pe.Routine, pe.File, pe.Line)
+	}
+	if pe.SQL != "" && pe.Position <= 0 {
+		fmt.Fprintf(&buf, "STATEMENT:  %s\n", pe.SQL)
+	}
+	if pe.Args != "" {
+		fmt.Fprintf(&buf, "ARGUMENTS:  %s\n", pe.Args)
+	}
+
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// maxExcerptWidth is the number of characters of a long line shown around an
+// error position.
+const maxExcerptWidth = 60
+
+// positionExcerpt returns the line of sql that contains the 1-based character
+// position followed by a line with a caret under the position. The line is
+// labeled "LINE n: " after prefix if lineLabel is set or sql has more than
+// one line.
+func positionExcerpt(prefix string, sql string, position int, lineLabel bool) string {
+	runes := []rune(sql)
+	if position > len(runes)+1 {
+		position = len(runes) + 1
+	}
+	pos := position - 1
+
+	lineNum := 1
+	lineStart := 0
+	for i := 0; i < pos; i++ {
+		if runes[i] == '\n' {
+			lineNum++
+			lineStart = i + 1
+		}
+	}
+	lineEnd := lineStart
+	for lineEnd < len(runes) && runes[lineEnd] != '\n' && runes[lineEnd] != '\r' {
+		lineEnd++
+	}
+	if lineNum > 1 || lineEnd < len(runes) {
+		lineLabel = true
+	}
+
+	line := runes[lineStart:lineEnd]
+	col := pos - lineStart
+
+	// Show a window around the position of long lines.
+	var head, tail string
+	if len(line) > maxExcerptWidth {
+		start := col - maxExcerptWidth/2
+		if start < 0 {
+			start = 0
+		}
+		end := start + maxExcerptWidth
+		if end > len(line) {
+			end = len(line)
+			start = end - maxExcerptWidth
+		}
+		if start > 0 {
+			head = "..."
+		}
+		if end < len(line) {
+			tail = "..."
+		}
+		line = line[start:end]
+		col -= start
+	}
+
+	label := prefix
+	if lineLabel {
+		label += fmt.Sprintf("LINE %d: ", lineNum)
+	}
+	label += head
+
+	// The caret line copies tabs so the caret lines up with the text.
+	var caret bytes.Buffer
+	caret.WriteString(strings.Repeat(" ", len(label)))
+	for i := 0; i < col && i < len(line); i++ {
+		if line[i] == '\t' {
+			caret.WriteByte('\t')
+		} else {
+			caret.WriteByte(' ')
+		}
+	}
+	if col > len(line) {
+		caret.WriteString(strings.Repeat(" ", col-len(line)))
+	}
+	caret.WriteByte('^')
+
+	return label + string(line) + tail + "\n" + caret.String() + "\n"
+}
+
+// Class returns the class of the error, the first two characters of Code. See
+// package pgerrcode for the codes and classes.
+func (pe PgError) Class() string {
+	return pgerrcode.Class(pe.Code)
+}
+
+// IsUniqueViolation returns true if the error is a unique constraint violation.
+func (pe PgError) IsUniqueViolation() bool {
+	return pe.Code == pgerrcode.UniqueViolation
+}
+
+// IsForeignKeyViolation returns true if the error is a foreign key constraint
+// violation.
+func (pe PgError) IsForeignKeyViolation() bool {
+	return pe.Code == pgerrcode.ForeignKeyViolation
+}
+
+// IsSerializationFailure returns true if a serializable or repeatable read
+// transaction failed because of concurrent updates.
+func (pe PgError) IsSerializationFailure() bool {
+	return pe.Code == pgerrcode.SerializationFailure
+}
+
+// IsDeadlockDetected returns true if the transaction was aborted to resolve a
+// deadlock.
+func (pe PgError) IsDeadlockDetected() bool {
+	return pe.Code == pgerrcode.DeadlockDetected
+}
+
+// IsConnectionException returns true if the error is in the connection
+// exception class.
+func (pe PgError) IsConnectionException() bool {
+	return pe.Class() == pgerrcode.ClassConnectionException
+}
+
+// IsRetryable returns true if the failed transaction may succeed when it is
+// retried from the start: serialization failures, deadlocks, connection
+// exceptions and a server that cannot accept connections yet. The retry of a
+// connection exception needs a new connection.
+//
+// TransactionResolutionUnknown is not retryable: the transaction may have been
+// committed, so retrying it could apply it twice.
+func (pe PgError) IsRetryable() bool {
+	switch pe.Code {
+	case pgerrcode.SerializationFailure, pgerrcode.DeadlockDetected, pgerrcode.CannotConnectNow:
+		return true
+	case pgerrcode.TransactionResolutionUnknown:
+		return false
+	}
+	return pe.IsConnectionException()
+}
+
+// AsPgError returns the PgError err is or wraps. Errors wrapped by
+// github.com/pkg/errors or that implement Unwrap are unwrapped.
+func AsPgError(err error) (PgError, bool) {
+	for err != nil {
+		switch e := err.(type) {
+		case PgError:
+			return e, true
+		case *PgError:
+			if e != nil {
+				return *e, true
+			}
+			return PgError{}, false
+		case interface{ Cause() error }:
+			err = e.Cause()
+		case interface{ Unwrap() error }:
+			err = e.Unwrap()
+		default:
+			return PgError{}, false
+		}
+	}
+
+	return PgError{}, false
+}
+
 // Notice represents a notice response message reported by the PostgreSQL
 // server. Be aware that this is distinct from LISTEN/NOTIFY notification.
 type Notice PgError
@@ -134,6 +354,40 @@ func appendBind(
 	return buf, nil
 }
 
+// appendFunctionCall appends a fastpath FunctionCall message. The result is
+// requested in the binary format.
+func appendFunctionCall(
+	buf []byte,
+	functionOID pgtype.OID,
+	connInfo *pgtype.ConnInfo,
+	argumentOIDs []pgtype.OID,
+	arguments []interface{},
+) ([]byte, error) {
+	buf = append(buf, 'F')
+	sp := len(buf)
+	buf = pgio.AppendInt32(buf, -1)
+	buf = pgio.AppendUint32(buf, uint32(functionOID))
+
+	buf = pgio.AppendInt16(buf, int16(len(argumentOIDs)))
+	for i, oid := range argumentOIDs {
+		buf = pgio.AppendInt16(buf, chooseParameterFormatCode(connInfo, oid, arguments[i]))
+	}
+
+	buf = pgio.AppendInt16(buf, int16(len(arguments)))
+	for i, oid := range argumentOIDs {
+		var err error
+		buf, err = encodePreparedStatementArgument(connInfo, buf, oid, arguments[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	buf = pgio.AppendInt16(buf, BinaryFormatCode)
+	pgio.SetInt32(buf[sp:], int32(len(buf[sp:])))
+
+	return buf, nil
+}
+
 // appendExecute appends a PostgreSQL wire protocol execute message to buf and returns it.
 func appendExecute(buf []byte, portal string, maxRows uint32) []byte {
 	buf = append(buf, 'E')
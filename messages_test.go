@@ -0,0 +1,281 @@
+package pgx_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/ronaldslc/pgx"
+	"github.com/ronaldslc/pgx/pgerrcode"
+)
+
+type unwrapError struct {
+	err error
+}
+
+func (e unwrapError) Error() string { return "unwrap: " + e.err.Error() }
+func (e unwrapError) Unwrap() error { return e.err }
+
+func TestAsPgError(t *testing.T) {
+	t.Parallel()
+
+	pgErr := pgx.PgError{Severity: "ERROR", Code: pgerrcode.UniqueViolation}
+
+	tests := []struct {
+		err error
+		ok  bool
+	}{
+		{pgErr, true},
+		{&pgErr, true},
+		{errors.Wrap(pgErr, "insert user"), true},
+		{errors.WithMessage(errors.Wrap(pgErr, "insert user"), "signup"), true},
+		{unwrapError{errors.Wrap(pgErr, "insert user")}, true},
+		{fmt.Errorf("%v", pgErr), false},
+		{errors.New("not a pg error"), false},
+		{nil, false},
+		{(*pgx.PgError)(nil), false},
+	}
+
+	for i, tt := range tests {
+		actual, ok := pgx.AsPgError(tt.err)
+		if ok != tt.ok {
+			t.Errorf("%d. AsPgError(%v): expected ok %v, got %v", i, tt.err, tt.ok, ok)
+			continue
+		}
+		if ok && actual != pgErr {
+			t.Errorf("%d. AsPgError(%v): expected %v, got %v", i, tt.err, pgErr, actual)
+		}
+	}
+}
+
+func TestPgErrorClassification(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		code                 string
+		class                string
+		uniqueViolation      bool
+		foreignKeyViolation  bool
+		serializationFailure bool
+		deadlockDetected     bool
+		connectionException  bool
+		retryable            bool
+	}{
+		{code: pgerrcode.UniqueViolation, class: "23", uniqueViolation: true},
+		{code: pgerrcode.ForeignKeyViolation, class: "23", foreignKeyViolation: true},
+		{code: pgerrcode.SerializationFailure, class: "40", serializationFailure: true, retryable: true},
+		{code: pgerrcode.DeadlockDetected, class: "40", deadlockDetected: true, retryable: true},
+		{code: pgerrcode.ConnectionFailure, class: "08", connectionException: true, retryable: true},
+		{code: pgerrcode.TransactionResolutionUnknown, class: "08", connectionException: true},
+		{code: pgerrcode.CannotConnectNow, class: "57", retryable: true},
+		{code: pgerrcode.QueryCanceled, class: "57"},
+		{code: pgerrcode.TransactionTimeout, class: "25"},
+		{code: pgerrcode.SyntaxError, class: "42"},
+	}
+
+	for i, tt := range tests {
+		pgErr := pgx.PgError{Code: tt.code}
+		if pgErr.Class() != tt.class {
+			t.Errorf("%d. %s: expected Class %s, got %s", i, tt.code, tt.class, pgErr.Class())
+		}
+		if pgErr.IsUniqueViolation() != tt.uniqueViolation {
+			t.Errorf("%d. %s: expected IsUniqueViolation %v", i, tt.code, tt.uniqueViolation)
+		}
+		if pgErr.IsForeignKeyViolation() != tt.foreignKeyViolation {
+			t.Errorf("%d. %s: expected IsForeignKeyViolation %v", i, tt.code, tt.foreignKeyViolation)
+		}
+		if pgErr.IsSerializationFailure() != tt.serializationFailure {
+			t.Errorf("%d. %s: expected IsSerializationFailure %v", i, tt.code, tt.serializationFailure)
+		}
+		if pgErr.IsDeadlockDetected() != tt.deadlockDetected {
+			t.Errorf("%d. %s: expected IsDeadlockDetected %v", i, tt.code, tt.deadlockDetected)
+		}
+		if pgErr.IsConnectionException() != tt.connectionException {
+			t.Errorf("%d. %s: expected IsConnectionException %v", i, tt.code, tt.connectionException)
+		}
+		if pgErr.IsRetryable() != tt.retryable {
+			t.Errorf("%d. %s: expected IsRetryable %v", i, tt.code, tt.retryable)
+		}
+	}
+}
+
+func TestPgErrorUniqueViolation(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnect(t, *defaultConnConfig)
+	defer closeConn(t, conn)
+
+	mustExec(t, conn, "create temporary table foo(id int4 primary key)")
+	mustExec(t, conn, "insert into foo values (1)")
+
+	_, err := conn.Exec("insert into foo values (1)")
+	pgErr, ok := pgx.AsPgError(errors.Wrap(err, "insert"))
+	if !ok {
+		t.Fatalf("Expected PgError, got %v", err)
+	}
+	if !pgErr.IsUniqueViolation() || pgErr.ConstraintName != "foo_pkey" {
+		t.Errorf("Expected unique violation of foo_pkey, got %v", pgErr)
+	}
+
+	ensureConnValid(t, conn)
+}
+
+func TestPgErrorVerbose(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		pgErr    pgx.PgError
+		expected string
+	}{
+		{
+			pgErr:    pgx.PgError{Severity: "ERROR", Code: "22012", Message: "division by zero"},
+			expected: "ERROR:  22012: division by zero",
+		},
+		{
+			pgErr: pgx.PgError{
+				Severity: "ERROR",
+				Code:     "42703",
+				Message:  `column "nme" does not exist`,
+				Hint:     `Perhaps you meant to reference the column "users.name".`,
+				Position: 8,
+				Routine:  "errorMissingColumn",
+				File:     "parse_relation.c",
+				Line:     3294,
+				SQL:      "select nme from users where id = $1",
+				Args:     "[42]",
+			},
+			expected: `ERROR:  42703: column "nme" does not exist
+LINE 1: select nme from users where id = $1
+               ^
+HINT:  Perhaps you meant to reference the column "users.name".
+LOCATION:  errorMissingColumn, parse_relation.c:3294
+ARGUMENTS:  [42]`,
+		},
+		{
+			pgErr: pgx.PgError{
+				Severity: "ERROR",
+				Code:     "42601",
+				Message:  `syntax error at or near "frm"`,
+				Position: 15,
+				SQL:      "select\n\tä, b\n\tfrm users",
+			},
+			expected: "ERROR:  42601: syntax error at or near \"frm\"\nLINE 3: \tfrm users\n        \t^",
+		},
+		{
+			pgErr: pgx.PgError{
+				Severity: "ERROR",
+				Code:     "42601",
+				Message:  `syntax error at end of input`,
+				Position: 14,
+				SQL:      "select * from",
+			},
+			expected: "ERROR:  42601: syntax error at end of input\nLINE 1: select * from\n                     ^",
+		},
+		{
+			pgErr: pgx.PgError{
+				Severity:         "ERROR",
+				Code:             "42703",
+				Message:          `column "y" does not exist`,
+				InternalQuery:    "select y",
+				InternalPosition: 8,
+				Where:            "PL/pgSQL function f() line 3 at RETURN",
+				SQL:              "select f()",
+			},
+			expected: `ERROR:  42703: column "y" does not exist
+QUERY:  select y
+               ^
+CONTEXT:  PL/pgSQL function f() line 3 at RETURN
+STATEMENT:  select f()`,
+		},
+		{
+			pgErr: pgx.PgError{
+				Severity: "ERROR",
+				Code:     "22P02",
+				Message:  "invalid input syntax",
+				Position: 68,
+				SQL:      "select " + strings.Repeat("a, ", 20) + "'x'::int4, " + strings.Repeat("b, ", 20) + "c",
+			},
+			expected: "ERROR:  22P02: invalid input syntax\n" +
+				"LINE 1: ..." + strings.Repeat("a, ", 10) + "'x'::int4, " + strings.Repeat("b, ", 6) + "b...\n" +
+				strings.Repeat(" ", 41) + "^",
+		},
+	}
+
+	for i, tt := range tests {
+		if actual := tt.pgErr.Verbose(); actual != tt.expected {
+			t.Errorf("%d. Verbose():\nexpected:\n%s\ngot:\n%s", i, tt.expected, actual)
+		}
+	}
+}
+
+func TestPgErrorSQLContext(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnect(t, *defaultConnConfig)
+	defer closeConn(t, conn)
+
+	_, err := conn.Exec("select $1::int4 / 0", 1)
+	pgErr, ok := err.(pgx.PgError)
+	if !ok {
+		t.Fatalf("Expected PgError, got %v", err)
+	}
+	if pgErr.SQL != "select $1::int4 / 0" || pgErr.Args != "[int]" {
+		t.Errorf("Expected SQL and Args to be set, got %q and %q", pgErr.SQL, pgErr.Args)
+	}
+
+	rows, err := conn.Query("select nme from pg_class where oid = $1", "secret")
+	if err == nil {
+		rows.Close()
+		err = rows.Err()
+	}
+	pgErr, ok = err.(pgx.PgError)
+	if !ok {
+		t.Fatalf("Expected PgError, got %v", err)
+	}
+	if pgErr.SQL != "select nme from pg_class where oid = $1" || pgErr.Args != "[string]" {
+		t.Errorf("Expected SQL and Args to be set, got %q and %q", pgErr.SQL, pgErr.Args)
+	}
+	if !strings.Contains(pgErr.Verbose(), "LINE 1: select nme from pg_class where oid = $1\n               ^") {
+		t.Errorf("Expected Verbose to point at nme, got:\n%s", pgErr.Verbose())
+	}
+
+	ensureConnValid(t, conn)
+
+	// Argument values are only included when the policy opts in.
+	config := *defaultConnConfig
+	config.LogArgs = &pgx.LogArgsPolicy{Mode: pgx.LogArgsAll}
+	valuesConn := mustConnect(t, config)
+	defer closeConn(t, valuesConn)
+
+	_, err = valuesConn.Exec("select $1::int4 / 0", 1)
+	pgErr, ok = err.(pgx.PgError)
+	if !ok {
+		t.Fatalf("Expected PgError, got %v", err)
+	}
+	if pgErr.Args != "[1]" {
+		t.Errorf("Expected Args with values, got %q", pgErr.Args)
+	}
+
+	// QueryResults returns the same error as the Rows it returns. The failed
+	// query ends the batch, so it gets its own connection.
+	batchConn := mustConnect(t, *defaultConnConfig)
+	defer batchConn.Close()
+
+	batch := batchConn.BeginBatch()
+	batch.Queue("select nme from pg_class where oid = $1", []interface{}{"secret"}, nil, nil)
+	if err := batch.Send(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	rows, err = batch.QueryResults()
+	pgErr, ok = err.(pgx.PgError)
+	if !ok {
+		t.Fatalf("Expected PgError, got %v", err)
+	}
+	if pgErr.SQL != "select nme from pg_class where oid = $1" || !reflect.DeepEqual(err, rows.Err()) {
+		t.Errorf("Expected QueryResults to return rows.Err() with SQL set, got %#v and %#v", err, rows.Err())
+	}
+	batch.Close()
+}
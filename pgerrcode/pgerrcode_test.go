@@ -0,0 +1,29 @@
+package pgerrcode_test
+
+import (
+	"testing"
+
+	"github.com/ronaldslc/pgx/pgerrcode"
+)
+
+func TestClass(t *testing.T) {
+	tests := []struct {
+		code     string
+		expected string
+	}{
+		{pgerrcode.UniqueViolation, pgerrcode.ClassIntegrityConstraintViolation},
+		{pgerrcode.ProtocolViolation, pgerrcode.ClassConnectionException},
+		{pgerrcode.DataCorrupted, pgerrcode.ClassInternalError},
+		{pgerrcode.InvalidArgumentForXquery, "10"},
+		{pgerrcode.TransactionTimeout, "25"},
+		{pgerrcode.FileNameTooLong, "58"},
+		{"0", "0"},
+		{"", ""},
+	}
+
+	for i, tt := range tests {
+		if actual := pgerrcode.Class(tt.code); actual != tt.expected {
+			t.Errorf("%d. Class(%q): expected %q, got %q", i, tt.code, tt.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,822 @@
+package pgmock
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ronaldslc/pgx"
+	"github.com/ronaldslc/pgx/pgerrcode"
+	"github.com/ronaldslc/pgx/pgproto3"
+	"github.com/ronaldslc/pgx/pgtype"
+)
+
+// Mock is a Controller that plays a PostgreSQL server from a list of expected
+// queries instead of a Script of messages. It handles the startup, the data
+// type query pgx runs after connecting and the Parse, Describe, Bind, Execute
+// and Sync messages of the extended protocol, so a test only declares the
+// queries it expects and their results as Go values:
+//
+//	mock := pgmock.NewMock()
+//	mock.ExpectQuery(`select name from users where id = \$1`).
+//		WithArgs(int32(42)).
+//		WillReturnRows([]string{"name"}, []interface{}{"Alice"})
+//
+//	server, err := pgmock.NewServer(mock)
+//
+// The queries must be run in the order they are expected. A query that does
+// not match the next expectation fails with an error and is reported by
+// ExpectationsWereMet.
+//
+// Values are encoded and decoded with pgtype. The data type of a parameter or
+// column is chosen from the Go type of the values given for it (see
+// WithArgs), so use the Go types the client scans into. Mock is safe for
+// concurrent use and can serve several connections.
+type Mock struct {
+	mux          sync.Mutex
+	connInfo     *pgtype.ConnInfo
+	expectations []*ExpectedQuery
+	next         int
+	errs         []error
+}
+
+// NewMock returns a Mock without expectations.
+func NewMock() *Mock {
+	nameOIDs := make(map[string]pgtype.OID, len(pgxInitTypes))
+	for _, t := range pgxInitTypes {
+		nameOIDs[t.name] = t.oid
+	}
+
+	connInfo := pgtype.NewConnInfo()
+	connInfo.InitializeDataTypes(nameOIDs)
+
+	return &Mock{connInfo: connInfo}
+}
+
+// ExpectQuery adds an expectation for a query whose SQL matches the regular
+// expression sqlRegexp. It panics if sqlRegexp does not compile. Queries run
+// with Exec are expected with ExpectQuery too.
+//
+// Without WillReturnRows, WillReturnCommandTag or WillReturnError the query
+// returns no rows and the command tag "SELECT 0".
+func (m *Mock) ExpectQuery(sqlRegexp string) *ExpectedQuery {
+	eq := &ExpectedQuery{
+		mock:       m,
+		sqlRegexp:  regexp.MustCompile(sqlRegexp),
+		commandTag: "SELECT 0",
+	}
+
+	m.mux.Lock()
+	m.expectations = append(m.expectations, eq)
+	m.mux.Unlock()
+
+	return eq
+}
+
+// ExpectationsWereMet returns an error if any expected query was not run or
+// if a client ran a query that was not expected.
+func (m *Mock) ExpectationsWereMet() error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if len(m.errs) > 0 {
+		return m.errs[0]
+	}
+	if m.next < len(m.expectations) {
+		return errors.Errorf("pgmock: expected query was not run: %s", m.expectations[m.next])
+	}
+	return nil
+}
+
+// Serve implements Controller.
+func (m *Mock) Serve(backend *pgproto3.Backend) error {
+	mc := &mockConn{
+		mock:       m,
+		backend:    backend,
+		statements: make(map[string]*mockStatement),
+		portals:    make(map[string]*mockPortal),
+		txStatus:   'I',
+	}
+	return mc.serve()
+}
+
+// peek returns the first expectation not run yet that matches sql.
+func (m *Mock) peek(sql string) *ExpectedQuery {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	for _, eq := range m.expectations[m.next:] {
+		if eq.sqlRegexp.MatchString(sql) {
+			return eq
+		}
+	}
+	return nil
+}
+
+// consume returns the next expectation if it matches sql and marks it as run.
+func (m *Mock) consume(sql string) (*ExpectedQuery, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if m.next >= len(m.expectations) {
+		return nil, m.fail(errors.Errorf("pgmock: unexpected query: %s", sql))
+	}
+
+	eq := m.expectations[m.next]
+	if !eq.sqlRegexp.MatchString(sql) {
+		return nil, m.fail(errors.Errorf("pgmock: query %q does not match next expectation %s", sql, eq))
+	}
+	m.next++
+
+	if eq.err != nil {
+		return nil, m.fail(eq.err)
+	}
+
+	return eq, nil
+}
+
+// reportError records err to be returned by ExpectationsWereMet and returns it.
+func (m *Mock) reportError(err error) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	return m.fail(err)
+}
+
+func (m *Mock) fail(err error) error {
+	m.errs = append(m.errs, err)
+	return err
+}
+
+// ExpectedQuery is a query expected by a Mock. Its methods configure the
+// expectation and return it so they can be chained.
+type ExpectedQuery struct {
+	mock      *Mock
+	sqlRegexp *regexp.Regexp
+
+	args []interface{}
+
+	columns     []string
+	columnTypes []*pgtype.DataType
+	rows        [][]interface{}
+	commandTag  string
+	pgErr       *pgx.PgError
+
+	// err is an error in the configuration of the expectation. It is reported
+	// when the query is run.
+	err error
+}
+
+// Argument is an expected argument that matches values itself instead of
+// being compared to them. v is the argument decoded with pgtype as returned
+// by pgtype.Value.Get, or nil for NULL.
+type Argument interface {
+	Match(v interface{}) bool
+}
+
+type anyArg struct{}
+
+func (anyArg) Match(v interface{}) bool { return true }
+
+// AnyArg returns an Argument that matches any value, including NULL.
+func AnyArg() Argument {
+	return anyArg{}
+}
+
+// WithArgs sets the arguments the query must be run with. Each argument is
+// an Argument, nil for NULL, a pgtype.Value, or a Go value that is converted
+// to the data type of the parameter with pgtype.Value.Set and compared to
+// the decoded argument.
+//
+// Unless the client specifies the parameter types when it prepares the query,
+// the parameter types described to the client are chosen from the Go types of
+// args: bool, int16, int32, int or int64, float32, float64, string, []byte,
+// time.Time, slices of these, or a pgtype.Value. Parameters without a usable
+// argument are described as text.
+func (eq *ExpectedQuery) WithArgs(args ...interface{}) *ExpectedQuery {
+	eq.args = args
+	return eq
+}
+
+// WillReturnRows sets the result of the query to rows of columns. Each row
+// has a value for each column. Values are nil for NULL, a pgtype.Value or a
+// Go value of a type supported by WithArgs. The data type of a column is
+// chosen from its first non-nil value, and is text when all values are nil.
+// The command tag is "SELECT n" where n is the number of rows.
+func (eq *ExpectedQuery) WillReturnRows(columns []string, rows ...[]interface{}) *ExpectedQuery {
+	eq.columns = columns
+	eq.rows = rows
+	eq.commandTag = fmt.Sprintf("SELECT %d", len(rows))
+
+	for i, row := range rows {
+		if len(row) != len(columns) {
+			eq.err = errors.Errorf("pgmock: row %d of %s has %d values for %d columns", i, eq, len(row), len(columns))
+			return eq
+		}
+	}
+
+	eq.columnTypes = make([]*pgtype.DataType, len(columns))
+	for i := range columns {
+		var v interface{}
+		for _, row := range rows {
+			if row[i] != nil {
+				v = row[i]
+				break
+			}
+		}
+
+		dt, err := eq.mock.dataTypeForValue(v)
+		if err != nil {
+			eq.err = errors.Wrapf(err, "pgmock: column %s of %s", columns[i], eq)
+			return eq
+		}
+		eq.columnTypes[i] = dt
+	}
+
+	return eq
+}
+
+// WillReturnCommandTag sets the command tag of the query, such as "INSERT 0 1"
+// for a query run with Exec. A command tag of "BEGIN", "COMMIT" or "ROLLBACK"
+// also changes the transaction status the mock reports to the client.
+func (eq *ExpectedQuery) WillReturnCommandTag(commandTag string) *ExpectedQuery {
+	eq.commandTag = commandTag
+	return eq
+}
+
+// WillReturnError makes the query fail with pgErr. Severity defaults to
+// ERROR. A FATAL error closes the connection as the server would.
+func (eq *ExpectedQuery) WillReturnError(pgErr pgx.PgError) *ExpectedQuery {
+	eq.pgErr = &pgErr
+	return eq
+}
+
+func (eq *ExpectedQuery) String() string {
+	if len(eq.args) == 0 {
+		return eq.sqlRegexp.String()
+	}
+	return fmt.Sprintf("%s with args %v", eq.sqlRegexp, eq.args)
+}
+
+// fields returns the RowDescription fields of the result in formats, which
+// are result format codes as in Bind.
+func (eq *ExpectedQuery) fields(formats []int16) []pgproto3.FieldDescription {
+	fields := make([]pgproto3.FieldDescription, len(eq.columns))
+	for i, dt := range eq.columnTypes {
+		fields[i] = pgproto3.FieldDescription{
+			Name:         eq.columns[i],
+			DataTypeOID:  uint32(dt.OID),
+			DataTypeSize: -1,
+			TypeModifier: 4294967295,
+			Format:       formatCode(formats, i),
+		}
+	}
+	return fields
+}
+
+// matchArgs returns an error if the parameters bound to stmt do not match
+// the expected arguments.
+func (eq *ExpectedQuery) matchArgs(ci *pgtype.ConnInfo, stmt *mockStatement, bind *pgproto3.Bind) error {
+	if eq.args == nil {
+		return nil
+	}
+
+	params := bind.Parameters
+	if len(params) != len(eq.args) {
+		return errors.Errorf("pgmock: %s run with %d args", eq, len(params))
+	}
+
+	for i, expected := range eq.args {
+		oid := pgtype.OID(pgtype.TextOID)
+		if i < len(stmt.paramOIDs) {
+			oid = stmt.paramOIDs[i]
+		}
+		dt, ok := ci.DataTypeForOID(oid)
+		if !ok {
+			return errors.Errorf("pgmock: unknown data type for parameter $%d: %d", i+1, oid)
+		}
+
+		var actual interface{}
+		if params[i] != nil {
+			value := newValue(dt)
+			if err := decodeValue(ci, value, formatCode(bind.ParameterFormatCodes, i), params[i]); err != nil {
+				return errors.Wrapf(err, "pgmock: decoding parameter $%d", i+1)
+			}
+			actual = value.Get()
+		}
+
+		if !argMatches(expected, actual, dt) {
+			return errors.Errorf("pgmock: %s run with $%d = %v", eq, i+1, actual)
+		}
+	}
+
+	return nil
+}
+
+func argMatches(expected, actual interface{}, dt *pgtype.DataType) bool {
+	if a, ok := expected.(Argument); ok {
+		return a.Match(actual)
+	}
+	if v, ok := expected.(pgtype.Value); ok {
+		expected = v.Get()
+	}
+	if expected == nil || actual == nil {
+		return expected == nil && actual == nil
+	}
+
+	value := newValue(dt)
+	if err := value.Set(expected); err != nil {
+		return false
+	}
+	expected = value.Get()
+
+	if e, ok := expected.(time.Time); ok {
+		a, ok := actual.(time.Time)
+		return ok && e.Equal(a)
+	}
+	return reflect.DeepEqual(expected, actual)
+}
+
+// dataRows encodes the rows of the result in formats, which are result format
+// codes as in Bind.
+func (eq *ExpectedQuery) dataRows(ci *pgtype.ConnInfo, formats []int16) ([]*pgproto3.DataRow, error) {
+	dataRows := make([]*pgproto3.DataRow, len(eq.rows))
+	for i, row := range eq.rows {
+		dr := &pgproto3.DataRow{Values: make([][]byte, len(row))}
+		for j, v := range row {
+			buf, err := encodeValue(ci, eq.columnTypes[j], formatCode(formats, j), v)
+			if err != nil {
+				return nil, errors.Wrapf(err, "pgmock: row %d column %s of %s", i, eq.columns[j], eq)
+			}
+			dr.Values[j] = buf
+		}
+		dataRows[i] = dr
+	}
+	return dataRows, nil
+}
+
+// initQuery answers the data type query pgx runs after connecting.
+func (m *Mock) initQuery() *ExpectedQuery {
+	oidType, _ := m.connInfo.DataTypeForName("oid")
+	nameType, _ := m.connInfo.DataTypeForName("name")
+
+	eq := &ExpectedQuery{
+		mock:        m,
+		sqlRegexp:   regexp.MustCompile(regexp.QuoteMeta(pgxInitSQL)),
+		columns:     []string{"oid", "typname"},
+		columnTypes: []*pgtype.DataType{oidType, nameType},
+		commandTag:  fmt.Sprintf("SELECT %d", len(pgxInitTypes)),
+	}
+	for _, t := range pgxInitTypes {
+		eq.rows = append(eq.rows, []interface{}{uint32(t.oid), t.name})
+	}
+
+	return eq
+}
+
+// dataTypeForValue returns the data type for the Go value v. nil is text.
+func (m *Mock) dataTypeForValue(v interface{}) (*pgtype.DataType, error) {
+	if v, ok := v.(pgtype.Value); ok {
+		if dt, ok := m.connInfo.DataTypeForValue(v); ok {
+			return dt, nil
+		}
+		return nil, errors.Errorf("no data type for %T", v)
+	}
+
+	var name string
+	switch v.(type) {
+	case nil, string:
+		name = "text"
+	case bool:
+		name = "bool"
+	case int16:
+		name = "int2"
+	case int32:
+		name = "int4"
+	case int, int64:
+		name = "int8"
+	case float32:
+		name = "float4"
+	case float64:
+		name = "float8"
+	case []byte:
+		name = "bytea"
+	case time.Time:
+		name = "timestamptz"
+	case []bool:
+		name = "_bool"
+	case []int16:
+		name = "_int2"
+	case []int32:
+		name = "_int4"
+	case []int, []int64:
+		name = "_int8"
+	case []float32:
+		name = "_float4"
+	case []float64:
+		name = "_float8"
+	case []string:
+		name = "_text"
+	case []time.Time:
+		name = "_timestamptz"
+	default:
+		return nil, errors.Errorf("no data type for %T", v)
+	}
+
+	dt, ok := m.connInfo.DataTypeForName(name)
+	if !ok {
+		return nil, errors.Errorf("no data type %s", name)
+	}
+	return dt, nil
+}
+
+func newValue(dt *pgtype.DataType) pgtype.Value {
+	return reflect.New(reflect.ValueOf(dt.Value).Elem().Type()).Interface().(pgtype.Value)
+}
+
+func formatCode(formats []int16, i int) int16 {
+	switch len(formats) {
+	case 0:
+		return pgproto3.TextFormat
+	case 1:
+		return formats[0]
+	default:
+		return formats[i]
+	}
+}
+
+func decodeValue(ci *pgtype.ConnInfo, value pgtype.Value, format int16, src []byte) error {
+	switch format {
+	case pgproto3.TextFormat:
+		if d, ok := value.(pgtype.TextDecoder); ok {
+			return d.DecodeText(ci, src)
+		}
+	case pgproto3.BinaryFormat:
+		if d, ok := value.(pgtype.BinaryDecoder); ok {
+			return d.DecodeBinary(ci, src)
+		}
+	}
+	return errors.Errorf("%T can't decode format %d", value, format)
+}
+
+// encodeValue encodes v as dt in format. It returns nil for NULL.
+func encodeValue(ci *pgtype.ConnInfo, dt *pgtype.DataType, format int16, v interface{}) ([]byte, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	value, ok := v.(pgtype.Value)
+	if !ok || reflect.TypeOf(value) != reflect.TypeOf(dt.Value) {
+		value = newValue(dt)
+		if err := value.Set(v); err != nil {
+			return nil, err
+		}
+	}
+
+	switch format {
+	case pgproto3.TextFormat:
+		if e, ok := value.(pgtype.TextEncoder); ok {
+			return e.EncodeText(ci, nil)
+		}
+	case pgproto3.BinaryFormat:
+		if e, ok := value.(pgtype.BinaryEncoder); ok {
+			return e.EncodeBinary(ci, nil)
+		}
+	}
+	return nil, errors.Errorf("%T can't encode format %d", value, format)
+}
+
+var paramRegexp = regexp.MustCompile(`\$(\d+)`)
+
+// countParams returns the highest parameter number in sql.
+func countParams(sql string) int {
+	n := 0
+	for _, match := range paramRegexp.FindAllStringSubmatch(sql, -1) {
+		if i, err := strconv.Atoi(match[1]); err == nil && i > n {
+			n = i
+		}
+	}
+	return n
+}
+
+type mockStatement struct {
+	sql       string
+	init      bool
+	paramOIDs []pgtype.OID
+}
+
+// mockPortal is a bound statement. The Bind message is not kept because the
+// Backend reuses it for the next Bind.
+type mockPortal struct {
+	resultFormats []int16
+	eq            *ExpectedQuery
+}
+
+// mockConn is the state of a connection served by a Mock.
+type mockConn struct {
+	mock       *Mock
+	backend    *pgproto3.Backend
+	statements map[string]*mockStatement
+	portals    map[string]*mockPortal
+	txStatus   byte
+
+	// skipUntilSync is set after an error in the extended protocol. Messages
+	// are ignored until the next Sync.
+	skipUntilSync bool
+}
+
+func (mc *mockConn) serve() error {
+	msg, err := mc.backend.ReceiveStartupMessage()
+	if err != nil {
+		return err
+	}
+	if _, ok := msg.(*pgproto3.StartupMessage); !ok {
+		return errors.Errorf("pgmock: mock can't handle %T", msg)
+	}
+
+	startupMsgs := []pgproto3.BackendMessage{
+		&pgproto3.Authentication{Type: pgproto3.AuthTypeOk},
+		&pgproto3.ParameterStatus{Name: "client_encoding", Value: "UTF8"},
+		&pgproto3.ParameterStatus{Name: "DateStyle", Value: "ISO, MDY"},
+		&pgproto3.ParameterStatus{Name: "integer_datetimes", Value: "on"},
+		&pgproto3.ParameterStatus{Name: "server_encoding", Value: "UTF8"},
+		&pgproto3.ParameterStatus{Name: "server_version", Value: "10.0"},
+		&pgproto3.ParameterStatus{Name: "standard_conforming_strings", Value: "on"},
+		&pgproto3.ParameterStatus{Name: "TimeZone", Value: "UTC"},
+		&pgproto3.BackendKeyData{ProcessID: 1, SecretKey: 1},
+		&pgproto3.ReadyForQuery{TxStatus: mc.txStatus},
+	}
+	if err := mc.send(startupMsgs...); err != nil {
+		return err
+	}
+
+	for {
+		msg, err := mc.backend.Receive()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if _, ok := msg.(*pgproto3.Terminate); ok {
+			return nil
+		}
+
+		if mc.skipUntilSync {
+			if _, ok := msg.(*pgproto3.Sync); !ok {
+				continue
+			}
+		}
+
+		var fatal bool
+		switch msg := msg.(type) {
+		case *pgproto3.Query:
+			fatal, err = mc.handleQuery(msg)
+		case *pgproto3.Parse:
+			fatal, err = mc.handleParse(msg)
+		case *pgproto3.Describe:
+			fatal, err = mc.handleDescribe(msg)
+		case *pgproto3.Bind:
+			fatal, err = mc.handleBind(msg)
+		case *pgproto3.Execute:
+			fatal, err = mc.handleExecute(msg)
+		case *pgproto3.Close:
+			if msg.ObjectType == 'S' {
+				delete(mc.statements, msg.Name)
+			} else {
+				delete(mc.portals, msg.Name)
+			}
+			err = mc.send(&pgproto3.CloseComplete{})
+		case *pgproto3.Sync:
+			mc.skipUntilSync = false
+			err = mc.send(&pgproto3.ReadyForQuery{TxStatus: mc.txStatus})
+		case *pgproto3.Flush:
+		default:
+			fatal, err = mc.sendError(mc.mock.reportError(errors.Errorf("pgmock: mock can't handle %T", msg)), nil)
+		}
+		if err != nil || fatal {
+			return err
+		}
+	}
+}
+
+func (mc *mockConn) send(msgs ...pgproto3.BackendMessage) error {
+	for _, msg := range msgs {
+		if err := mc.backend.Send(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendError sends pgErr to the client, or err as an internal error if pgErr is
+// nil, and skips the rest of the extended protocol messages until Sync. It
+// returns true if the error is FATAL and the connection must be closed.
+func (mc *mockConn) sendError(err error, pgErr *pgx.PgError) (bool, error) {
+	if pgErr == nil {
+		pgErr = &pgx.PgError{Code: pgerrcode.InternalError, Message: err.Error()}
+	}
+
+	severity := pgErr.Severity
+	if severity == "" {
+		severity = "ERROR"
+	}
+
+	mc.skipUntilSync = true
+	if mc.txStatus == 'T' {
+		mc.txStatus = 'E'
+	}
+
+	return severity == "FATAL" || severity == "PANIC", mc.send(&pgproto3.ErrorResponse{
+		Severity:         severity,
+		Code:             pgErr.Code,
+		Message:          pgErr.Message,
+		Detail:           pgErr.Detail,
+		Hint:             pgErr.Hint,
+		Position:         pgErr.Position,
+		InternalPosition: pgErr.InternalPosition,
+		InternalQuery:    pgErr.InternalQuery,
+		Where:            pgErr.Where,
+		SchemaName:       pgErr.SchemaName,
+		TableName:        pgErr.TableName,
+		ColumnName:       pgErr.ColumnName,
+		DataTypeName:     pgErr.DataTypeName,
+		ConstraintName:   pgErr.ConstraintName,
+		File:             pgErr.File,
+		Line:             pgErr.Line,
+		Routine:          pgErr.Routine,
+	})
+}
+
+// complete sends the rows and command tag of eq and updates the transaction
+// status from the command tag.
+func (mc *mockConn) complete(eq *ExpectedQuery, formats []int16) (bool, error) {
+	dataRows, err := eq.dataRows(mc.mock.connInfo, formats)
+	if err != nil {
+		return mc.sendError(mc.mock.reportError(err), nil)
+	}
+	for _, dr := range dataRows {
+		if err := mc.send(dr); err != nil {
+			return false, err
+		}
+	}
+
+	switch strings.ToUpper(eq.commandTag) {
+	case "BEGIN":
+		mc.txStatus = 'T'
+	case "COMMIT", "ROLLBACK":
+		mc.txStatus = 'I'
+	}
+
+	return false, mc.send(&pgproto3.CommandComplete{CommandTag: eq.commandTag})
+}
+
+func (mc *mockConn) handleQuery(msg *pgproto3.Query) (bool, error) {
+	if strings.TrimSpace(msg.String) == "" {
+		return false, mc.send(&pgproto3.EmptyQueryResponse{}, &pgproto3.ReadyForQuery{TxStatus: mc.txStatus})
+	}
+
+	fatal, err := mc.simpleQuery(msg.String)
+	if err != nil || fatal {
+		return fatal, err
+	}
+
+	mc.skipUntilSync = false
+	return false, mc.send(&pgproto3.ReadyForQuery{TxStatus: mc.txStatus})
+}
+
+func (mc *mockConn) simpleQuery(sql string) (bool, error) {
+	eq, err := mc.mock.consume(sql)
+	if err != nil {
+		return mc.sendError(err, nil)
+	}
+	if eq.pgErr != nil {
+		return mc.sendError(nil, eq.pgErr)
+	}
+
+	if len(eq.columns) > 0 {
+		if err := mc.send(&pgproto3.RowDescription{Fields: eq.fields(nil)}); err != nil {
+			return false, err
+		}
+	}
+	return mc.complete(eq, nil)
+}
+
+func (mc *mockConn) handleParse(msg *pgproto3.Parse) (bool, error) {
+	stmt := &mockStatement{sql: msg.Query, init: msg.Query == pgxInitSQL}
+
+	var eq *ExpectedQuery
+	if !stmt.init {
+		eq = mc.mock.peek(msg.Query)
+	}
+
+	n := len(msg.ParameterOIDs)
+	if c := countParams(msg.Query); c > n {
+		n = c
+	}
+	if eq != nil && len(eq.args) > n {
+		n = len(eq.args)
+	}
+
+	stmt.paramOIDs = make([]pgtype.OID, n)
+	for i := range stmt.paramOIDs {
+		if i < len(msg.ParameterOIDs) && msg.ParameterOIDs[i] != 0 {
+			stmt.paramOIDs[i] = pgtype.OID(msg.ParameterOIDs[i])
+			continue
+		}
+
+		stmt.paramOIDs[i] = pgtype.TextOID
+		if eq != nil && i < len(eq.args) && eq.args[i] != nil {
+			if _, ok := eq.args[i].(Argument); ok {
+				continue
+			}
+			if dt, err := mc.mock.dataTypeForValue(eq.args[i]); err == nil {
+				stmt.paramOIDs[i] = dt.OID
+			}
+		}
+	}
+
+	mc.statements[msg.Name] = stmt
+	return false, mc.send(&pgproto3.ParseComplete{})
+}
+
+func (mc *mockConn) handleDescribe(msg *pgproto3.Describe) (bool, error) {
+	if msg.ObjectType == 'P' {
+		portal, ok := mc.portals[msg.Name]
+		if !ok {
+			return mc.sendError(mc.mock.reportError(errors.Errorf("pgmock: unknown portal %q", msg.Name)), nil)
+		}
+		return false, mc.sendRowDescription(portal.eq, portal.resultFormats)
+	}
+
+	stmt, ok := mc.statements[msg.Name]
+	if !ok {
+		return mc.sendError(mc.mock.reportError(errors.Errorf("pgmock: unknown prepared statement %q", msg.Name)), nil)
+	}
+
+	var eq *ExpectedQuery
+	if stmt.init {
+		eq = mc.mock.initQuery()
+	} else if eq = mc.mock.peek(stmt.sql); eq == nil {
+		return mc.sendError(mc.mock.reportError(errors.Errorf("pgmock: unexpected query: %s", stmt.sql)), nil)
+	}
+
+	paramOIDs := make([]uint32, len(stmt.paramOIDs))
+	for i, oid := range stmt.paramOIDs {
+		paramOIDs[i] = uint32(oid)
+	}
+	if err := mc.send(&pgproto3.ParameterDescription{ParameterOIDs: paramOIDs}); err != nil {
+		return false, err
+	}
+
+	return false, mc.sendRowDescription(eq, nil)
+}
+
+func (mc *mockConn) sendRowDescription(eq *ExpectedQuery, formats []int16) error {
+	if len(eq.columns) == 0 {
+		return mc.send(&pgproto3.NoData{})
+	}
+	return mc.send(&pgproto3.RowDescription{Fields: eq.fields(formats)})
+}
+
+func (mc *mockConn) handleBind(msg *pgproto3.Bind) (bool, error) {
+	stmt, ok := mc.statements[msg.PreparedStatement]
+	if !ok {
+		return mc.sendError(mc.mock.reportError(errors.Errorf("pgmock: unknown prepared statement %q", msg.PreparedStatement)), nil)
+	}
+
+	portal := &mockPortal{resultFormats: append([]int16(nil), msg.ResultFormatCodes...)}
+	if stmt.init {
+		portal.eq = mc.mock.initQuery()
+	} else {
+		eq, err := mc.mock.consume(stmt.sql)
+		if err != nil {
+			return mc.sendError(err, nil)
+		}
+		if err := eq.matchArgs(mc.mock.connInfo, stmt, msg); err != nil {
+			return mc.sendError(mc.mock.reportError(err), nil)
+		}
+		portal.eq = eq
+	}
+
+	mc.portals[msg.DestinationPortal] = portal
+	return false, mc.send(&pgproto3.BindComplete{})
+}
+
+func (mc *mockConn) handleExecute(msg *pgproto3.Execute) (bool, error) {
+	portal, ok := mc.portals[msg.Portal]
+	if !ok {
+		return mc.sendError(mc.mock.reportError(errors.Errorf("pgmock: unknown portal %q", msg.Portal)), nil)
+	}
+
+	if portal.eq.pgErr != nil {
+		return mc.sendError(nil, portal.eq.pgErr)
+	}
+	return mc.complete(portal.eq, portal.resultFormats)
+}
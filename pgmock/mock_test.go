@@ -0,0 +1,311 @@
+package pgmock
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ronaldslc/pgx"
+	"github.com/ronaldslc/pgx/pgproto3"
+	"github.com/ronaldslc/pgx/pgtype"
+)
+
+// rawClient speaks the protocol to a Mock without pgx.
+type rawClient struct {
+	t    *testing.T
+	conn net.Conn
+	r    *backendReader
+}
+
+func connectRawClient(t *testing.T, addr net.Addr) *rawClient {
+	conn, err := net.Dial(addr.Network(), addr.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &rawClient{t: t, conn: conn, r: newBackendReader(conn)}
+	c.send(&pgproto3.StartupMessage{ProtocolVersion: pgproto3.ProtocolVersionNumber, Parameters: map[string]string{"user": "pgx"}})
+	c.receiveUntilReady()
+
+	return c
+}
+
+func (c *rawClient) send(msgs ...pgproto3.FrontendMessage) {
+	var buf []byte
+	for _, msg := range msgs {
+		buf = msg.Encode(buf)
+	}
+	if _, err := c.conn.Write(buf); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *rawClient) receiveUntilReady() []pgproto3.BackendMessage {
+	var received []pgproto3.BackendMessage
+	for {
+		msg, err := c.r.Receive()
+		if err != nil {
+			c.t.Fatal(err)
+		}
+		received = append(received, msg)
+		if _, ok := msg.(*pgproto3.ReadyForQuery); ok {
+			return received
+		}
+	}
+}
+
+func (c *rawClient) close() {
+	c.send(&pgproto3.Terminate{})
+	c.conn.Close()
+}
+
+func serveMock(t *testing.T, mock *Mock) (*Server, chan error) {
+	server, err := NewServer(mock)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- server.ServeOne()
+	}()
+
+	return server, errChan
+}
+
+func messageTypes(msgs []pgproto3.BackendMessage) []string {
+	types := make([]string, len(msgs))
+	for i, msg := range msgs {
+		types[i] = messageTypeName(msg)
+	}
+	return types
+}
+
+func TestMockExtendedProtocol(t *testing.T) {
+	mock := NewMock()
+	mock.ExpectQuery(`select id, name from users where id = \$1`).
+		WithArgs(int32(42)).
+		WillReturnRows([]string{"id", "name"},
+			[]interface{}{int32(42), "Alice"},
+			[]interface{}{int32(43), nil},
+		)
+
+	server, errChan := serveMock(t, mock)
+	defer server.Close()
+
+	c := connectRawClient(t, server.Addr())
+
+	sql := "select id, name from users where id = $1"
+	c.send(&pgproto3.Parse{Query: sql}, &pgproto3.Describe{ObjectType: 'S'}, &pgproto3.Sync{})
+	received := c.receiveUntilReady()
+
+	expectedTypes := []string{"ParseComplete", "ParameterDescription", "RowDescription", "ReadyForQuery"}
+	if types := messageTypes(received); !reflect.DeepEqual(types, expectedTypes) {
+		t.Fatalf("Expected %v, got %v", expectedTypes, types)
+	}
+	if pd := received[1].(*pgproto3.ParameterDescription); !reflect.DeepEqual(pd.ParameterOIDs, []uint32{pgtype.Int4OID}) {
+		t.Errorf("Expected int4 parameter, got %v", pd.ParameterOIDs)
+	}
+	rd := received[2].(*pgproto3.RowDescription)
+	if len(rd.Fields) != 2 || rd.Fields[0].Name != "id" || rd.Fields[0].DataTypeOID != pgtype.Int4OID || rd.Fields[1].DataTypeOID != pgtype.TextOID {
+		t.Errorf("Unexpected RowDescription: %v", rd.Fields)
+	}
+
+	arg, err := (&pgtype.Int4{Int: 42, Status: pgtype.Present}).EncodeBinary(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.send(
+		&pgproto3.Bind{ParameterFormatCodes: []int16{pgproto3.BinaryFormat}, Parameters: [][]byte{arg}, ResultFormatCodes: []int16{pgproto3.BinaryFormat, pgproto3.TextFormat}},
+		&pgproto3.Execute{},
+		&pgproto3.Sync{},
+	)
+	received = c.receiveUntilReady()
+
+	expected := []pgproto3.BackendMessage{
+		&pgproto3.BindComplete{},
+		&pgproto3.DataRow{Values: [][]byte{{0, 0, 0, 42}, []byte("Alice")}},
+		&pgproto3.DataRow{Values: [][]byte{{0, 0, 0, 43}, nil}},
+		&pgproto3.CommandComplete{CommandTag: "SELECT 2"},
+		&pgproto3.ReadyForQuery{TxStatus: 'I'},
+	}
+	if !reflect.DeepEqual(received, expected) {
+		t.Errorf("Expected %#v, got %#v", expected, received)
+	}
+
+	c.close()
+	if err := <-errChan; err != nil {
+		t.Fatalf("mock err: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMockNamedPortals(t *testing.T) {
+	mock := NewMock()
+	mock.ExpectQuery("select n").WillReturnRows([]string{"n"}, []interface{}{int32(1)})
+	mock.ExpectQuery("select n").WillReturnRows([]string{"n"}, []interface{}{int32(2)})
+
+	server, errChan := serveMock(t, mock)
+	defer server.Close()
+
+	c := connectRawClient(t, server.Addr())
+
+	// The second Bind must not change the result formats of the first portal.
+	c.send(
+		&pgproto3.Parse{Query: "select n"},
+		&pgproto3.Bind{DestinationPortal: "a", ResultFormatCodes: []int16{pgproto3.BinaryFormat}},
+		&pgproto3.Bind{DestinationPortal: "b", ResultFormatCodes: []int16{pgproto3.TextFormat}},
+		&pgproto3.Describe{ObjectType: 'P', Name: "a"},
+		&pgproto3.Execute{Portal: "a"},
+		&pgproto3.Execute{Portal: "b"},
+		&pgproto3.Sync{},
+	)
+	received := c.receiveUntilReady()
+
+	expected := []pgproto3.BackendMessage{
+		&pgproto3.ParseComplete{},
+		&pgproto3.BindComplete{},
+		&pgproto3.BindComplete{},
+		&pgproto3.RowDescription{Fields: []pgproto3.FieldDescription{
+			{Name: "n", DataTypeOID: pgtype.Int4OID, DataTypeSize: -1, TypeModifier: 4294967295, Format: pgproto3.BinaryFormat},
+		}},
+		&pgproto3.DataRow{Values: [][]byte{{0, 0, 0, 1}}},
+		&pgproto3.CommandComplete{CommandTag: "SELECT 1"},
+		&pgproto3.DataRow{Values: [][]byte{[]byte("2")}},
+		&pgproto3.CommandComplete{CommandTag: "SELECT 1"},
+		&pgproto3.ReadyForQuery{TxStatus: 'I'},
+	}
+	if !reflect.DeepEqual(received, expected) {
+		t.Errorf("Expected %#v, got %#v", expected, received)
+	}
+
+	c.close()
+	if err := <-errChan; err != nil {
+		t.Fatalf("mock err: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMockErrors(t *testing.T) {
+	mock := NewMock()
+	mock.ExpectQuery("insert into users").
+		WillReturnError(pgx.PgError{Code: "23505", Message: "duplicate key value violates unique constraint"})
+	mock.ExpectQuery("update users").WithArgs("Bob")
+	mock.ExpectQuery("delete from users")
+
+	server, errChan := serveMock(t, mock)
+	defer server.Close()
+
+	c := connectRawClient(t, server.Addr())
+
+	// Error returned by the expectation with the simple protocol.
+	c.send(&pgproto3.Query{String: "insert into users values (1)"})
+	received := c.receiveUntilReady()
+	if types := messageTypes(received); !reflect.DeepEqual(types, []string{"ErrorResponse", "ReadyForQuery"}) {
+		t.Fatalf("Expected error, got %v", types)
+	}
+	if er := received[0].(*pgproto3.ErrorResponse); er.Severity != "ERROR" || er.Code != "23505" {
+		t.Errorf("Unexpected ErrorResponse: %#v", er)
+	}
+
+	// Argument mismatch skips the rest of the messages until Sync.
+	c.send(
+		&pgproto3.Parse{Query: "update users set name = $1"},
+		&pgproto3.Bind{Parameters: [][]byte{[]byte("Carol")}},
+		&pgproto3.Execute{},
+		&pgproto3.Sync{},
+	)
+	received = c.receiveUntilReady()
+	if types := messageTypes(received); !reflect.DeepEqual(types, []string{"ParseComplete", "ErrorResponse", "ReadyForQuery"}) {
+		t.Fatalf("Expected argument mismatch, got %v", types)
+	}
+
+	// Queries out of order are not expected.
+	c.send(&pgproto3.Query{String: "select 1"})
+	received = c.receiveUntilReady()
+	if types := messageTypes(received); !reflect.DeepEqual(types, []string{"ErrorResponse", "ReadyForQuery"}) {
+		t.Fatalf("Expected unexpected query error, got %v", types)
+	}
+
+	c.close()
+	if err := <-errChan; err != nil {
+		t.Fatalf("mock err: %v", err)
+	}
+
+	err := mock.ExpectationsWereMet()
+	if err == nil || !strings.Contains(err.Error(), "$1 = Carol") {
+		t.Errorf("Expected argument mismatch to be reported, got %v", err)
+	}
+}
+
+func TestMockUnmetExpectations(t *testing.T) {
+	mock := NewMock()
+	mock.ExpectQuery("select 1")
+
+	if err := mock.ExpectationsWereMet(); err == nil {
+		t.Error("Expected unmet expectation error")
+	}
+}
+
+func TestMockPgx(t *testing.T) {
+	mock := NewMock()
+	mock.ExpectQuery("begin").WillReturnCommandTag("BEGIN")
+	mock.ExpectQuery(`select name, score from users where id = \$1`).
+		WithArgs(1).
+		WillReturnRows([]string{"name", "score"}, []interface{}{"Alice", 1.5})
+	mock.ExpectQuery("insert into users").
+		WithArgs(AnyArg()).
+		WillReturnError(pgx.PgError{Code: "23505", Message: "duplicate key"})
+	mock.ExpectQuery("rollback").WillReturnCommandTag("ROLLBACK")
+
+	server, errChan := serveMock(t, mock)
+	defer server.Close()
+
+	config, err := pgx.ParseURI(fmt.Sprintf("postgres://pgx@%s/pgx_test?sslmode=disable", server.Addr()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := pgx.Connect(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := conn.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var name string
+	var score float64
+	if err := tx.QueryRow("select name, score from users where id = $1", 1).Scan(&name, &score); err != nil {
+		t.Fatal(err)
+	}
+	if name != "Alice" || score != 1.5 {
+		t.Errorf("Expected Alice and 1.5, got %s and %v", name, score)
+	}
+
+	_, err = tx.Exec("insert into users(name) values($1)", "Bob")
+	if pgErr, ok := pgx.AsPgError(err); !ok || !pgErr.IsUniqueViolation() {
+		t.Errorf("Expected unique violation, got %v", err)
+	}
+
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := conn.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errChan; err != nil {
+		t.Fatalf("mock err: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
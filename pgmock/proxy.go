@@ -0,0 +1,266 @@
+package pgmock
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+
+	"github.com/pkg/errors"
+
+	"github.com/ronaldslc/pgx/chunkreader"
+	"github.com/ronaldslc/pgx/pgproto3"
+)
+
+// Proxy sits between a client and a PostgreSQL server and records the messages
+// they exchange with a Recorder. Replay the recording with LoadScript to run
+// the client against a Server without the database.
+//
+// The proxy refuses TLS and GSSAPI encryption requests from the client, so the
+// client must allow unencrypted connections, and it connects to the server
+// without TLS. Cancel requests are not forwarded.
+type Proxy struct {
+	ln            net.Listener
+	serverNetwork string
+	serverAddress string
+	recorder      *Recorder
+}
+
+// NewProxy returns a Proxy listening on a random local port that forwards to
+// the server at serverNetwork and serverAddress (as in net.Dial) and writes
+// the recording to w.
+func NewProxy(serverNetwork, serverAddress string, w io.Writer) (*Proxy, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:")
+	if err != nil {
+		return nil, err
+	}
+
+	proxy := &Proxy{
+		ln:            ln,
+		serverNetwork: serverNetwork,
+		serverAddress: serverAddress,
+		recorder:      NewRecorder(w),
+	}
+
+	return proxy, nil
+}
+
+func (p *Proxy) Addr() net.Addr {
+	return p.ln.Addr()
+}
+
+// ServeOne accepts one client connection, stops listening and forwards and
+// records its messages until the client or the server closes the connection.
+func (p *Proxy) ServeOne() error {
+	clientConn, err := p.ln.Accept()
+	if err != nil {
+		return err
+	}
+	defer clientConn.Close()
+
+	p.Close()
+
+	backend, err := pgproto3.NewBackend(clientConn, clientConn)
+	if err != nil {
+		return err
+	}
+
+	startupMsg, err := p.receiveStartupMessage(clientConn, backend)
+	if err != nil {
+		return err
+	}
+
+	serverConn, err := net.Dial(p.serverNetwork, p.serverAddress)
+	if err != nil {
+		return err
+	}
+	defer serverConn.Close()
+
+	frontend, err := pgproto3.NewFrontend(serverConn, serverConn)
+	if err != nil {
+		return err
+	}
+	server := newBackendReader(serverConn)
+
+	if err := p.forwardFrontend(frontend, startupMsg); err != nil {
+		return err
+	}
+
+	// Authentication is relayed in one goroutine because the Authentication
+	// sent determines how the backend decodes the client's response.
+	for ready := false; !ready; {
+		msg, err := server.Receive()
+		if err != nil {
+			return err
+		}
+		if err := p.forwardBackend(backend, msg); err != nil {
+			return err
+		}
+
+		switch msg := msg.(type) {
+		case *pgproto3.Authentication:
+			if msg.Type == pgproto3.AuthTypeOk || msg.Type == pgproto3.AuthTypeSASLFinal {
+				continue
+			}
+			response, err := backend.Receive()
+			if err != nil {
+				return err
+			}
+			if err := p.forwardFrontend(frontend, response); err != nil {
+				return err
+			}
+		case *pgproto3.ErrorResponse:
+			// The server closes the connection after a startup error.
+			return nil
+		case *pgproto3.ReadyForQuery:
+			ready = true
+		}
+	}
+
+	errChan := make(chan error, 2)
+	go func() {
+		for {
+			msg, err := backend.Receive()
+			if err != nil {
+				errChan <- err
+				return
+			}
+			if err := p.forwardFrontend(frontend, msg); err != nil {
+				errChan <- err
+				return
+			}
+			if _, ok := msg.(*pgproto3.Terminate); ok {
+				errChan <- nil
+				return
+			}
+		}
+	}()
+	go func() {
+		for {
+			msg, err := server.Receive()
+			if err != nil {
+				errChan <- err
+				return
+			}
+			if err := p.forwardBackend(backend, msg); err != nil {
+				errChan <- err
+				return
+			}
+		}
+	}()
+
+	// When one side is done close both connections to stop the other.
+	err = <-errChan
+	clientConn.Close()
+	serverConn.Close()
+	<-errChan
+
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
+
+// receiveStartupMessage receives the StartupMessage of the client. Encryption
+// requests are refused.
+func (p *Proxy) receiveStartupMessage(clientConn net.Conn, backend *pgproto3.Backend) (*pgproto3.StartupMessage, error) {
+	for {
+		msg, err := backend.ReceiveStartupMessage()
+		if err != nil {
+			return nil, err
+		}
+
+		switch msg := msg.(type) {
+		case *pgproto3.StartupMessage:
+			return msg, nil
+		case *pgproto3.SSLRequest, *pgproto3.GSSENCRequest:
+			if _, err := clientConn.Write([]byte{'N'}); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, errors.Errorf("proxy can't handle %T", msg)
+		}
+	}
+}
+
+// forwardFrontend records msg and sends it to the server. Messages are
+// recorded before they are sent so the recording is in causal order.
+func (p *Proxy) forwardFrontend(frontend *pgproto3.Frontend, msg pgproto3.FrontendMessage) error {
+	if err := p.recorder.RecordFrontend(msg); err != nil {
+		return err
+	}
+	return frontend.Send(msg)
+}
+
+// forwardBackend records msg and sends it to the client.
+func (p *Proxy) forwardBackend(backend *pgproto3.Backend, msg pgproto3.BackendMessage) error {
+	if err := p.recorder.RecordBackend(msg); err != nil {
+		return err
+	}
+	return backend.Send(msg)
+}
+
+func (p *Proxy) Close() error {
+	return p.ln.Close()
+}
+
+// backendReader reads one backend message at a time from a blocking reader.
+type backendReader struct {
+	cr *chunkreader.ChunkReader
+}
+
+func newBackendReader(r io.Reader) *backendReader {
+	return &backendReader{cr: chunkreader.NewChunkReader(r)}
+}
+
+func (r *backendReader) Receive() (pgproto3.BackendMessage, error) {
+	header, err := r.cr.Next(5)
+	if err != nil {
+		return nil, err
+	}
+
+	msgType := header[0]
+	msgLen := int(binary.BigEndian.Uint32(header[1:]))
+	if msgLen < 4 || msgLen > pgproto3.MaxMessageLen {
+		return nil, errors.Errorf("invalid message length: %d", msgLen)
+	}
+	bodyLen := msgLen - 4
+
+	newMsg, ok := backendMessageTypes[msgType]
+	if !ok {
+		return nil, errors.Errorf("unknown message type: %c", msgType)
+	}
+
+	msgBody, err := r.cr.Next(bodyLen)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := newMsg()
+	err = msg.Decode(msgBody)
+	return msg, err
+}
+
+var backendMessageTypes = map[byte]func() pgproto3.BackendMessage{
+	'1': func() pgproto3.BackendMessage { return &pgproto3.ParseComplete{} },
+	'2': func() pgproto3.BackendMessage { return &pgproto3.BindComplete{} },
+	'3': func() pgproto3.BackendMessage { return &pgproto3.CloseComplete{} },
+	'A': func() pgproto3.BackendMessage { return &pgproto3.NotificationResponse{} },
+	'C': func() pgproto3.BackendMessage { return &pgproto3.CommandComplete{} },
+	'c': func() pgproto3.BackendMessage { return &pgproto3.CopyDone{} },
+	'd': func() pgproto3.BackendMessage { return &pgproto3.CopyData{} },
+	'D': func() pgproto3.BackendMessage { return &pgproto3.DataRow{} },
+	'E': func() pgproto3.BackendMessage { return &pgproto3.ErrorResponse{} },
+	'G': func() pgproto3.BackendMessage { return &pgproto3.CopyInResponse{} },
+	'H': func() pgproto3.BackendMessage { return &pgproto3.CopyOutResponse{} },
+	'I': func() pgproto3.BackendMessage { return &pgproto3.EmptyQueryResponse{} },
+	'K': func() pgproto3.BackendMessage { return &pgproto3.BackendKeyData{} },
+	'n': func() pgproto3.BackendMessage { return &pgproto3.NoData{} },
+	'N': func() pgproto3.BackendMessage { return &pgproto3.NoticeResponse{} },
+	'R': func() pgproto3.BackendMessage { return &pgproto3.Authentication{} },
+	'S': func() pgproto3.BackendMessage { return &pgproto3.ParameterStatus{} },
+	't': func() pgproto3.BackendMessage { return &pgproto3.ParameterDescription{} },
+	'T': func() pgproto3.BackendMessage { return &pgproto3.RowDescription{} },
+	'V': func() pgproto3.BackendMessage { return &pgproto3.FunctionCallResponse{} },
+	'W': func() pgproto3.BackendMessage { return &pgproto3.CopyBothResponse{} },
+	'Z': func() pgproto3.BackendMessage { return &pgproto3.ReadyForQuery{} },
+}
@@ -0,0 +1,237 @@
+package pgmock
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/ronaldslc/pgx/pgproto3"
+)
+
+// RecordedMessage is a message of a Recording. Exactly one of Frontend and
+// Backend is set.
+type RecordedMessage struct {
+	Frontend pgproto3.FrontendMessage
+	Backend  pgproto3.BackendMessage
+}
+
+// Recording is the sequence of messages exchanged on a connection between a
+// frontend and a backend, as written by a Recorder.
+type Recording struct {
+	Messages []RecordedMessage
+}
+
+// recordLine is a line of a recording file. The file has one JSON object per
+// message. Data is the message in the wire format, which is what
+// ReadRecording decodes. Message is the JSON encoding of the message for
+// people reading the file.
+type recordLine struct {
+	From    string          `json:"from"` // F for frontend or B for backend
+	Type    string          `json:"type"`
+	Data    []byte          `json:"data"`
+	Message json.RawMessage `json:"message,omitempty"`
+}
+
+// Recorder writes the messages of a connection to a recording file that can be
+// read with ReadRecording. It is safe for concurrent use.
+type Recorder struct {
+	mux sync.Mutex
+	enc *json.Encoder
+	err error
+}
+
+// NewRecorder returns a Recorder that writes to w.
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{enc: json.NewEncoder(w)}
+}
+
+// RecordFrontend records msg as sent by the frontend.
+func (r *Recorder) RecordFrontend(msg pgproto3.FrontendMessage) error {
+	return r.record("F", msg)
+}
+
+// RecordBackend records msg as sent by the backend.
+func (r *Recorder) RecordBackend(msg pgproto3.BackendMessage) error {
+	return r.record("B", msg)
+}
+
+func (r *Recorder) record(from string, msg pgproto3.Message) error {
+	line := recordLine{
+		From: from,
+		Type: messageTypeName(msg),
+		Data: msg.Encode(nil),
+	}
+	if buf, err := json.Marshal(msg); err == nil {
+		line.Message = buf
+	}
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if r.err != nil {
+		return r.err
+	}
+	r.err = r.enc.Encode(line)
+	return r.err
+}
+
+// ReadRecording reads a recording file written by a Recorder.
+func ReadRecording(r io.Reader) (*Recording, error) {
+	dec := json.NewDecoder(r)
+	rec := &Recording{}
+
+	for {
+		var line recordLine
+		if err := dec.Decode(&line); err == io.EOF {
+			return rec, nil
+		} else if err != nil {
+			return nil, errors.Wrapf(err, "message %d", len(rec.Messages))
+		}
+
+		msg, err := decodeRecordLine(&line)
+		if err != nil {
+			return nil, errors.Wrapf(err, "message %d", len(rec.Messages))
+		}
+		rec.Messages = append(rec.Messages, msg)
+	}
+}
+
+func decodeRecordLine(line *recordLine) (RecordedMessage, error) {
+	typ, ok := recordedMessageTypes[line.Type]
+	if !ok {
+		return RecordedMessage{}, errors.Errorf("unknown message type %q", line.Type)
+	}
+	msg := reflect.New(typ).Interface().(pgproto3.Message)
+
+	// Startup messages have no message type byte.
+	headerLen := 5
+	if startupMessageTypes[line.Type] {
+		headerLen = 4
+	}
+	if len(line.Data) < headerLen {
+		return RecordedMessage{}, errors.Errorf("%s data too short", line.Type)
+	}
+	if err := msg.Decode(line.Data[headerLen:]); err != nil {
+		return RecordedMessage{}, err
+	}
+
+	switch line.From {
+	case "F":
+		if msg, ok := msg.(pgproto3.FrontendMessage); ok {
+			return RecordedMessage{Frontend: msg}, nil
+		}
+	case "B":
+		if msg, ok := msg.(pgproto3.BackendMessage); ok {
+			return RecordedMessage{Backend: msg}, nil
+		}
+	default:
+		return RecordedMessage{}, errors.Errorf("unknown sender %q", line.From)
+	}
+
+	return RecordedMessage{}, errors.Errorf("%s can't be sent by %s", line.Type, line.From)
+}
+
+// Script returns a Script that plays the backend of the recording. It expects
+// the frontend messages and sends the backend messages in the recorded order.
+// The startup message only needs to be a StartupMessage, its parameters are
+// not compared.
+//
+// The frontend must send the same messages as when the recording was made.
+// Sessions that authenticated with SCRAM can't be replayed because the client
+// nonce is random. Record against a server that uses trust, password or md5
+// authentication.
+func (rec *Recording) Script() *Script {
+	script := &Script{Steps: make([]Step, 0, len(rec.Messages))}
+
+	for _, msg := range rec.Messages {
+		switch {
+		case msg.Frontend != nil:
+			if _, ok := msg.Frontend.(*pgproto3.StartupMessage); ok {
+				script.Steps = append(script.Steps, ExpectAnyMessage(msg.Frontend))
+			} else {
+				script.Steps = append(script.Steps, ExpectMessage(msg.Frontend))
+			}
+		case msg.Backend != nil:
+			script.Steps = append(script.Steps, SendMessage(msg.Backend))
+		}
+	}
+
+	return script
+}
+
+// LoadScript reads a recording file and returns its Script.
+func LoadScript(r io.Reader) (*Script, error) {
+	rec, err := ReadRecording(r)
+	if err != nil {
+		return nil, err
+	}
+	return rec.Script(), nil
+}
+
+func messageTypeName(msg pgproto3.Message) string {
+	return reflect.TypeOf(msg).Elem().Name()
+}
+
+var recordedMessageTypes = make(map[string]reflect.Type)
+
+var startupMessageTypes = map[string]bool{
+	"StartupMessage": true,
+	"SSLRequest":     true,
+	"GSSENCRequest":  true,
+	"CancelRequest":  true,
+}
+
+func init() {
+	for _, msg := range []pgproto3.Message{
+		// Frontend
+		&pgproto3.Bind{},
+		&pgproto3.CancelRequest{},
+		&pgproto3.Close{},
+		&pgproto3.CopyFail{},
+		&pgproto3.Describe{},
+		&pgproto3.Execute{},
+		&pgproto3.Flush{},
+		&pgproto3.FunctionCall{},
+		&pgproto3.GSSENCRequest{},
+		&pgproto3.Parse{},
+		&pgproto3.PasswordMessage{},
+		&pgproto3.Query{},
+		&pgproto3.SASLInitialResponse{},
+		&pgproto3.SASLResponse{},
+		&pgproto3.SSLRequest{},
+		&pgproto3.StartupMessage{},
+		&pgproto3.Sync{},
+		&pgproto3.Terminate{},
+
+		// Frontend and backend
+		&pgproto3.CopyData{},
+		&pgproto3.CopyDone{},
+
+		// Backend
+		&pgproto3.Authentication{},
+		&pgproto3.BackendKeyData{},
+		&pgproto3.BindComplete{},
+		&pgproto3.CloseComplete{},
+		&pgproto3.CommandComplete{},
+		&pgproto3.CopyBothResponse{},
+		&pgproto3.CopyInResponse{},
+		&pgproto3.CopyOutResponse{},
+		&pgproto3.DataRow{},
+		&pgproto3.EmptyQueryResponse{},
+		&pgproto3.ErrorResponse{},
+		&pgproto3.FunctionCallResponse{},
+		&pgproto3.NoData{},
+		&pgproto3.NoticeResponse{},
+		&pgproto3.NotificationResponse{},
+		&pgproto3.ParameterDescription{},
+		&pgproto3.ParameterStatus{},
+		&pgproto3.ParseComplete{},
+		&pgproto3.ReadyForQuery{},
+		&pgproto3.RowDescription{},
+	} {
+		recordedMessageTypes[messageTypeName(msg)] = reflect.TypeOf(msg).Elem()
+	}
+}
@@ -0,0 +1,224 @@
+package pgmock
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/ronaldslc/pgx/pgproto3"
+)
+
+func queryOneSteps() []Step {
+	steps := AcceptUnauthenticatedConnRequestSteps()
+	return append(steps,
+		ExpectMessage(&pgproto3.Query{String: "select 1"}),
+		SendMessage(&pgproto3.RowDescription{Fields: []pgproto3.FieldDescription{
+			{Name: "?column?", DataTypeOID: 23, DataTypeSize: 4, TypeModifier: 4294967295},
+		}}),
+		SendMessage(&pgproto3.DataRow{Values: [][]byte{[]byte("1")}}),
+		SendMessage(&pgproto3.CommandComplete{CommandTag: "SELECT 1"}),
+		SendMessage(&pgproto3.ReadyForQuery{TxStatus: 'I'}),
+		ExpectMessage(&pgproto3.Terminate{}),
+	)
+}
+
+// runQueryOneClient connects to addr as a client that first asks for TLS,
+// runs "select 1" with the simple protocol and returns the backend messages
+// it received.
+func runQueryOneClient(t *testing.T, addr net.Addr, expectTLSRefused bool) []pgproto3.BackendMessage {
+	conn, err := net.Dial(addr.Network(), addr.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if expectTLSRefused {
+		if _, err := conn.Write((&pgproto3.SSLRequest{}).Encode(nil)); err != nil {
+			t.Fatal(err)
+		}
+		response := make([]byte, 1)
+		if _, err := io.ReadFull(conn, response); err != nil || response[0] != 'N' {
+			t.Fatalf("Expected TLS to be refused, got %q %v", response, err)
+		}
+	}
+
+	startupMsg := &pgproto3.StartupMessage{ProtocolVersion: pgproto3.ProtocolVersionNumber, Parameters: map[string]string{"user": "pgx"}}
+	if _, err := conn.Write(startupMsg.Encode(nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	r := newBackendReader(conn)
+	var received []pgproto3.BackendMessage
+	receiveUntilReady := func() {
+		for {
+			msg, err := r.Receive()
+			if err != nil {
+				t.Fatal(err)
+			}
+			received = append(received, msg)
+			if _, ok := msg.(*pgproto3.ReadyForQuery); ok {
+				return
+			}
+		}
+	}
+
+	receiveUntilReady()
+	if _, err := conn.Write((&pgproto3.Query{String: "select 1"}).Encode(nil)); err != nil {
+		t.Fatal(err)
+	}
+	receiveUntilReady()
+	if _, err := conn.Write((&pgproto3.Terminate{}).Encode(nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	return received
+}
+
+func TestProxyRecordAndReplay(t *testing.T) {
+	server, err := NewServer(&Script{Steps: queryOneSteps()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	serverErrChan := make(chan error, 1)
+	go func() {
+		serverErrChan <- server.ServeOne()
+	}()
+
+	var recording bytes.Buffer
+	proxy, err := NewProxy(server.Addr().Network(), server.Addr().String(), &recording)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.Close()
+
+	proxyErrChan := make(chan error, 1)
+	go func() {
+		proxyErrChan <- proxy.ServeOne()
+	}()
+
+	recorded := runQueryOneClient(t, proxy.Addr(), true)
+
+	if err := <-proxyErrChan; err != nil {
+		t.Fatalf("proxy err: %v", err)
+	}
+	if err := <-serverErrChan; err != nil {
+		t.Fatalf("server err: %v", err)
+	}
+
+	rec, err := ReadRecording(bytes.NewReader(recording.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var types []string
+	for _, msg := range rec.Messages {
+		if msg.Frontend != nil {
+			types = append(types, "F "+messageTypeName(msg.Frontend))
+		} else {
+			types = append(types, "B "+messageTypeName(msg.Backend))
+		}
+	}
+	expectedTypes := []string{
+		"F StartupMessage",
+		"B Authentication",
+		"B BackendKeyData",
+		"B ReadyForQuery",
+		"F Query",
+		"B RowDescription",
+		"B DataRow",
+		"B CommandComplete",
+		"B ReadyForQuery",
+		"F Terminate",
+	}
+	if !reflect.DeepEqual(types, expectedTypes) {
+		t.Fatalf("Expected recording of %v, got %v", expectedTypes, types)
+	}
+
+	// Replay the recording without the original server.
+	script, err := LoadScript(bytes.NewReader(recording.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayServer, err := NewServer(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replayServer.Close()
+
+	go func() {
+		serverErrChan <- replayServer.ServeOne()
+	}()
+
+	replayed := runQueryOneClient(t, replayServer.Addr(), false)
+
+	if err := <-serverErrChan; err != nil {
+		t.Fatalf("replay server err: %v", err)
+	}
+
+	if !reflect.DeepEqual(replayed, recorded) {
+		t.Errorf("Expected replay to send %#v, got %#v", recorded, replayed)
+	}
+}
+
+func TestReadRecordingInvalid(t *testing.T) {
+	tests := []string{
+		`{"from":"F","type":"Bogus","data":""}`,
+		`{"from":"B","type":"Query","data":"UQAAAA1zZWxlY3QgMQA="}`,
+		`{"from":"X","type":"Query","data":"UQAAAA1zZWxlY3QgMQA="}`,
+		`{"from":"F","type":"Query","data":"UQ=="}`,
+		`not json`,
+	}
+
+	for i, tt := range tests {
+		if _, err := ReadRecording(bytes.NewReader([]byte(tt))); err == nil {
+			t.Errorf("%d. Expected error for %s", i, tt)
+		}
+	}
+}
+
+func TestBackendReaderInvalidLength(t *testing.T) {
+	tests := [][]byte{
+		{'Z', 0, 0, 0, 1},
+		{'Z', 255, 255, 255, 255},
+	}
+
+	for i, tt := range tests {
+		if msg, err := newBackendReader(bytes.NewReader(tt)).Receive(); err == nil {
+			t.Errorf("%d. Expected error, got %#v", i, msg)
+		}
+	}
+}
+
+func TestRecordingErrorResponse(t *testing.T) {
+	var buf bytes.Buffer
+	recorder := NewRecorder(&buf)
+
+	errResponse := &pgproto3.ErrorResponse{
+		Severity: "ERROR",
+		Code:     "42P01",
+		Message:  `relation "foo" does not exist`,
+		Position: 15,
+	}
+	notice := &pgproto3.NoticeResponse{Severity: "NOTICE", Code: "00000", Message: "hello"}
+
+	if err := recorder.RecordBackend(errResponse); err != nil {
+		t.Fatal(err)
+	}
+	if err := recorder.RecordBackend(notice); err != nil {
+		t.Fatal(err)
+	}
+
+	rec, err := ReadRecording(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []RecordedMessage{{Backend: errResponse}, {Backend: notice}}
+	if !reflect.DeepEqual(rec.Messages, expected) {
+		t.Errorf("Expected %#v, got %#v", expected, rec.Messages)
+	}
+}
@@ -0,0 +1,276 @@
+package pgoutput
+
+import (
+	"encoding/binary"
+	"reflect"
+
+	"github.com/pkg/errors"
+
+	"github.com/ronaldslc/pgx/pgtype"
+)
+
+// Decoder decodes pgoutput messages. It keeps a cache of the Relation and
+// Type messages it has seen so the tuples of subsequent Insert, Update and
+// Delete messages can be decoded into pgtype.Values.
+//
+// A Decoder must see every message of a replication session in order. It is
+// not safe for concurrent usage.
+type Decoder struct {
+	connInfo  *pgtype.ConnInfo
+	relations map[uint32]*Relation
+	types     map[pgtype.OID]*Type
+
+	// inStream is true between a StreamStart and a StreamStop. The changes
+	// sent in between are prefixed with the xid of their transaction.
+	inStream bool
+}
+
+// NewDecoder returns a Decoder that decodes column values with the data types
+// registered in connInfo. As replication connections do not load data types
+// connInfo usually comes from a regular connection to the same database. If
+// connInfo is nil or has no data type for a column, the column is decoded as
+// pgtype.GenericText or pgtype.GenericBinary.
+func NewDecoder(connInfo *pgtype.ConnInfo) *Decoder {
+	if connInfo == nil {
+		connInfo = pgtype.NewConnInfo()
+	}
+
+	return &Decoder{
+		connInfo:  connInfo,
+		relations: make(map[uint32]*Relation),
+		types:     make(map[pgtype.OID]*Type),
+	}
+}
+
+// Relation returns the most recently received Relation message for
+// relationID.
+func (d *Decoder) Relation(relationID uint32) (*Relation, bool) {
+	r, ok := d.relations[relationID]
+	return r, ok
+}
+
+// Decode decodes walData, the WalData of a pgx.WalMessage, into a Message.
+func (d *Decoder) Decode(walData []byte) (Message, error) {
+	if len(walData) == 0 {
+		return nil, errors.New("empty pgoutput message")
+	}
+
+	var msg Message
+	switch walData[0] {
+	case MessageTypeBegin:
+		msg = &Begin{}
+	case MessageTypeCommit:
+		msg = &Commit{}
+	case MessageTypeOrigin:
+		msg = &Origin{}
+	case MessageTypeRelation:
+		msg = &Relation{}
+	case MessageTypeType:
+		msg = &Type{}
+	case MessageTypeInsert:
+		msg = &Insert{}
+	case MessageTypeUpdate:
+		msg = &Update{}
+	case MessageTypeDelete:
+		msg = &Delete{}
+	case MessageTypeTruncate:
+		msg = &Truncate{}
+	case MessageTypeMessage:
+		msg = &LogicalMessage{}
+	case MessageTypeStreamStart:
+		msg = &StreamStart{}
+	case MessageTypeStreamStop:
+		msg = &StreamStop{}
+	case MessageTypeStreamCommit:
+		msg = &StreamCommit{}
+	case MessageTypeStreamAbort:
+		msg = &StreamAbort{}
+	case MessageTypeBeginPrepare:
+		msg = &BeginPrepare{}
+	case MessageTypePrepare:
+		msg = &Prepare{}
+	case MessageTypeCommitPrepared:
+		msg = &CommitPrepared{}
+	case MessageTypeRollbackPrepared:
+		msg = &RollbackPrepared{}
+	case MessageTypeStreamPrepare:
+		msg = &StreamPrepare{}
+	default:
+		return nil, errors.Errorf("unknown pgoutput message type: %c", walData[0])
+	}
+
+	body := walData[1:]
+	var xid uint32
+	if d.inStream && isStreamedChange(walData[0]) {
+		if len(body) < 4 {
+			return nil, errors.Errorf("streamed pgoutput message %c is missing its xid", walData[0])
+		}
+		xid = binary.BigEndian.Uint32(body)
+		body = body[4:]
+	}
+
+	if err := msg.Decode(body); err != nil {
+		return nil, err
+	}
+	setXid(msg, xid)
+
+	if err := d.process(msg); err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+}
+
+// process updates the caches from msg and resolves the relations and column
+// values of msg.
+func (d *Decoder) process(msg Message) error {
+	switch msg := msg.(type) {
+	case *StreamStart:
+		d.inStream = true
+	case *StreamStop:
+		d.inStream = false
+	case *Relation:
+		d.relations[msg.RelationID] = msg
+	case *Type:
+		d.types[msg.DataType] = msg
+	case *Insert:
+		rel, err := d.relation(msg.RelationID)
+		if err != nil {
+			return err
+		}
+		msg.Relation = rel
+		return d.decodeTuple(rel, msg.NewTuple)
+	case *Update:
+		rel, err := d.relation(msg.RelationID)
+		if err != nil {
+			return err
+		}
+		msg.Relation = rel
+		if msg.OldTuple != nil {
+			if err := d.decodeTuple(rel, msg.OldTuple); err != nil {
+				return err
+			}
+		}
+		return d.decodeTuple(rel, msg.NewTuple)
+	case *Delete:
+		rel, err := d.relation(msg.RelationID)
+		if err != nil {
+			return err
+		}
+		msg.Relation = rel
+		return d.decodeTuple(rel, msg.OldTuple)
+	case *Truncate:
+		msg.Relations = make([]*Relation, len(msg.RelationIDs))
+		for i, id := range msg.RelationIDs {
+			rel, err := d.relation(id)
+			if err != nil {
+				return err
+			}
+			msg.Relations[i] = rel
+		}
+	}
+
+	return nil
+}
+
+// isStreamedChange returns true if messages of messageType are prefixed with
+// an xid inside a stream block.
+func isStreamedChange(messageType byte) bool {
+	switch messageType {
+	case MessageTypeRelation, MessageTypeType, MessageTypeInsert, MessageTypeUpdate,
+		MessageTypeDelete, MessageTypeTruncate, MessageTypeMessage:
+		return true
+	}
+	return false
+}
+
+func setXid(msg Message, xid uint32) {
+	switch msg := msg.(type) {
+	case *Relation:
+		msg.Xid = xid
+	case *Type:
+		msg.Xid = xid
+	case *Insert:
+		msg.Xid = xid
+	case *Update:
+		msg.Xid = xid
+	case *Delete:
+		msg.Xid = xid
+	case *Truncate:
+		msg.Xid = xid
+	case *LogicalMessage:
+		msg.Xid = xid
+	}
+}
+
+func (d *Decoder) relation(relationID uint32) (*Relation, error) {
+	rel, ok := d.relations[relationID]
+	if !ok {
+		return nil, errors.Errorf("unknown relation %d", relationID)
+	}
+	return rel, nil
+}
+
+func (d *Decoder) decodeTuple(rel *Relation, tuple *Tuple) error {
+	if len(tuple.Columns) != len(rel.Columns) {
+		return errors.Errorf("relation %s has %d columns, but tuple has %d", rel.RelationName, len(rel.Columns), len(tuple.Columns))
+	}
+
+	for i := range tuple.Columns {
+		col := &tuple.Columns[i]
+		if col.Kind == TupleColumnUnchangedToast {
+			continue
+		}
+
+		value, err := d.decodeValue(rel.Columns[i].DataType, col.Kind, col.Data)
+		if err != nil {
+			return errors.Wrapf(err, "column %s", rel.Columns[i].Name)
+		}
+		col.Value = value
+	}
+
+	return nil
+}
+
+// newValue returns a new pgtype.Value for oid. Data types that are not built
+// in are also looked up by the name sent in their Type message.
+func (d *Decoder) newValue(oid pgtype.OID) (pgtype.Value, bool) {
+	dt, ok := d.connInfo.DataTypeForOID(oid)
+	if !ok {
+		if t, found := d.types[oid]; found {
+			dt, ok = d.connInfo.DataTypeForName(t.Name)
+		}
+	}
+	if !ok {
+		return nil, false
+	}
+
+	return reflect.New(reflect.ValueOf(dt.Value).Elem().Type()).Interface().(pgtype.Value), true
+}
+
+func (d *Decoder) decodeValue(oid pgtype.OID, kind byte, src []byte) (pgtype.Value, error) {
+	value, ok := d.newValue(oid)
+
+	switch kind {
+	case TupleColumnNull, TupleColumnText:
+		decoder, isDecoder := value.(pgtype.TextDecoder)
+		if !ok || !isDecoder {
+			decoder = &pgtype.GenericText{}
+		}
+		if err := decoder.DecodeText(d.connInfo, src); err != nil {
+			return nil, err
+		}
+		return decoder.(pgtype.Value), nil
+	case TupleColumnBinary:
+		decoder, isDecoder := value.(pgtype.BinaryDecoder)
+		if !ok || !isDecoder {
+			decoder = &pgtype.GenericBinary{}
+		}
+		if err := decoder.DecodeBinary(d.connInfo, src); err != nil {
+			return nil, err
+		}
+		return decoder.(pgtype.Value), nil
+	default:
+		return nil, errors.Errorf("unknown tuple column kind: %c", kind)
+	}
+}
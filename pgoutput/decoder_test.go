@@ -0,0 +1,403 @@
+package pgoutput_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ronaldslc/pgx/pgio"
+	"github.com/ronaldslc/pgx/pgoutput"
+	"github.com/ronaldslc/pgx/pgtype"
+)
+
+func newTestConnInfo() *pgtype.ConnInfo {
+	ci := pgtype.NewConnInfo()
+	ci.InitializeDataTypes(map[string]pgtype.OID{
+		"int4":    pgtype.Int4OID,
+		"text":    pgtype.TextOID,
+		"hstore":  16400,
+		"numeric": 1700,
+	})
+	return ci
+}
+
+func appendCString(buf []byte, s string) []byte {
+	buf = append(buf, s...)
+	return append(buf, 0)
+}
+
+func relationMsg() []byte {
+	buf := []byte{'R'}
+	buf = pgio.AppendUint32(buf, 16385)
+	buf = appendCString(buf, "public")
+	buf = appendCString(buf, "foo")
+	buf = append(buf, 'd')
+	buf = pgio.AppendInt16(buf, 3)
+
+	buf = append(buf, 1)
+	buf = appendCString(buf, "id")
+	buf = pgio.AppendUint32(buf, pgtype.Int4OID)
+	buf = pgio.AppendInt32(buf, -1)
+
+	buf = append(buf, 0)
+	buf = appendCString(buf, "name")
+	buf = pgio.AppendUint32(buf, pgtype.TextOID)
+	buf = pgio.AppendInt32(buf, -1)
+
+	buf = append(buf, 0)
+	buf = appendCString(buf, "attrs")
+	buf = pgio.AppendUint32(buf, 90000)
+	buf = pgio.AppendInt32(buf, -1)
+
+	return buf
+}
+
+func appendTupleText(buf []byte, values ...interface{}) []byte {
+	buf = pgio.AppendInt16(buf, int16(len(values)))
+	for _, v := range values {
+		switch v := v.(type) {
+		case nil:
+			buf = append(buf, 'n')
+		case byte:
+			buf = append(buf, v)
+		case string:
+			buf = append(buf, 't')
+			buf = pgio.AppendInt32(buf, int32(len(v)))
+			buf = append(buf, v...)
+		}
+	}
+	return buf
+}
+
+func mustDecode(t *testing.T, d *pgoutput.Decoder, buf []byte) pgoutput.Message {
+	msg, err := d.Decode(buf)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	return msg
+}
+
+func TestDecodeBeginCommit(t *testing.T) {
+	d := pgoutput.NewDecoder(nil)
+	commitTime := time.Date(2017, 8, 12, 1, 2, 3, 4000, time.UTC)
+	pgCommitTime := commitTime.Sub(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)).Nanoseconds() / 1000
+
+	buf := []byte{'B'}
+	buf = pgio.AppendUint64(buf, 0x16B3748)
+	buf = pgio.AppendInt64(buf, pgCommitTime)
+	buf = pgio.AppendUint32(buf, 571)
+
+	begin, ok := mustDecode(t, d, buf).(*pgoutput.Begin)
+	if !ok {
+		t.Fatalf("Expected *pgoutput.Begin")
+	}
+	if begin.FinalLSN != 0x16B3748 || begin.Xid != 571 || !begin.CommitTime.Equal(commitTime) {
+		t.Errorf("Unexpected Begin: %#v", begin)
+	}
+
+	buf = []byte{'C', 0}
+	buf = pgio.AppendUint64(buf, 0x16B3748)
+	buf = pgio.AppendUint64(buf, 0x16B3778)
+	buf = pgio.AppendInt64(buf, pgCommitTime)
+
+	commit, ok := mustDecode(t, d, buf).(*pgoutput.Commit)
+	if !ok {
+		t.Fatalf("Expected *pgoutput.Commit")
+	}
+	if commit.CommitLSN != 0x16B3748 || commit.TransactionEndLSN != 0x16B3778 || !commit.CommitTime.Equal(commitTime) {
+		t.Errorf("Unexpected Commit: %#v", commit)
+	}
+}
+
+func TestDecodeRelationAndInsert(t *testing.T) {
+	d := pgoutput.NewDecoder(newTestConnInfo())
+
+	rel, ok := mustDecode(t, d, relationMsg()).(*pgoutput.Relation)
+	if !ok {
+		t.Fatalf("Expected *pgoutput.Relation")
+	}
+	if rel.RelationID != 16385 || rel.Namespace != "public" || rel.RelationName != "foo" || len(rel.Columns) != 3 {
+		t.Fatalf("Unexpected Relation: %#v", rel)
+	}
+	if !rel.Columns[0].IsKey() || rel.Columns[1].IsKey() {
+		t.Errorf("Unexpected key flags: %#v", rel.Columns)
+	}
+
+	// The Type message lets the unknown OID be resolved by name
+	buf := []byte{'Y'}
+	buf = pgio.AppendUint32(buf, 90000)
+	buf = appendCString(buf, "public")
+	buf = appendCString(buf, "hstore")
+	mustDecode(t, d, buf)
+
+	buf = []byte{'I'}
+	buf = pgio.AppendUint32(buf, 16385)
+	buf = append(buf, 'N')
+	buf = appendTupleText(buf, "42", nil, `"a"=>"1"`)
+
+	insert, ok := mustDecode(t, d, buf).(*pgoutput.Insert)
+	if !ok {
+		t.Fatalf("Expected *pgoutput.Insert")
+	}
+	if insert.Relation != rel {
+		t.Errorf("Expected Insert.Relation to be the cached relation")
+	}
+
+	values := insert.NewTuple.Values()
+	if id, ok := values[0].(*pgtype.Int4); !ok || id.Int != 42 || id.Status != pgtype.Present {
+		t.Errorf("Unexpected id value: %#v", values[0])
+	}
+	if name, ok := values[1].(*pgtype.Text); !ok || name.Status != pgtype.Null {
+		t.Errorf("Unexpected name value: %#v", values[1])
+	}
+	var attrs map[string]string
+	if _, ok := values[2].(*pgtype.Hstore); !ok {
+		t.Errorf("Expected attrs to be decoded as *pgtype.Hstore, but got %#v", values[2])
+	} else if err := values[2].AssignTo(&attrs); err != nil || !reflect.DeepEqual(attrs, map[string]string{"a": "1"}) {
+		t.Errorf("Unexpected attrs value: %v %v", attrs, err)
+	}
+}
+
+func TestDecodeUpdateDelete(t *testing.T) {
+	d := pgoutput.NewDecoder(newTestConnInfo())
+	mustDecode(t, d, relationMsg())
+
+	buf := []byte{'U'}
+	buf = pgio.AppendUint32(buf, 16385)
+	buf = append(buf, 'K')
+	buf = appendTupleText(buf, "1", nil, nil)
+	buf = append(buf, 'N')
+	buf = appendTupleText(buf, "2", "bar", byte('u'))
+
+	update, ok := mustDecode(t, d, buf).(*pgoutput.Update)
+	if !ok {
+		t.Fatalf("Expected *pgoutput.Update")
+	}
+	if update.OldTupleKind != pgoutput.OldTupleKey || update.OldTuple == nil {
+		t.Fatalf("Expected key old tuple: %#v", update)
+	}
+	if v := update.OldTuple.Columns[0].Value.Get(); v != int32(1) {
+		t.Errorf("Unexpected old id: %v", v)
+	}
+	if v := update.NewTuple.Columns[1].Value.Get(); v != "bar" {
+		t.Errorf("Unexpected new name: %v", v)
+	}
+	if update.NewTuple.Columns[2].Kind != pgoutput.TupleColumnUnchangedToast || update.NewTuple.Columns[2].Value != nil {
+		t.Errorf("Expected unchanged toast column without value: %#v", update.NewTuple.Columns[2])
+	}
+
+	buf = []byte{'U'}
+	buf = pgio.AppendUint32(buf, 16385)
+	buf = append(buf, 'N')
+	buf = appendTupleText(buf, "2", "baz", nil)
+
+	update = mustDecode(t, d, buf).(*pgoutput.Update)
+	if update.OldTuple != nil || update.OldTupleKind != 0 {
+		t.Errorf("Expected no old tuple: %#v", update)
+	}
+
+	buf = []byte{'D'}
+	buf = pgio.AppendUint32(buf, 16385)
+	buf = append(buf, 'O')
+	buf = appendTupleText(buf, "2", "baz", nil)
+
+	del, ok := mustDecode(t, d, buf).(*pgoutput.Delete)
+	if !ok {
+		t.Fatalf("Expected *pgoutput.Delete")
+	}
+	if del.OldTupleKind != pgoutput.OldTupleOld || del.OldTuple.Columns[1].Value.Get() != "baz" {
+		t.Errorf("Unexpected Delete: %#v", del)
+	}
+}
+
+func TestDecodeTruncateOriginMessage(t *testing.T) {
+	d := pgoutput.NewDecoder(nil)
+	mustDecode(t, d, relationMsg())
+
+	buf := []byte{'T'}
+	buf = pgio.AppendInt32(buf, 1)
+	buf = append(buf, pgoutput.TruncateCascade)
+	buf = pgio.AppendUint32(buf, 16385)
+
+	truncate, ok := mustDecode(t, d, buf).(*pgoutput.Truncate)
+	if !ok {
+		t.Fatalf("Expected *pgoutput.Truncate")
+	}
+	if !truncate.Cascade() || truncate.RestartIdentity() || len(truncate.Relations) != 1 || truncate.Relations[0].RelationName != "foo" {
+		t.Errorf("Unexpected Truncate: %#v", truncate)
+	}
+
+	buf = []byte{'T'}
+	buf = pgio.AppendInt32(buf, 0x7fffffff)
+	buf = append(buf, 0)
+	buf = pgio.AppendUint32(buf, 16385)
+	if _, err := d.Decode(buf); err == nil {
+		t.Error("Expected error for Truncate with too many relations")
+	}
+
+	buf = []byte{'O'}
+	buf = pgio.AppendUint64(buf, 0x1000)
+	buf = appendCString(buf, "node_a")
+
+	origin, ok := mustDecode(t, d, buf).(*pgoutput.Origin)
+	if !ok || origin.CommitLSN != 0x1000 || origin.Name != "node_a" {
+		t.Errorf("Unexpected Origin: %#v", origin)
+	}
+
+	buf = []byte{'M', 1}
+	buf = pgio.AppendUint64(buf, 0x2000)
+	buf = appendCString(buf, "app")
+	buf = pgio.AppendInt32(buf, 5)
+	buf = append(buf, "hello"...)
+
+	lm, ok := mustDecode(t, d, buf).(*pgoutput.LogicalMessage)
+	if !ok || !lm.Transactional() || lm.LSN != 0x2000 || lm.Prefix != "app" || string(lm.Content) != "hello" {
+		t.Errorf("Unexpected LogicalMessage: %#v", lm)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	d := pgoutput.NewDecoder(nil)
+
+	insert := []byte{'I'}
+	insert = pgio.AppendUint32(insert, 16385)
+	insert = append(insert, 'N')
+	insert = appendTupleText(insert, "1")
+
+	tests := []struct {
+		name string
+		buf  []byte
+	}{
+		{"empty", nil},
+		{"unknown type", []byte{'?'}},
+		{"short begin", []byte{'B', 0, 0, 0}},
+		{"trailing data", append(append([]byte{'O'}, make([]byte, 8)...), 'a', 0, 'b')},
+		{"unknown relation", insert},
+	}
+
+	for _, tt := range tests {
+		if _, err := d.Decode(tt.buf); err == nil {
+			t.Errorf("%s: expected error but got none", tt.name)
+		}
+	}
+}
+
+func TestDecodeStreamedTransaction(t *testing.T) {
+	d := pgoutput.NewDecoder(newTestConnInfo())
+
+	buf := []byte{'S'}
+	buf = pgio.AppendUint32(buf, 700)
+	buf = append(buf, 1)
+
+	start, ok := mustDecode(t, d, buf).(*pgoutput.StreamStart)
+	if !ok || start.Xid != 700 || !start.IsFirstSegment() {
+		t.Fatalf("Unexpected StreamStart: %#v", start)
+	}
+
+	// Inside a stream block changes are prefixed with the xid
+	rel := relationMsg()
+	buf = append([]byte{'R'}, pgio.AppendUint32(nil, 700)...)
+	buf = append(buf, rel[1:]...)
+	relation := mustDecode(t, d, buf).(*pgoutput.Relation)
+	if relation.Xid != 700 || relation.RelationName != "foo" {
+		t.Errorf("Unexpected streamed Relation: %#v", relation)
+	}
+
+	buf = []byte{'I'}
+	buf = pgio.AppendUint32(buf, 700)
+	buf = pgio.AppendUint32(buf, 16385)
+	buf = append(buf, 'N')
+	buf = appendTupleText(buf, "1", "a", nil)
+
+	insert, ok := mustDecode(t, d, buf).(*pgoutput.Insert)
+	if !ok || insert.Xid != 700 || insert.NewTuple.Columns[1].Value.Get() != "a" {
+		t.Fatalf("Unexpected streamed Insert: %#v", insert)
+	}
+
+	if _, ok := mustDecode(t, d, []byte{'E'}).(*pgoutput.StreamStop); !ok {
+		t.Fatalf("Expected *pgoutput.StreamStop")
+	}
+
+	// After the stream block changes have no xid prefix again
+	buf = []byte{'I'}
+	buf = pgio.AppendUint32(buf, 16385)
+	buf = append(buf, 'N')
+	buf = appendTupleText(buf, "2", "b", nil)
+
+	insert = mustDecode(t, d, buf).(*pgoutput.Insert)
+	if insert.Xid != 0 || insert.NewTuple.Columns[1].Value.Get() != "b" {
+		t.Errorf("Unexpected Insert: %#v", insert)
+	}
+
+	buf = []byte{'A'}
+	buf = pgio.AppendUint32(buf, 700)
+	buf = pgio.AppendUint32(buf, 701)
+
+	abort, ok := mustDecode(t, d, buf).(*pgoutput.StreamAbort)
+	if !ok || abort.Xid != 700 || abort.SubXid != 701 || abort.IsTransactionAbort() {
+		t.Errorf("Unexpected StreamAbort: %#v", abort)
+	}
+
+	buf = []byte{'c'}
+	buf = pgio.AppendUint32(buf, 700)
+	buf = append(buf, 0)
+	buf = pgio.AppendUint64(buf, 0x3000)
+	buf = pgio.AppendUint64(buf, 0x3030)
+	buf = pgio.AppendInt64(buf, 0)
+
+	commit, ok := mustDecode(t, d, buf).(*pgoutput.StreamCommit)
+	if !ok || commit.Xid != 700 || commit.CommitLSN != 0x3000 || commit.TransactionEndLSN != 0x3030 {
+		t.Errorf("Unexpected StreamCommit: %#v", commit)
+	}
+}
+
+func TestDecodeTwoPhase(t *testing.T) {
+	d := pgoutput.NewDecoder(nil)
+
+	buf := []byte{'b'}
+	buf = pgio.AppendUint64(buf, 0x4000)
+	buf = pgio.AppendUint64(buf, 0x4040)
+	buf = pgio.AppendInt64(buf, 0)
+	buf = pgio.AppendUint32(buf, 800)
+	buf = appendCString(buf, "gid1")
+
+	begin, ok := mustDecode(t, d, buf).(*pgoutput.BeginPrepare)
+	if !ok || begin.PrepareLSN != 0x4000 || begin.Xid != 800 || begin.GID != "gid1" {
+		t.Errorf("Unexpected BeginPrepare: %#v", begin)
+	}
+
+	prepareBody := []byte{0}
+	prepareBody = pgio.AppendUint64(prepareBody, 0x4000)
+	prepareBody = pgio.AppendUint64(prepareBody, 0x4040)
+	prepareBody = pgio.AppendInt64(prepareBody, 0)
+	prepareBody = pgio.AppendUint32(prepareBody, 800)
+	prepareBody = appendCString(prepareBody, "gid1")
+
+	prepare, ok := mustDecode(t, d, append([]byte{'P'}, prepareBody...)).(*pgoutput.Prepare)
+	if !ok || prepare.TransactionEndLSN != 0x4040 || prepare.GID != "gid1" {
+		t.Errorf("Unexpected Prepare: %#v", prepare)
+	}
+
+	streamPrepare, ok := mustDecode(t, d, append([]byte{'p'}, prepareBody...)).(*pgoutput.StreamPrepare)
+	if !ok || streamPrepare.Xid != 800 || streamPrepare.GID != "gid1" {
+		t.Errorf("Unexpected StreamPrepare: %#v", streamPrepare)
+	}
+
+	commit, ok := mustDecode(t, d, append([]byte{'K'}, prepareBody...)).(*pgoutput.CommitPrepared)
+	if !ok || commit.CommitLSN != 0x4000 || commit.Xid != 800 || commit.GID != "gid1" {
+		t.Errorf("Unexpected CommitPrepared: %#v", commit)
+	}
+
+	buf = []byte{'r', 0}
+	buf = pgio.AppendUint64(buf, 0x4040)
+	buf = pgio.AppendUint64(buf, 0x5000)
+	buf = pgio.AppendInt64(buf, 0)
+	buf = pgio.AppendInt64(buf, 0)
+	buf = pgio.AppendUint32(buf, 800)
+	buf = appendCString(buf, "gid1")
+
+	rollback, ok := mustDecode(t, d, buf).(*pgoutput.RollbackPrepared)
+	if !ok || rollback.PrepareEndLSN != 0x4040 || rollback.RollbackTransactionEndLSN != 0x5000 || rollback.GID != "gid1" {
+		t.Errorf("Unexpected RollbackPrepared: %#v", rollback)
+	}
+}
@@ -0,0 +1,366 @@
+package pgoutput
+
+import (
+	"time"
+
+	"github.com/ronaldslc/pgx/pgtype"
+)
+
+// Begin marks the start of a transaction.
+type Begin struct {
+	FinalLSN   uint64 // LSN of the commit record of the transaction
+	CommitTime time.Time
+	Xid        uint32
+}
+
+func (*Begin) MessageType() byte { return MessageTypeBegin }
+
+func (dst *Begin) Decode(src []byte) error {
+	r := &msgReader{src: src}
+	dst.FinalLSN = r.uint64()
+	dst.CommitTime = pgTime(r.int64())
+	dst.Xid = r.uint32()
+	return r.finish("Begin")
+}
+
+// Commit marks the end of a transaction.
+type Commit struct {
+	Flags             uint8 // currently unused
+	CommitLSN         uint64
+	TransactionEndLSN uint64
+	CommitTime        time.Time
+}
+
+func (*Commit) MessageType() byte { return MessageTypeCommit }
+
+func (dst *Commit) Decode(src []byte) error {
+	r := &msgReader{src: src}
+	dst.Flags = r.uint8()
+	dst.CommitLSN = r.uint64()
+	dst.TransactionEndLSN = r.uint64()
+	dst.CommitTime = pgTime(r.int64())
+	return r.finish("Commit")
+}
+
+// Origin identifies the replication origin a transaction came from. It is
+// sent after Begin for transactions that were themselves replicated.
+type Origin struct {
+	CommitLSN uint64 // LSN of the commit on the origin server
+	Name      string
+}
+
+func (*Origin) MessageType() byte { return MessageTypeOrigin }
+
+func (dst *Origin) Decode(src []byte) error {
+	r := &msgReader{src: src}
+	dst.CommitLSN = r.uint64()
+	dst.Name = r.cstring()
+	return r.finish("Origin")
+}
+
+// RelationColumn describes a column of a Relation.
+type RelationColumn struct {
+	Flags        uint8 // 1 marks the column as part of the key
+	Name         string
+	DataType     pgtype.OID
+	TypeModifier int32
+}
+
+// IsKey returns true if the column is part of the replica identity key.
+func (c *RelationColumn) IsKey() bool {
+	return c.Flags&1 != 0
+}
+
+// Relation describes a table. It is sent before the first change to the
+// table in a session and again whenever the table definition changes.
+type Relation struct {
+	Xid             uint32 // set by Decoder for streamed transactions, see StreamStart
+	RelationID      uint32
+	Namespace       string // "pg_catalog" is sent as an empty string
+	RelationName    string
+	ReplicaIdentity uint8 // same as relreplident in pg_class
+	Columns         []RelationColumn
+}
+
+func (*Relation) MessageType() byte { return MessageTypeRelation }
+
+func (dst *Relation) Decode(src []byte) error {
+	r := &msgReader{src: src}
+	dst.RelationID = r.uint32()
+	dst.Namespace = r.cstring()
+	dst.RelationName = r.cstring()
+	dst.ReplicaIdentity = r.uint8()
+
+	columnCount := int(r.int16())
+	if r.err || columnCount < 0 {
+		return &invalidMessageFormatErr{messageType: "Relation"}
+	}
+
+	dst.Columns = make([]RelationColumn, columnCount)
+	for i := range dst.Columns {
+		dst.Columns[i].Flags = r.uint8()
+		dst.Columns[i].Name = r.cstring()
+		dst.Columns[i].DataType = pgtype.OID(r.uint32())
+		dst.Columns[i].TypeModifier = r.int32()
+	}
+
+	return r.finish("Relation")
+}
+
+// Type describes a data type that is not built in. It is sent before the
+// first Relation that uses the type.
+type Type struct {
+	Xid       uint32 // set by Decoder for streamed transactions, see StreamStart
+	DataType  pgtype.OID
+	Namespace string // "pg_catalog" is sent as an empty string
+	Name      string
+}
+
+func (*Type) MessageType() byte { return MessageTypeType }
+
+func (dst *Type) Decode(src []byte) error {
+	r := &msgReader{src: src}
+	dst.DataType = pgtype.OID(r.uint32())
+	dst.Namespace = r.cstring()
+	dst.Name = r.cstring()
+	return r.finish("Type")
+}
+
+// Tuple column kinds
+const (
+	TupleColumnNull           = 'n'
+	TupleColumnUnchangedToast = 'u'
+	TupleColumnText           = 't'
+	TupleColumnBinary         = 'b'
+)
+
+// TupleColumn is a single column value of a Tuple.
+type TupleColumn struct {
+	Kind byte   // one of the TupleColumn* constants
+	Data []byte // raw value for TupleColumnText and TupleColumnBinary
+
+	// Value is the decoded value. It is set by Decoder when the relation of the
+	// tuple is known. Value is nil for TupleColumnUnchangedToast columns as the
+	// server does not send their contents.
+	Value pgtype.Value
+}
+
+// Tuple is a row of a relation.
+type Tuple struct {
+	Columns []TupleColumn
+}
+
+// Values returns the decoded value of each column. See TupleColumn.Value.
+func (t *Tuple) Values() []pgtype.Value {
+	values := make([]pgtype.Value, len(t.Columns))
+	for i := range t.Columns {
+		values[i] = t.Columns[i].Value
+	}
+	return values
+}
+
+func (t *Tuple) decode(r *msgReader) bool {
+	columnCount := int(r.int16())
+	if r.err || columnCount < 0 {
+		return false
+	}
+
+	t.Columns = make([]TupleColumn, columnCount)
+	for i := range t.Columns {
+		t.Columns[i].Kind = r.uint8()
+		switch t.Columns[i].Kind {
+		case TupleColumnNull, TupleColumnUnchangedToast:
+		case TupleColumnText, TupleColumnBinary:
+			n := int(r.int32())
+			if n < 0 {
+				return false
+			}
+			t.Columns[i].Data = r.next(n)
+		default:
+			return false
+		}
+	}
+
+	return !r.err
+}
+
+// Insert is a row inserted into Relation.
+type Insert struct {
+	Xid        uint32 // set by Decoder for streamed transactions, see StreamStart
+	RelationID uint32
+	Relation   *Relation // set by Decoder
+	NewTuple   *Tuple
+}
+
+func (*Insert) MessageType() byte { return MessageTypeInsert }
+
+func (dst *Insert) Decode(src []byte) error {
+	r := &msgReader{src: src}
+	dst.RelationID = r.uint32()
+	if r.uint8() != 'N' {
+		return &invalidMessageFormatErr{messageType: "Insert"}
+	}
+
+	dst.NewTuple = &Tuple{}
+	if !dst.NewTuple.decode(r) {
+		return &invalidMessageFormatErr{messageType: "Insert"}
+	}
+
+	return r.finish("Insert")
+}
+
+// Old tuple kinds sent with Update and Delete
+const (
+	OldTupleKey = 'K' // only the replica identity key columns are set
+	OldTupleOld = 'O' // all columns are set (REPLICA IDENTITY FULL)
+)
+
+// Update is a row of Relation that was updated.
+type Update struct {
+	Xid        uint32 // set by Decoder for streamed transactions, see StreamStart
+	RelationID uint32
+	Relation   *Relation // set by Decoder
+
+	// OldTupleKind is OldTupleKey or OldTupleOld if OldTuple is present and 0
+	// otherwise. The old tuple is only sent when the replica identity changed or
+	// the table uses REPLICA IDENTITY FULL.
+	OldTupleKind byte
+	OldTuple     *Tuple
+	NewTuple     *Tuple
+}
+
+func (*Update) MessageType() byte { return MessageTypeUpdate }
+
+func (dst *Update) Decode(src []byte) error {
+	r := &msgReader{src: src}
+	dst.RelationID = r.uint32()
+
+	kind := r.uint8()
+	dst.OldTupleKind = 0
+	dst.OldTuple = nil
+	if kind == OldTupleKey || kind == OldTupleOld {
+		dst.OldTupleKind = kind
+		dst.OldTuple = &Tuple{}
+		if !dst.OldTuple.decode(r) {
+			return &invalidMessageFormatErr{messageType: "Update"}
+		}
+		kind = r.uint8()
+	}
+
+	if kind != 'N' {
+		return &invalidMessageFormatErr{messageType: "Update"}
+	}
+
+	dst.NewTuple = &Tuple{}
+	if !dst.NewTuple.decode(r) {
+		return &invalidMessageFormatErr{messageType: "Update"}
+	}
+
+	return r.finish("Update")
+}
+
+// Delete is a row deleted from Relation.
+type Delete struct {
+	Xid          uint32 // set by Decoder for streamed transactions, see StreamStart
+	RelationID   uint32
+	Relation     *Relation // set by Decoder
+	OldTupleKind byte      // OldTupleKey or OldTupleOld
+	OldTuple     *Tuple
+}
+
+func (*Delete) MessageType() byte { return MessageTypeDelete }
+
+func (dst *Delete) Decode(src []byte) error {
+	r := &msgReader{src: src}
+	dst.RelationID = r.uint32()
+
+	dst.OldTupleKind = r.uint8()
+	if dst.OldTupleKind != OldTupleKey && dst.OldTupleKind != OldTupleOld {
+		return &invalidMessageFormatErr{messageType: "Delete"}
+	}
+
+	dst.OldTuple = &Tuple{}
+	if !dst.OldTuple.decode(r) {
+		return &invalidMessageFormatErr{messageType: "Delete"}
+	}
+
+	return r.finish("Delete")
+}
+
+// Truncate options
+const (
+	TruncateCascade         = 1
+	TruncateRestartIdentity = 2
+)
+
+// Truncate is one or more relations that were truncated.
+type Truncate struct {
+	Xid         uint32 // set by Decoder for streamed transactions, see StreamStart
+	Options     uint8  // bitmask of the Truncate* options
+	RelationIDs []uint32
+	Relations   []*Relation // set by Decoder
+}
+
+func (*Truncate) MessageType() byte { return MessageTypeTruncate }
+
+func (dst *Truncate) Decode(src []byte) error {
+	r := &msgReader{src: src}
+	relationCount := int(r.int32())
+	dst.Options = r.uint8()
+	// Each relation ID takes 4 bytes. Checking the count against the rest of
+	// the message keeps a malformed count from causing a huge allocation.
+	if r.err || relationCount < 0 || relationCount > (len(r.src)-r.rp)/4 {
+		return &invalidMessageFormatErr{messageType: "Truncate"}
+	}
+
+	dst.RelationIDs = make([]uint32, relationCount)
+	for i := range dst.RelationIDs {
+		dst.RelationIDs[i] = r.uint32()
+	}
+
+	return r.finish("Truncate")
+}
+
+// Cascade returns true if the relations were truncated with CASCADE.
+func (t *Truncate) Cascade() bool {
+	return t.Options&TruncateCascade != 0
+}
+
+// RestartIdentity returns true if the relations were truncated with RESTART
+// IDENTITY.
+func (t *Truncate) RestartIdentity() bool {
+	return t.Options&TruncateRestartIdentity != 0
+}
+
+// LogicalMessage is a message emitted with pg_logical_emit_message. It is
+// only sent when the messages option of the plugin is enabled.
+type LogicalMessage struct {
+	Xid     uint32 // set by Decoder for streamed transactions, see StreamStart
+	Flags   uint8  // 1 marks the message as transactional
+	LSN     uint64
+	Prefix  string
+	Content []byte
+}
+
+func (*LogicalMessage) MessageType() byte { return MessageTypeMessage }
+
+func (dst *LogicalMessage) Decode(src []byte) error {
+	r := &msgReader{src: src}
+	dst.Flags = r.uint8()
+	dst.LSN = r.uint64()
+	dst.Prefix = r.cstring()
+
+	n := int(r.int32())
+	if n < 0 {
+		return &invalidMessageFormatErr{messageType: "LogicalMessage"}
+	}
+	dst.Content = r.next(n)
+
+	return r.finish("LogicalMessage")
+}
+
+// Transactional returns true if the message was emitted as part of a
+// transaction.
+func (m *LogicalMessage) Transactional() bool {
+	return m.Flags&1 != 0
+}
@@ -0,0 +1,141 @@
+package pgoutput
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/ronaldslc/pgx"
+	"github.com/ronaldslc/pgx/internal/pgversion"
+)
+
+// Protocol versions of the pgoutput plugin
+const (
+	ProtoVersion1 = 1 // PostgreSQL 10
+	ProtoVersion2 = 2 // PostgreSQL 14, adds streaming of in-progress transactions
+	ProtoVersion3 = 3 // PostgreSQL 15, adds two-phase commit
+	ProtoVersion4 = 4 // PostgreSQL 16, adds parallel streaming
+)
+
+// Options are the options of the pgoutput plugin.
+type Options struct {
+	// ProtoVersion is the protocol version to request. If it is 0 the highest
+	// version supported by the server is used.
+	ProtoVersion int
+
+	// Publications are the names of the publications to replicate. At least
+	// one is required.
+	Publications []string
+
+	// Binary requests column values in the binary format (PostgreSQL 14 and
+	// later).
+	Binary bool
+
+	// Messages requests messages emitted with pg_logical_emit_message
+	// (PostgreSQL 14 and later).
+	Messages bool
+
+	// Streaming requests that large in-progress transactions are streamed in
+	// blocks between StreamStart and StreamStop instead of being sent at
+	// commit. It requires protocol version 2.
+	Streaming bool
+
+	// TwoPhase requests that prepared transactions are sent at PREPARE
+	// TRANSACTION instead of at COMMIT PREPARED. It requires protocol version
+	// 3 and a slot created with two-phase decoding enabled.
+	TwoPhase bool
+}
+
+// NegotiateProtoVersion returns the highest protocol version supported by
+// a server with the given server_version.
+func NegotiateProtoVersion(serverVersion string) (int, error) {
+	major, err := pgversion.Major(serverVersion)
+	if err != nil {
+		return 0, err
+	}
+
+	switch {
+	case major >= 16:
+		return ProtoVersion4, nil
+	case major == 15:
+		return ProtoVersion3, nil
+	case major == 14:
+		return ProtoVersion2, nil
+	case major >= 10:
+		return ProtoVersion1, nil
+	default:
+		return 0, errors.Errorf("pgoutput requires PostgreSQL 10 or later, but server version is %s", serverVersion)
+	}
+}
+
+// PluginArguments returns the plugin arguments for
+// pgx.ReplicationConn.StartReplication. ProtoVersion must be set.
+func (o *Options) PluginArguments() ([]string, error) {
+	if o.ProtoVersion < ProtoVersion1 {
+		return nil, errors.New("pgoutput ProtoVersion is required")
+	}
+	if len(o.Publications) == 0 {
+		return nil, errors.New("pgoutput requires at least one publication")
+	}
+	if o.Streaming && o.ProtoVersion < ProtoVersion2 {
+		return nil, errors.Errorf("pgoutput streaming requires protocol version 2, but version %d is used", o.ProtoVersion)
+	}
+	if o.TwoPhase && o.ProtoVersion < ProtoVersion3 {
+		return nil, errors.Errorf("pgoutput two_phase requires protocol version 3, but version %d is used", o.ProtoVersion)
+	}
+
+	publications := make([]string, len(o.Publications))
+	for i, p := range o.Publications {
+		publications[i] = `"` + strings.Replace(p, `"`, `""`, -1) + `"`
+	}
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, `("proto_version" '%d', "publication_names" %s`, o.ProtoVersion, quoteLiteral(strings.Join(publications, ",")))
+	if o.Binary {
+		buf.WriteString(`, "binary" 'on'`)
+	}
+	if o.Messages {
+		buf.WriteString(`, "messages" 'on'`)
+	}
+	if o.Streaming {
+		buf.WriteString(`, "streaming" 'on'`)
+	}
+	if o.TwoPhase {
+		buf.WriteString(`, "two_phase" 'on'`)
+	}
+	buf.WriteString(")")
+
+	return []string{buf.String()}, nil
+}
+
+func quoteLiteral(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+// StartReplication starts logical replication from slotName with the pgoutput
+// plugin. If options.ProtoVersion is 0 the highest protocol version supported
+// by the server is negotiated. It returns the protocol version used.
+//
+// With Streaming or TwoPhase set the negotiated version must support them.
+func StartReplication(rc *pgx.ReplicationConn, slotName string, startLsn uint64, options Options) (int, error) {
+	if options.ProtoVersion == 0 {
+		protoVersion, err := NegotiateProtoVersion(rc.ServerVersion())
+		if err != nil {
+			return 0, err
+		}
+		options.ProtoVersion = protoVersion
+	}
+
+	pluginArguments, err := options.PluginArguments()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := rc.StartReplication(slotName, startLsn, -1, pluginArguments...); err != nil {
+		return 0, err
+	}
+
+	return options.ProtoVersion, nil
+}
@@ -0,0 +1,71 @@
+package pgoutput_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ronaldslc/pgx/pgoutput"
+)
+
+func TestNegotiateProtoVersion(t *testing.T) {
+	tests := []struct {
+		serverVersion string
+		protoVersion  int
+	}{
+		{"10.4", pgoutput.ProtoVersion1},
+		{"13.11 (Debian 13.11-1.pgdg110+1)", pgoutput.ProtoVersion1},
+		{"14.2", pgoutput.ProtoVersion2},
+		{"15beta1", pgoutput.ProtoVersion3},
+		{"16.0", pgoutput.ProtoVersion4},
+		{"17", pgoutput.ProtoVersion4},
+	}
+
+	for _, tt := range tests {
+		protoVersion, err := pgoutput.NegotiateProtoVersion(tt.serverVersion)
+		if err != nil {
+			t.Errorf("%s: %v", tt.serverVersion, err)
+			continue
+		}
+		if protoVersion != tt.protoVersion {
+			t.Errorf("%s: expected protocol version %d, but got %d", tt.serverVersion, tt.protoVersion, protoVersion)
+		}
+	}
+
+	for _, serverVersion := range []string{"9.6.3", "", "devel"} {
+		if _, err := pgoutput.NegotiateProtoVersion(serverVersion); err == nil {
+			t.Errorf("%q: expected error but got none", serverVersion)
+		}
+	}
+}
+
+func TestOptionsPluginArguments(t *testing.T) {
+	options := pgoutput.Options{
+		ProtoVersion: pgoutput.ProtoVersion3,
+		Publications: []string{"pub", `it's "quoted"`},
+		Messages:     true,
+		Streaming:    true,
+		TwoPhase:     true,
+	}
+
+	args, err := options.PluginArguments()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{`("proto_version" '3', "publication_names" '"pub","it''s ""quoted"""', "messages" 'on', "streaming" 'on', "two_phase" 'on')`}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("Expected %v, but got %v", expected, args)
+	}
+
+	invalid := []pgoutput.Options{
+		{Publications: []string{"pub"}},
+		{ProtoVersion: pgoutput.ProtoVersion1},
+		{ProtoVersion: pgoutput.ProtoVersion1, Publications: []string{"pub"}, Streaming: true},
+		{ProtoVersion: pgoutput.ProtoVersion2, Publications: []string{"pub"}, TwoPhase: true},
+	}
+	for i, options := range invalid {
+		if _, err := options.PluginArguments(); err == nil {
+			t.Errorf("%d: expected error but got none", i)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+// Package pgoutput decodes the logical replication messages produced by the
+// PostgreSQL pgoutput output plugin.
+//
+// The messages are the WalData of the pgx.WalMessage values received from a
+// pgx.ReplicationConn that was started with the pgoutput plugin:
+//
+//	_, err := pgoutput.StartReplication(rc, "slot", 0, pgoutput.Options{Publications: []string{"pub"}})
+//	...
+//	decoder := pgoutput.NewDecoder(conn.ConnInfo)
+//	for {
+//		r, err := rc.WaitForReplicationMessage(ctx)
+//		...
+//		if r.WalMessage != nil {
+//			msg, err := decoder.Decode(r.WalMessage.WalData)
+//			...
+//			switch msg := msg.(type) {
+//			case *pgoutput.Insert:
+//				// msg.Relation.Columns[i] describes msg.NewTuple.Columns[i]
+//			}
+//		}
+//	}
+//
+// With Options.Streaming large transactions are sent in blocks before they
+// commit, and with Options.TwoPhase prepared transactions are sent when they
+// are prepared. The Decoder returns the corresponding StreamStart, StreamStop,
+// StreamCommit, StreamAbort, BeginPrepare, Prepare, StreamPrepare,
+// CommitPrepared and RollbackPrepared messages.
+//
+// See https://www.postgresql.org/docs/current/protocol-logicalrep-message-formats.html
+// for the message formats.
+package pgoutput
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// Message types sent by the pgoutput plugin
+const (
+	MessageTypeBegin    = 'B'
+	MessageTypeCommit   = 'C'
+	MessageTypeOrigin   = 'O'
+	MessageTypeRelation = 'R'
+	MessageTypeType     = 'Y'
+	MessageTypeInsert   = 'I'
+	MessageTypeUpdate   = 'U'
+	MessageTypeDelete   = 'D'
+	MessageTypeTruncate = 'T'
+	MessageTypeMessage  = 'M'
+
+	// Sent with protocol version 2 and later when streaming is enabled
+	MessageTypeStreamStart  = 'S'
+	MessageTypeStreamStop   = 'E'
+	MessageTypeStreamCommit = 'c'
+	MessageTypeStreamAbort  = 'A'
+
+	// Sent with protocol version 3 and later when two_phase is enabled
+	MessageTypeBeginPrepare     = 'b'
+	MessageTypePrepare          = 'P'
+	MessageTypeCommitPrepared   = 'K'
+	MessageTypeRollbackPrepared = 'r'
+	MessageTypeStreamPrepare    = 'p'
+)
+
+// Message is the interface implemented by all messages sent by the pgoutput
+// plugin.
+type Message interface {
+	// Decode is allowed and expected to retain a reference to data after
+	// returning.
+	Decode(data []byte) error
+
+	// MessageType returns the byte that identifies the message type.
+	MessageType() byte
+}
+
+type invalidMessageFormatErr struct {
+	messageType string
+}
+
+func (e *invalidMessageFormatErr) Error() string {
+	return fmt.Sprintf("%s body is invalid", e.messageType)
+}
+
+var epochNano int64
+
+func init() {
+	epochNano = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC).UnixNano()
+}
+
+// pgTime converts a PostgreSQL timestamp in microseconds since 2000-01-01 to a
+// time.Time.
+func pgTime(microsecSinceY2K int64) time.Time {
+	return time.Unix(0, microsecSinceY2K*1000+epochNano)
+}
+
+// msgReader reads the fields of a message body. The first read past the end of
+// the body sets err and all further reads return zero values.
+type msgReader struct {
+	src []byte
+	rp  int
+	err bool
+}
+
+func (r *msgReader) next(n int) []byte {
+	if r.err || len(r.src)-r.rp < n {
+		r.err = true
+		return nil
+	}
+	buf := r.src[r.rp : r.rp+n]
+	r.rp += n
+	return buf
+}
+
+func (r *msgReader) uint8() uint8 {
+	buf := r.next(1)
+	if buf == nil {
+		return 0
+	}
+	return buf[0]
+}
+
+func (r *msgReader) int16() int16 {
+	buf := r.next(2)
+	if buf == nil {
+		return 0
+	}
+	return int16(binary.BigEndian.Uint16(buf))
+}
+
+func (r *msgReader) int32() int32 {
+	return int32(r.uint32())
+}
+
+func (r *msgReader) uint32() uint32 {
+	buf := r.next(4)
+	if buf == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(buf)
+}
+
+func (r *msgReader) int64() int64 {
+	return int64(r.uint64())
+}
+
+func (r *msgReader) uint64() uint64 {
+	buf := r.next(8)
+	if buf == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint64(buf)
+}
+
+func (r *msgReader) cstring() string {
+	if r.err {
+		return ""
+	}
+	for i := r.rp; i < len(r.src); i++ {
+		if r.src[i] == 0 {
+			s := string(r.src[r.rp:i])
+			r.rp = i + 1
+			return s
+		}
+	}
+	r.err = true
+	return ""
+}
+
+// finish returns an error if the body was too short or has trailing data.
+func (r *msgReader) finish(messageType string) error {
+	if r.err || r.rp != len(r.src) {
+		return &invalidMessageFormatErr{messageType: messageType}
+	}
+	return nil
+}
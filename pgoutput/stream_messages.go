@@ -0,0 +1,207 @@
+package pgoutput
+
+import (
+	"time"
+)
+
+// StreamStart marks the start of a block of changes of an in-progress
+// transaction. Until the matching StreamStop the Relation, Type, Insert,
+// Update, Delete, Truncate and LogicalMessage messages belong to the
+// transaction Xid, and Decoder sets their Xid field. A large transaction is
+// streamed in several blocks that may be interleaved with other transactions.
+type StreamStart struct {
+	Xid          uint32
+	FirstSegment uint8 // 1 for the first block of the transaction
+}
+
+func (*StreamStart) MessageType() byte { return MessageTypeStreamStart }
+
+func (dst *StreamStart) Decode(src []byte) error {
+	r := &msgReader{src: src}
+	dst.Xid = r.uint32()
+	dst.FirstSegment = r.uint8()
+	return r.finish("StreamStart")
+}
+
+// IsFirstSegment returns true if this is the first block of the transaction.
+func (m *StreamStart) IsFirstSegment() bool {
+	return m.FirstSegment == 1
+}
+
+// StreamStop marks the end of a block of changes started by StreamStart.
+type StreamStop struct{}
+
+func (*StreamStop) MessageType() byte { return MessageTypeStreamStop }
+
+func (dst *StreamStop) Decode(src []byte) error {
+	r := &msgReader{src: src}
+	return r.finish("StreamStop")
+}
+
+// StreamCommit marks the commit of a streamed transaction. All of its changes
+// have been sent in earlier blocks.
+type StreamCommit struct {
+	Xid               uint32
+	Flags             uint8 // currently unused
+	CommitLSN         uint64
+	TransactionEndLSN uint64
+	CommitTime        time.Time
+}
+
+func (*StreamCommit) MessageType() byte { return MessageTypeStreamCommit }
+
+func (dst *StreamCommit) Decode(src []byte) error {
+	r := &msgReader{src: src}
+	dst.Xid = r.uint32()
+	dst.Flags = r.uint8()
+	dst.CommitLSN = r.uint64()
+	dst.TransactionEndLSN = r.uint64()
+	dst.CommitTime = pgTime(r.int64())
+	return r.finish("StreamCommit")
+}
+
+// StreamAbort marks the abort of a streamed transaction or of one of its
+// subtransactions. When SubXid equals Xid the whole transaction was aborted
+// and all of its streamed changes must be discarded. Otherwise only the
+// changes of the subtransaction SubXid must be discarded.
+type StreamAbort struct {
+	Xid    uint32
+	SubXid uint32
+
+	// AbortLSN and AbortTime are only sent with protocol version 4 when
+	// streaming is set to parallel.
+	AbortLSN  uint64
+	AbortTime time.Time
+}
+
+func (*StreamAbort) MessageType() byte { return MessageTypeStreamAbort }
+
+func (dst *StreamAbort) Decode(src []byte) error {
+	r := &msgReader{src: src}
+	dst.Xid = r.uint32()
+	dst.SubXid = r.uint32()
+	dst.AbortLSN = 0
+	dst.AbortTime = time.Time{}
+	if len(src) > 8 {
+		dst.AbortLSN = r.uint64()
+		dst.AbortTime = pgTime(r.int64())
+	}
+	return r.finish("StreamAbort")
+}
+
+// IsTransactionAbort returns true if the whole transaction was aborted rather
+// than a subtransaction.
+func (m *StreamAbort) IsTransactionAbort() bool {
+	return m.Xid == m.SubXid
+}
+
+// BeginPrepare marks the start of a transaction that is prepared for two-phase
+// commit. It is followed by the changes of the transaction and a Prepare.
+type BeginPrepare struct {
+	PrepareLSN        uint64
+	TransactionEndLSN uint64
+	PrepareTime       time.Time
+	Xid               uint32
+	GID               string // global identifier given to PREPARE TRANSACTION
+}
+
+func (*BeginPrepare) MessageType() byte { return MessageTypeBeginPrepare }
+
+func (dst *BeginPrepare) Decode(src []byte) error {
+	r := &msgReader{src: src}
+	dst.PrepareLSN = r.uint64()
+	dst.TransactionEndLSN = r.uint64()
+	dst.PrepareTime = pgTime(r.int64())
+	dst.Xid = r.uint32()
+	dst.GID = r.cstring()
+	return r.finish("BeginPrepare")
+}
+
+// Prepare marks the end of a transaction started by BeginPrepare. The
+// transaction is then prepared but neither committed nor rolled back, which
+// is later signalled by CommitPrepared or RollbackPrepared.
+type Prepare struct {
+	Flags             uint8 // currently unused
+	PrepareLSN        uint64
+	TransactionEndLSN uint64
+	PrepareTime       time.Time
+	Xid               uint32
+	GID               string
+}
+
+func (*Prepare) MessageType() byte { return MessageTypePrepare }
+
+func (dst *Prepare) Decode(src []byte) error {
+	return dst.decode(src, "Prepare")
+}
+
+func (dst *Prepare) decode(src []byte, messageType string) error {
+	r := &msgReader{src: src}
+	dst.Flags = r.uint8()
+	dst.PrepareLSN = r.uint64()
+	dst.TransactionEndLSN = r.uint64()
+	dst.PrepareTime = pgTime(r.int64())
+	dst.Xid = r.uint32()
+	dst.GID = r.cstring()
+	return r.finish(messageType)
+}
+
+// StreamPrepare is the Prepare of a streamed transaction. All of its changes
+// have been sent in earlier blocks.
+type StreamPrepare struct {
+	Prepare
+}
+
+func (*StreamPrepare) MessageType() byte { return MessageTypeStreamPrepare }
+
+func (dst *StreamPrepare) Decode(src []byte) error {
+	return dst.decode(src, "StreamPrepare")
+}
+
+// CommitPrepared marks the commit of a prepared transaction.
+type CommitPrepared struct {
+	Flags             uint8 // currently unused
+	CommitLSN         uint64
+	TransactionEndLSN uint64
+	CommitTime        time.Time
+	Xid               uint32
+	GID               string
+}
+
+func (*CommitPrepared) MessageType() byte { return MessageTypeCommitPrepared }
+
+func (dst *CommitPrepared) Decode(src []byte) error {
+	r := &msgReader{src: src}
+	dst.Flags = r.uint8()
+	dst.CommitLSN = r.uint64()
+	dst.TransactionEndLSN = r.uint64()
+	dst.CommitTime = pgTime(r.int64())
+	dst.Xid = r.uint32()
+	dst.GID = r.cstring()
+	return r.finish("CommitPrepared")
+}
+
+// RollbackPrepared marks the rollback of a prepared transaction.
+type RollbackPrepared struct {
+	Flags                     uint8 // currently unused
+	PrepareEndLSN             uint64
+	RollbackTransactionEndLSN uint64
+	PrepareTime               time.Time
+	RollbackTime              time.Time
+	Xid                       uint32
+	GID                       string
+}
+
+func (*RollbackPrepared) MessageType() byte { return MessageTypeRollbackPrepared }
+
+func (dst *RollbackPrepared) Decode(src []byte) error {
+	r := &msgReader{src: src}
+	dst.Flags = r.uint8()
+	dst.PrepareEndLSN = r.uint64()
+	dst.RollbackTransactionEndLSN = r.uint64()
+	dst.PrepareTime = pgTime(r.int64())
+	dst.RollbackTime = pgTime(r.int64())
+	dst.Xid = r.uint32()
+	dst.GID = r.cstring()
+	return r.finish("RollbackPrepared")
+}
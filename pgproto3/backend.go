@@ -8,22 +8,43 @@ import (
 	"github.com/ronaldslc/pgx/chunkreader"
 )
 
+// maxStartupPacketLen is the largest startup message the server accepts.
+const maxStartupPacketLen = 10000
+
+// MaxMessageLen is the largest length of a message other than a startup
+// message, including the length field itself. It is the limit the server
+// applies to the messages it receives.
+const MaxMessageLen = 1<<30 - 1
+
 type Backend struct {
 	cr *chunkreader.ChunkReader
 	w  io.Writer
 
+	// authType is the type of the last Authentication sent. It determines how
+	// a 'p' message is decoded.
+	authType uint32
+
 	// Frontend message flyweights
-	bind            Bind
-	_close          Close
-	describe        Describe
-	execute         Execute
-	flush           Flush
-	parse           Parse
-	passwordMessage PasswordMessage
-	query           Query
-	startupMessage  StartupMessage
-	sync            Sync
-	terminate       Terminate
+	bind                Bind
+	cancelRequest       CancelRequest
+	_close              Close
+	copyData            CopyData
+	copyDone            CopyDone
+	copyFail            CopyFail
+	describe            Describe
+	execute             Execute
+	flush               Flush
+	functionCall        FunctionCall
+	gssEncRequest       GSSENCRequest
+	parse               Parse
+	passwordMessage     PasswordMessage
+	query               Query
+	saslInitialResponse SASLInitialResponse
+	saslResponse        SASLResponse
+	sslRequest          SSLRequest
+	startupMessage      StartupMessage
+	sync                Sync
+	terminate           Terminate
 }
 
 func NewBackend(r io.Reader, w io.Writer) (*Backend, error) {
@@ -31,29 +52,75 @@ func NewBackend(r io.Reader, w io.Writer) (*Backend, error) {
 	return &Backend{cr: cr, w: w}, nil
 }
 
+// Send sends msg to the frontend. Sending an Authentication also sets the
+// authentication type, see SetAuthType.
 func (b *Backend) Send(msg BackendMessage) error {
+	if auth, ok := msg.(*Authentication); ok {
+		if err := b.SetAuthType(auth.Type); err != nil {
+			return err
+		}
+	}
+
 	_, err := b.w.Write(msg.Encode(nil))
 	return err
 }
 
-func (b *Backend) ReceiveStartupMessage() (*StartupMessage, error) {
+// SetAuthType sets the authentication type the frontend is responding to. It
+// determines whether Receive decodes a 'p' message as a PasswordMessage,
+// SASLInitialResponse or SASLResponse. It is set automatically when an
+// Authentication is sent with Send. Call it when the Authentication was sent
+// by other means, e.g. by a proxy forwarding the messages of a server.
+func (b *Backend) SetAuthType(authType uint32) error {
+	switch authType {
+	case AuthTypeOk, AuthTypeCleartextPassword, AuthTypeMD5Password,
+		AuthTypeSASL, AuthTypeSASLContinue, AuthTypeSASLFinal:
+		b.authType = authType
+	default:
+		return errors.Errorf("unknown authentication type: %d", authType)
+	}
+
+	return nil
+}
+
+// ReceiveStartupMessage receives the first message of a connection, which has
+// no message type. It returns a *StartupMessage, *SSLRequest, *GSSENCRequest
+// or *CancelRequest. After an SSLRequest or GSSENCRequest is answered the
+// frontend sends another startup message.
+func (b *Backend) ReceiveStartupMessage() (FrontendMessage, error) {
 	buf, err := b.cr.Next(4)
 	if err != nil {
 		return nil, err
 	}
-	msgSize := int(binary.BigEndian.Uint32(buf) - 4)
+	msgSize := int(int32(binary.BigEndian.Uint32(buf))) - 4
+	if msgSize < 4 || msgSize > maxStartupPacketLen {
+		return nil, errors.Errorf("invalid startup message length: %d", msgSize+4)
+	}
 
 	buf, err = b.cr.Next(msgSize)
 	if err != nil {
 		return nil, err
 	}
 
-	err = b.startupMessage.Decode(buf)
+	var msg FrontendMessage
+	switch code := binary.BigEndian.Uint32(buf); code {
+	case ProtocolVersionNumber:
+		msg = &b.startupMessage
+	case sslRequestNumber:
+		msg = &b.sslRequest
+	case gssEncReqNumber:
+		msg = &b.gssEncRequest
+	case cancelRequestCode:
+		msg = &b.cancelRequest
+	default:
+		return nil, errors.Errorf("unknown startup message code: %d", code)
+	}
+
+	err = msg.Decode(buf)
 	if err != nil {
 		return nil, err
 	}
 
-	return &b.startupMessage, nil
+	return msg, nil
 }
 
 func (b *Backend) Receive() (FrontendMessage, error) {
@@ -63,7 +130,11 @@ func (b *Backend) Receive() (FrontendMessage, error) {
 	}
 
 	msgType := header[0]
-	bodyLen := int(binary.BigEndian.Uint32(header[1:])) - 4
+	msgLen := int(binary.BigEndian.Uint32(header[1:]))
+	if msgLen < 4 || msgLen > MaxMessageLen {
+		return nil, errors.Errorf("invalid message length: %d", msgLen)
+	}
+	bodyLen := msgLen - 4
 
 	var msg FrontendMessage
 	switch msgType {
@@ -71,16 +142,31 @@ func (b *Backend) Receive() (FrontendMessage, error) {
 		msg = &b.bind
 	case 'C':
 		msg = &b._close
+	case 'c':
+		msg = &b.copyDone
 	case 'D':
 		msg = &b.describe
+	case 'd':
+		msg = &b.copyData
 	case 'E':
 		msg = &b.execute
+	case 'F':
+		msg = &b.functionCall
+	case 'f':
+		msg = &b.copyFail
 	case 'H':
 		msg = &b.flush
 	case 'P':
 		msg = &b.parse
 	case 'p':
-		msg = &b.passwordMessage
+		switch b.authType {
+		case AuthTypeSASL:
+			msg = &b.saslInitialResponse
+		case AuthTypeSASLContinue:
+			msg = &b.saslResponse
+		default:
+			msg = &b.passwordMessage
+		}
 	case 'Q':
 		msg = &b.query
 	case 'S':
@@ -0,0 +1,175 @@
+package pgproto3_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/ronaldslc/pgx/pgproto3"
+)
+
+func TestBackendReceiveStartupMessage(t *testing.T) {
+	t.Parallel()
+
+	tests := []pgproto3.FrontendMessage{
+		&pgproto3.StartupMessage{ProtocolVersion: pgproto3.ProtocolVersionNumber, Parameters: map[string]string{"user": "jack", "database": "mydb"}},
+		&pgproto3.SSLRequest{},
+		&pgproto3.GSSENCRequest{},
+		&pgproto3.CancelRequest{ProcessID: 42, SecretKey: 1234},
+	}
+
+	for i, tt := range tests {
+		backend, err := pgproto3.NewBackend(bytes.NewReader(tt.Encode(nil)), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		msg, err := backend.ReceiveStartupMessage()
+		if err != nil {
+			t.Errorf("%d. %T: %v", i, tt, err)
+			continue
+		}
+		if !reflect.DeepEqual(msg, tt) {
+			t.Errorf("%d. expected %#v, got %#v", i, tt, msg)
+		}
+	}
+}
+
+func TestBackendReceiveStartupMessageInvalid(t *testing.T) {
+	t.Parallel()
+
+	tests := [][]byte{
+		{0, 0, 0, 8, 0, 0, 0, 1},                  // unknown code
+		{0, 0, 0, 4},                              // too short
+		{0, 1, 0, 0, 0, 3, 0, 0},                  // too long
+		{0, 0, 0, 12, 4, 210, 22, 46, 0, 0, 0, 0}, // cancel request without secret key
+	}
+
+	for i, tt := range tests {
+		backend, err := pgproto3.NewBackend(bytes.NewReader(tt), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if msg, err := backend.ReceiveStartupMessage(); err == nil {
+			t.Errorf("%d. expected error, got %#v", i, msg)
+		}
+	}
+}
+
+func TestBackendReceive(t *testing.T) {
+	t.Parallel()
+
+	tests := []pgproto3.FrontendMessage{
+		&pgproto3.CopyData{Data: []byte("1\tfoo\n")},
+		&pgproto3.CopyDone{},
+		&pgproto3.CopyFail{Message: "client canceled"},
+		&pgproto3.FunctionCall{
+			Function:         1598,
+			ArgFormatCodes:   []int16{1},
+			Arguments:        [][]byte{{0, 0, 0, 1}, nil},
+			ResultFormatCode: 1,
+		},
+		&pgproto3.FunctionCall{Function: 1598},
+		&pgproto3.PasswordMessage{Password: "secret"},
+		&pgproto3.Query{String: "select 1"},
+		&pgproto3.Terminate{},
+	}
+
+	var buf []byte
+	for _, tt := range tests {
+		buf = tt.Encode(buf)
+	}
+
+	backend, err := pgproto3.NewBackend(bytes.NewReader(buf), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, tt := range tests {
+		msg, err := backend.Receive()
+		if err != nil {
+			t.Fatalf("%d. %T: %v", i, tt, err)
+		}
+		if !reflect.DeepEqual(msg, tt) {
+			t.Errorf("%d. expected %#v, got %#v", i, tt, msg)
+		}
+	}
+}
+
+func TestBackendReceiveInvalidLength(t *testing.T) {
+	t.Parallel()
+
+	tests := [][]byte{
+		{'Q', 0, 0, 0, 1},          // shorter than the length field
+		{'Q', 0, 0, 0, 0},          // zero
+		{'Q', 255, 255, 255, 255},  // negative
+		{'Q', 64, 0, 0, 0, 's', 0}, // too long
+	}
+
+	for i, tt := range tests {
+		backend, err := pgproto3.NewBackend(bytes.NewReader(tt), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if msg, err := backend.Receive(); err == nil {
+			t.Errorf("%d. expected error, got %#v", i, msg)
+		}
+	}
+}
+
+func TestBackendReceiveSASL(t *testing.T) {
+	t.Parallel()
+
+	initialResponse := &pgproto3.SASLInitialResponse{AuthMechanism: "SCRAM-SHA-256", Data: []byte("n,,n=,r=nonce")}
+	response := &pgproto3.SASLResponse{Data: []byte("c=biws,r=nonce,p=proof")}
+	password := &pgproto3.PasswordMessage{Password: "secret"}
+
+	var in []byte
+	in = initialResponse.Encode(in)
+	in = response.Encode(in)
+	in = password.Encode(in)
+
+	var out bytes.Buffer
+	backend, err := pgproto3.NewBackend(bytes.NewReader(in), &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		auth     *pgproto3.Authentication
+		expected pgproto3.FrontendMessage
+	}{
+		{&pgproto3.Authentication{Type: pgproto3.AuthTypeSASL, SASLAuthMechanisms: []string{"SCRAM-SHA-256"}}, initialResponse},
+		{&pgproto3.Authentication{Type: pgproto3.AuthTypeSASLContinue, SASLData: []byte("r=nonce,s=salt,i=4096")}, response},
+		{&pgproto3.Authentication{Type: pgproto3.AuthTypeCleartextPassword}, password},
+	}
+
+	for i, step := range steps {
+		if err := backend.Send(step.auth); err != nil {
+			t.Fatal(err)
+		}
+
+		msg, err := backend.Receive()
+		if err != nil {
+			t.Fatalf("%d. %v", i, err)
+		}
+		if !reflect.DeepEqual(msg, step.expected) {
+			t.Errorf("%d. expected %#v, got %#v", i, step.expected, msg)
+		}
+
+		var auth pgproto3.Authentication
+		sent := out.Next(out.Len())
+		if err := auth.Decode(sent[5:]); err != nil {
+			t.Fatalf("%d. %v", i, err)
+		}
+		if !reflect.DeepEqual(&auth, step.auth) {
+			t.Errorf("%d. expected Authentication %#v, got %#v", i, step.auth, &auth)
+		}
+	}
+
+	if err := backend.SetAuthType(99); err == nil {
+		t.Error("expected SetAuthType with unknown type to fail")
+	}
+}
@@ -0,0 +1,55 @@
+package pgproto3
+
+import (
+	"encoding/binary"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"github.com/ronaldslc/pgx/pgio"
+)
+
+const cancelRequestCode = 80877102
+
+type CancelRequest struct {
+	ProcessID uint32
+	SecretKey uint32
+}
+
+func (*CancelRequest) Frontend() {}
+
+// Decode decodes the body of a CancelRequest that starts with the request
+// code.
+func (dst *CancelRequest) Decode(src []byte) error {
+	if len(src) != 12 {
+		return &invalidMessageLenErr{messageType: "CancelRequest", expectedLen: 12, actualLen: len(src)}
+	}
+
+	if code := binary.BigEndian.Uint32(src); code != cancelRequestCode {
+		return errors.Errorf("bad cancel request code. Expected %d, got %d", cancelRequestCode, code)
+	}
+
+	dst.ProcessID = binary.BigEndian.Uint32(src[4:])
+	dst.SecretKey = binary.BigEndian.Uint32(src[8:])
+
+	return nil
+}
+
+func (src *CancelRequest) Encode(dst []byte) []byte {
+	dst = pgio.AppendInt32(dst, 16)
+	dst = pgio.AppendUint32(dst, cancelRequestCode)
+	dst = pgio.AppendUint32(dst, src.ProcessID)
+	dst = pgio.AppendUint32(dst, src.SecretKey)
+	return dst
+}
+
+func (src *CancelRequest) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type      string
+		ProcessID uint32
+		SecretKey uint32
+	}{
+		Type:      "CancelRequest",
+		ProcessID: src.ProcessID,
+		SecretKey: src.SecretKey,
+	})
+}
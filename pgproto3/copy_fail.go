@@ -0,0 +1,45 @@
+package pgproto3
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/ronaldslc/pgx/pgio"
+)
+
+type CopyFail struct {
+	Message string
+}
+
+func (*CopyFail) Frontend() {}
+
+func (dst *CopyFail) Decode(src []byte) error {
+	idx := bytes.IndexByte(src, 0)
+	if idx != len(src)-1 {
+		return &invalidMessageFormatErr{messageType: "CopyFail"}
+	}
+
+	dst.Message = string(src[:idx])
+
+	return nil
+}
+
+func (src *CopyFail) Encode(dst []byte) []byte {
+	dst = append(dst, 'f')
+	dst = pgio.AppendInt32(dst, int32(4+len(src.Message)+1))
+
+	dst = append(dst, src.Message...)
+	dst = append(dst, 0)
+
+	return dst
+}
+
+func (src *CopyFail) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type    string
+		Message string
+	}{
+		Type:    "CopyFail",
+		Message: src.Message,
+	})
+}
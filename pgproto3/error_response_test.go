@@ -0,0 +1,38 @@
+package pgproto3_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ronaldslc/pgx/pgproto3"
+)
+
+func TestErrorResponseEncodeDecode(t *testing.T) {
+	src := &pgproto3.ErrorResponse{
+		Severity:       "ERROR",
+		Code:           "23505",
+		Message:        "duplicate key value violates unique constraint",
+		Detail:         "Key (id)=(1) already exists.",
+		Position:       12,
+		SchemaName:     "public",
+		TableName:      "users",
+		ConstraintName: "users_pkey",
+		File:           "nbtinsert.c",
+		Line:           434,
+		Routine:        "_bt_check_unique",
+		UnknownFields:  map[byte]string{'V': "ERROR"},
+	}
+
+	buf := src.Encode(nil)
+	if buf[0] != 'E' {
+		t.Fatalf("Expected message type E, got %c", buf[0])
+	}
+
+	var dst pgproto3.ErrorResponse
+	if err := dst.Decode(buf[5:]); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&dst, src) {
+		t.Errorf("Expected %#v, got %#v", src, &dst)
+	}
+}
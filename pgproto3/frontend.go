@@ -12,6 +12,13 @@ type Frontend struct {
 	r  io.Reader // io.Reader to read BackendMessage, Read function must be non-blocking
 	w  io.Writer
 
+	// the message being received; kept between calls of Receive so a message
+	// that is partly read when reading fails, e.g. on a timeout, is resumed
+	header    [5]byte
+	headerLen int // how many bytes of header are read
+	body      []byte
+	bodyLen   int // how many bytes of body are read
+
 	// array of BackendMessage for getting BackendMessage by message header first byte
 	backendMsgFlyweights [256]BackendMessage
 }
@@ -38,6 +45,7 @@ func NewFrontend(r io.Reader, w io.Writer) (*Frontend, error) {
 	b.backendMsgFlyweights[uint8('3')] = &CloseComplete{}
 	b.backendMsgFlyweights[uint8('A')] = &NotificationResponse{}
 	b.backendMsgFlyweights[uint8('C')] = &CommandComplete{}
+	b.backendMsgFlyweights[uint8('c')] = &CopyDone{}
 	b.backendMsgFlyweights[uint8('d')] = &CopyData{}
 	b.backendMsgFlyweights[uint8('D')] = &DataRow{}
 	b.backendMsgFlyweights[uint8('E')] = &ErrorResponse{}
@@ -66,14 +74,8 @@ func (b *Frontend) Send(msg FrontendMessage) error {
 // function to batch receive backend message and write to given ReceivedMessages
 // this will make sure ReceivedMessages have at least 1 BackendMessage
 func (b *Frontend) Receive(rmsgs *ReceivedMessages) error {
-	var header [5]byte       // the header array to get message type and body length
-	headerSlice := header[:] // the header slice to read
-
-	var msgBody []byte      // current processing message body
-	var msgBodySlice []byte // the message body slice to read
-
-	// loop until at least 1 message and make sure header does not have any data read before return, so the header data will not lost
-	for rmsgs.Readable() <= 0 || len(headerSlice) < len(header) {
+	// loop until at least 1 message, a partly read message is kept in b for the next call
+	for rmsgs.Readable() <= 0 {
 		_, err := b.rb.ReadFrom(b.r)
 		if err != nil {
 			return err
@@ -82,59 +84,64 @@ func (b *Frontend) Receive(rmsgs *ReceivedMessages) error {
 		// decode the message header and write message and its body to ReceivedMessages
 		for b.rb.Avail() > 0 {
 			// read header
-			rn, err := b.rb.Read(headerSlice)
-			if err != nil {
-				return err
-			}
-			headerSlice = headerSlice[rn:]
+			if b.headerLen < len(b.header) {
+				rn, err := b.rb.Read(b.header[b.headerLen:])
+				if err != nil {
+					return err
+				}
+				b.headerLen += rn
+
+				// header array is not full, break to read data from raw conn
+				if b.headerLen < len(b.header) {
+					break
+				}
 
-			// header array is not full, break to read data from raw conn
-			if len(headerSlice) > 0 {
-				break
+				// header array is full, get message body length
+				b.body = nil
+				b.bodyLen = 0
+				if bodyLen := int(binary.BigEndian.Uint32(b.header[1:])) - 4; bodyLen > 0 {
+					b.body = make([]byte, bodyLen)
+				}
 			}
 
-			// header array is full, get message body length
-			bodyLen := int(binary.BigEndian.Uint32(header[1:])) - 4
-			if bodyLen > 0 {
-				msgBody = make([]byte, bodyLen)
-				msgBodySlice = msgBody
-
-				// loop to get whole message body
-				for len(msgBodySlice) > 0 {
-					_, err = b.rb.ReadFrom(b.r)
-					if err != nil {
-						return err
-					}
-
-					if b.rb.Avail() > 0 {
-						rn, err = b.rb.Read(msgBodySlice)
-						if err != nil {
-							return err
-						}
-						msgBodySlice = msgBodySlice[rn:]
-					}
+			// read body
+			if b.bodyLen < len(b.body) {
+				if b.rb.Avail() == 0 {
+					break
+				}
+
+				rn, err := b.rb.Read(b.body[b.bodyLen:])
+				if err != nil {
+					return err
+				}
+				b.bodyLen += rn
+
+				// body is not full, break to read data from raw conn
+				if b.bodyLen < len(b.body) {
+					break
 				}
 			}
 
 			// decode message type
-			msg := b.backendMsgFlyweights[header[0]]
+			msg := b.backendMsgFlyweights[b.header[0]]
 			if msg == nil {
-				return errors.Errorf("unknown message type: %c", header[0])
+				return errors.Errorf("unknown message type: %c", b.header[0])
 			}
 
 			// write the message to ReceivedMessages
-			if err := rmsgs.Write(msg, msgBody); err != nil {
+			if err := rmsgs.Write(msg, b.body); err != nil {
 				return err
 			}
 
+			// reset header and body for next message decode usage
+			b.headerLen = 0
+			b.body = nil
+			b.bodyLen = 0
+
 			// buffer is full, no need to get and decode more message
 			if rmsgs.WriteCapacity() <= 0 {
 				return nil
 			}
-
-			// reset headerSlice and msgBody for next message decode usage
-			headerSlice = header[:]
-			msgBody = nil
 		}
 	}
 
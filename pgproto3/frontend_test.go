@@ -1,9 +1,87 @@
 package pgproto3
 
 import (
+	"bytes"
+	"io"
 	"testing"
 )
 
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// interruptedReader returns its chunks one per Read. A nil chunk is returned
+// as a timeout error.
+type interruptedReader struct {
+	chunks [][]byte
+}
+
+func (r *interruptedReader) Read(p []byte) (int, error) {
+	if len(r.chunks) == 0 {
+		return 0, io.EOF
+	}
+
+	chunk := r.chunks[0]
+	r.chunks = r.chunks[1:]
+	if chunk == nil {
+		return 0, timeoutError{}
+	}
+	return copy(p, chunk), nil
+}
+
+func TestFrontendReceiveResumesAfterTimeout(t *testing.T) {
+	buf := (&CopyData{Data: []byte("hello")}).Encode(nil)
+	buf = (&ReadyForQuery{TxStatus: 'I'}).Encode(buf)
+
+	// Interrupt inside the header of the first message and inside its body.
+	r := &interruptedReader{chunks: [][]byte{buf[:3], nil, buf[3:7], nil, buf[7:]}}
+	frontend, err := NewFrontend(r, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rmsgs := NewReceivedMessages(4)
+
+	var timeouts int
+	for {
+		err := frontend.Receive(rmsgs)
+		if err == nil {
+			break
+		}
+		if _, ok := err.(timeoutError); !ok {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		timeouts++
+	}
+	if timeouts != 2 {
+		t.Errorf("Expected 2 timeouts, got %d", timeouts)
+	}
+
+	if rmsgs.Readable() != 2 {
+		t.Fatalf("Expected 2 messages, got %d", rmsgs.Readable())
+	}
+
+	msg, body, err := rmsgs.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	copyData, ok := msg.(*CopyData)
+	if !ok {
+		t.Fatalf("Expected CopyData, got %T", msg)
+	}
+	if err := copyData.Decode(body); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(copyData.Data, []byte("hello")) {
+		t.Errorf("Expected hello, got %q", copyData.Data)
+	}
+
+	if msg, _, _ := rmsgs.Read(); msg != frontend.backendMsgFlyweights['Z'] {
+		t.Errorf("Expected ReadyForQuery, got %T", msg)
+	}
+}
+
 // benchmark for decode message header type by switch
 func BenchmarkBackendMessageDecodeBySwitch(b *testing.B) {
 	var authentication Authentication
@@ -0,0 +1,140 @@
+package pgproto3
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/ronaldslc/pgx/pgio"
+)
+
+type FunctionCall struct {
+	Function         uint32
+	ArgFormatCodes   []int16
+	Arguments        [][]byte
+	ResultFormatCode int16
+}
+
+func (*FunctionCall) Frontend() {}
+
+func (dst *FunctionCall) Decode(src []byte) error {
+	*dst = FunctionCall{}
+
+	if len(src) < 8 {
+		return &invalidMessageFormatErr{messageType: "FunctionCall"}
+	}
+	rp := 0
+	dst.Function = binary.BigEndian.Uint32(src[rp:])
+	rp += 4
+
+	argFormatCodeCount := int(binary.BigEndian.Uint16(src[rp:]))
+	rp += 2
+	if len(src[rp:]) < argFormatCodeCount*2+2 {
+		return &invalidMessageFormatErr{messageType: "FunctionCall"}
+	}
+	if argFormatCodeCount > 0 {
+		dst.ArgFormatCodes = make([]int16, argFormatCodeCount)
+		for i := 0; i < argFormatCodeCount; i++ {
+			dst.ArgFormatCodes[i] = int16(binary.BigEndian.Uint16(src[rp:]))
+			rp += 2
+		}
+	}
+
+	argumentCount := int(binary.BigEndian.Uint16(src[rp:]))
+	rp += 2
+	if argumentCount > 0 {
+		dst.Arguments = make([][]byte, argumentCount)
+		for i := 0; i < argumentCount; i++ {
+			if len(src[rp:]) < 4 {
+				return &invalidMessageFormatErr{messageType: "FunctionCall"}
+			}
+
+			argumentLength := int(int32(binary.BigEndian.Uint32(src[rp:])))
+			rp += 4
+
+			// null
+			if argumentLength == -1 {
+				continue
+			}
+
+			if argumentLength < 0 || len(src[rp:]) < argumentLength {
+				return &invalidMessageFormatErr{messageType: "FunctionCall"}
+			}
+
+			dst.Arguments[i] = src[rp : rp+argumentLength]
+			rp += argumentLength
+		}
+	}
+
+	if len(src[rp:]) != 2 {
+		return &invalidMessageFormatErr{messageType: "FunctionCall"}
+	}
+	dst.ResultFormatCode = int16(binary.BigEndian.Uint16(src[rp:]))
+
+	return nil
+}
+
+func (src *FunctionCall) Encode(dst []byte) []byte {
+	dst = append(dst, 'F')
+	sp := len(dst)
+	dst = pgio.AppendInt32(dst, -1)
+
+	dst = pgio.AppendUint32(dst, src.Function)
+
+	dst = pgio.AppendUint16(dst, uint16(len(src.ArgFormatCodes)))
+	for _, fc := range src.ArgFormatCodes {
+		dst = pgio.AppendInt16(dst, fc)
+	}
+
+	dst = pgio.AppendUint16(dst, uint16(len(src.Arguments)))
+	for _, a := range src.Arguments {
+		if a == nil {
+			dst = pgio.AppendInt32(dst, -1)
+		} else {
+			dst = pgio.AppendInt32(dst, int32(len(a)))
+			dst = append(dst, a...)
+		}
+	}
+
+	dst = pgio.AppendInt16(dst, src.ResultFormatCode)
+
+	pgio.SetInt32(dst[sp:], int32(len(dst[sp:])))
+
+	return dst
+}
+
+func (src *FunctionCall) MarshalJSON() ([]byte, error) {
+	formattedArguments := make([]map[string]string, len(src.Arguments))
+	for i, a := range src.Arguments {
+		if a == nil {
+			continue
+		}
+
+		textFormat := true
+		if len(src.ArgFormatCodes) == 1 {
+			textFormat = src.ArgFormatCodes[0] == 0
+		} else if len(src.ArgFormatCodes) > 1 {
+			textFormat = src.ArgFormatCodes[i] == 0
+		}
+
+		if textFormat {
+			formattedArguments[i] = map[string]string{"text": string(a)}
+		} else {
+			formattedArguments[i] = map[string]string{"binary": hex.EncodeToString(a)}
+		}
+	}
+
+	return json.Marshal(struct {
+		Type             string
+		Function         uint32
+		ArgFormatCodes   []int16
+		Arguments        []map[string]string
+		ResultFormatCode int16
+	}{
+		Type:             "FunctionCall",
+		Function:         src.Function,
+		ArgFormatCodes:   src.ArgFormatCodes,
+		Arguments:        formattedArguments,
+		ResultFormatCode: src.ResultFormatCode,
+	})
+}
@@ -0,0 +1,44 @@
+package pgproto3
+
+import (
+	"encoding/binary"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"github.com/ronaldslc/pgx/pgio"
+)
+
+const gssEncReqNumber = 80877104
+
+// GSSENCRequest asks the server to start GSSAPI encryption. Like SSLRequest the
+// server responds with a single byte, 'G' to proceed or 'N' to refuse.
+type GSSENCRequest struct{}
+
+func (*GSSENCRequest) Frontend() {}
+
+// Decode decodes the body of a GSSENCRequest, which is the request code.
+func (dst *GSSENCRequest) Decode(src []byte) error {
+	if len(src) != 4 {
+		return &invalidMessageLenErr{messageType: "GSSENCRequest", expectedLen: 4, actualLen: len(src)}
+	}
+
+	if code := binary.BigEndian.Uint32(src); code != gssEncReqNumber {
+		return errors.Errorf("bad gssenc request code. Expected %d, got %d", gssEncReqNumber, code)
+	}
+
+	return nil
+}
+
+func (src *GSSENCRequest) Encode(dst []byte) []byte {
+	dst = pgio.AppendInt32(dst, 8)
+	dst = pgio.AppendUint32(dst, gssEncReqNumber)
+	return dst
+}
+
+func (src *GSSENCRequest) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type string
+	}{
+		Type: "GSSENCRequest",
+	})
+}
@@ -0,0 +1,80 @@
+package pgproto3
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/ronaldslc/pgx/pgio"
+)
+
+type SASLInitialResponse struct {
+	AuthMechanism string
+	Data          []byte
+}
+
+func (*SASLInitialResponse) Frontend() {}
+
+func (dst *SASLInitialResponse) Decode(src []byte) error {
+	*dst = SASLInitialResponse{}
+
+	idx := bytes.IndexByte(src, 0)
+	if idx < 0 {
+		return &invalidMessageFormatErr{messageType: "SASLInitialResponse"}
+	}
+	dst.AuthMechanism = string(src[:idx])
+	rp := idx + 1
+
+	if len(src[rp:]) < 4 {
+		return &invalidMessageFormatErr{messageType: "SASLInitialResponse"}
+	}
+	dataLength := int(int32(binary.BigEndian.Uint32(src[rp:])))
+	rp += 4
+
+	if dataLength == -1 {
+		if len(src[rp:]) != 0 {
+			return &invalidMessageFormatErr{messageType: "SASLInitialResponse"}
+		}
+		return nil
+	}
+
+	if len(src[rp:]) != dataLength {
+		return &invalidMessageFormatErr{messageType: "SASLInitialResponse"}
+	}
+	dst.Data = src[rp:]
+
+	return nil
+}
+
+func (src *SASLInitialResponse) Encode(dst []byte) []byte {
+	dst = append(dst, 'p')
+	sp := len(dst)
+	dst = pgio.AppendInt32(dst, -1)
+
+	dst = append(dst, src.AuthMechanism...)
+	dst = append(dst, 0)
+
+	if src.Data == nil {
+		dst = pgio.AppendInt32(dst, -1)
+	} else {
+		dst = pgio.AppendInt32(dst, int32(len(src.Data)))
+		dst = append(dst, src.Data...)
+	}
+
+	pgio.SetInt32(dst[sp:], int32(len(dst[sp:])))
+
+	return dst
+}
+
+func (src *SASLInitialResponse) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type          string
+		AuthMechanism string
+		Data          string
+	}{
+		Type:          "SASLInitialResponse",
+		AuthMechanism: src.AuthMechanism,
+		Data:          hex.EncodeToString(src.Data),
+	})
+}
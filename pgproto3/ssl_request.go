@@ -0,0 +1,43 @@
+package pgproto3
+
+import (
+	"encoding/binary"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"github.com/ronaldslc/pgx/pgio"
+)
+
+// SSLRequest asks the server to start TLS. The server responds with a single
+// byte, 'S' to proceed with the TLS handshake or 'N' to refuse, not with a
+// message.
+type SSLRequest struct{}
+
+func (*SSLRequest) Frontend() {}
+
+// Decode decodes the body of an SSLRequest, which is the request code.
+func (dst *SSLRequest) Decode(src []byte) error {
+	if len(src) != 4 {
+		return &invalidMessageLenErr{messageType: "SSLRequest", expectedLen: 4, actualLen: len(src)}
+	}
+
+	if code := binary.BigEndian.Uint32(src); code != sslRequestNumber {
+		return errors.Errorf("bad ssl request code. Expected %d, got %d", sslRequestNumber, code)
+	}
+
+	return nil
+}
+
+func (src *SSLRequest) Encode(dst []byte) []byte {
+	dst = pgio.AppendInt32(dst, 8)
+	dst = pgio.AppendUint32(dst, sslRequestNumber)
+	return dst
+}
+
+func (src *SSLRequest) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type string
+	}{
+		Type: "SSLRequest",
+	})
+}
@@ -56,6 +56,8 @@ type Rows struct {
 	args       []interface{}
 	unlockConn bool
 	closed     bool
+	ctx        context.Context // context of the query, used for logging and tracing
+	traced     bool
 
 	// the count of row which need to be read in Scan
 	// if it is large then rowIdx, rows.Next will not get the row data from Reader
@@ -84,21 +86,29 @@ func (rows *Rows) Close() {
 
 	rows.err = rows.conn.termContext(rows.err)
 
+	if rows.traced {
+		rows.conn.config.Tracer.TraceQueryEnd(rows.ctx, rows.conn, TraceQueryEndData{RowCount: rows.rowCount, Err: rows.err})
+	}
+
+	duration := time.Since(rows.startTime)
+	if rows.batch == nil {
+		rows.conn.recordQuery(rows.sql, duration, int64(rows.rowCount), rows.err)
+	}
+
 	if rows.err == nil {
-		if rows.conn.shouldLog(LogLevelInfo) {
-			endTime := time.Now()
+		if lvl := rows.conn.queryLogLevel(duration); rows.conn.shouldLog(lvl) {
 			var ld LogData
-			ld.Add("time", endTime.Sub(rows.startTime))
+			ld.Add("time", duration)
 			ld.Add("rowCount", rows.rowCount)
-			ld.Add("sql", rows.sql)
-			ld.Add("args", logQueryArgs(rows.args))
-			rows.conn.log(LogLevelInfo, "Query", ld)
+			ld.Add("sql", rows.conn.config.LogArgs.logSQL(rows.sql))
+			rows.conn.addLogArgs(&ld, rows.sql, rows.args)
+			rows.conn.log(rows.ctx, LogLevel(lvl), "Query", ld)
 		}
 	} else if rows.conn.shouldLog(LogLevelError) {
 		var ld LogData
-		ld.Add("sql", rows.sql)
-		ld.Add("args", logQueryArgs(rows.args))
-		rows.conn.log(LogLevelError, "Query", ld)
+		ld.Add("sql", rows.conn.config.LogArgs.logSQL(rows.sql))
+		rows.conn.addLogArgs(&ld, rows.sql, rows.args)
+		rows.conn.log(rows.ctx, LogLevelError, "Query", ld)
 	}
 
 	if rows.batch != nil && rows.err != nil {
@@ -121,7 +131,7 @@ func (rows *Rows) fatal(err error) {
 		return
 	}
 
-	rows.err = err
+	rows.err = rows.conn.addErrorContext(err, rows.sql, rows.args)
 	rows.Close()
 }
 
@@ -428,6 +438,7 @@ func (c *Conn) getRows(bufferSize int, sql string, args []interface{}) *Rows {
 	c.preallocatedRows = c.preallocatedRows[0 : len(c.preallocatedRows)-1]
 
 	r.conn = c
+	r.ctx = context.Background()
 	r.startTime = c.lastActivityTime
 	r.sql = sql
 	r.args = args
@@ -465,18 +476,26 @@ type QueryExOptions struct {
 }
 
 func (c *Conn) QueryEx(ctx context.Context, maxRowCount int, sql string, options *QueryExOptions, args ...interface{}) (rows *Rows, err error) {
-	err = c.waitForPreviousCancelQuery(ctx)
-	if err != nil {
-		return nil, err
+	if c.config.Tracer != nil {
+		ctx = c.config.Tracer.TraceQueryStart(ctx, c, TraceQueryStartData{SQL: sql, Args: args})
 	}
 
-	if err := c.ensureConnectionReadyForQuery(); err != nil {
+	err = c.waitForPreviousCancelQuery(ctx)
+	if err == nil {
+		err = c.ensureConnectionReadyForQuery()
+	}
+	if err != nil {
+		if c.config.Tracer != nil {
+			c.config.Tracer.TraceQueryEnd(ctx, c, TraceQueryEndData{Err: err})
+		}
 		return nil, err
 	}
 
 	c.lastActivityTime = time.Now()
 
 	rows = c.getRows(maxRowCount, sql, args)
+	rows.ctx = ctx
+	rows.traced = c.config.Tracer != nil
 
 	if err := c.lock(); err != nil {
 		rows.fatal(err)
@@ -521,7 +540,7 @@ func (c *Conn) QueryEx(ctx context.Context, maxRowCount int, sql string, options
 		fieldDescriptions, err := c.readUntilRowDescription()
 		if err != nil {
 			rows.fatal(err)
-			return nil, err
+			return nil, rows.err
 		}
 
 		if len(options.ResultFormatCodes) == 0 {
@@ -547,14 +566,14 @@ func (c *Conn) QueryEx(ctx context.Context, maxRowCount int, sql string, options
 	ps, ok := c.preparedStatements[sql]
 	if !ok {
 		if st, ok := c.config.LazyPreparedStatements[sql]; ok {
-			ps, err = c.prepareEx(sql, st, nil)
+			ps, err = c.prepareEx(ctx, sql, st, nil)
 			if err != nil {
 				rows.fatal(err)
 				return rows, rows.err
 			}
 		} else {
 			var err error
-			ps, err = c.prepareEx("", sql, nil)
+			ps, err = c.prepareEx(ctx, "", sql, nil)
 			if err != nil {
 				rows.fatal(err)
 				return rows, rows.err
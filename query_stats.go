@@ -0,0 +1,407 @@
+package pgx
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultLatencyBuckets are the upper bounds of the latency buckets used by a
+// QueryStatsCollector created with nil buckets.
+var DefaultLatencyBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+}
+
+// DefaultMaxFingerprints is the number of fingerprints a QueryStatsCollector
+// keeps before it records further fingerprints in OverflowFingerprint.
+const DefaultMaxFingerprints = 5000
+
+// OverflowFingerprint is the fingerprint under which a QueryStatsCollector
+// records the queries of new fingerprints once it has reached its maximum
+// number of fingerprints.
+const OverflowFingerprint = "<other>"
+
+// QueryStats are the statistics of the queries with one fingerprint.
+type QueryStats struct {
+	Fingerprint string // normalized SQL, see FingerprintSQL
+	Count       int64  // number of executions
+	Errors      int64  // number of executions that failed
+	Rows        int64  // rows returned by Query or affected by Exec
+	TotalTime   time.Duration
+	MinTime     time.Duration
+	MaxTime     time.Duration
+
+	// Buckets counts the executions by latency. Buckets[i] is the number of
+	// executions that took at most the i-th bucket bound and more than the
+	// previous bound. The last element counts the executions that took longer
+	// than the last bound.
+	Buckets []int64
+}
+
+// MeanTime returns the mean latency of the queries.
+func (s *QueryStats) MeanTime() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.TotalTime / time.Duration(s.Count)
+}
+
+// QueryStatsCollector records latency statistics of the queries of one or more
+// connections keyed by the fingerprint of their SQL. It is set with
+// ConnConfig.QueryStats. Set the same collector on the ConnConfig of several
+// connections, or on a ConnPoolConfig, to aggregate them. It is safe for
+// concurrent use.
+//
+// Query and Exec and the methods that delegate to them are recorded. A Query
+// is recorded when its Rows are closed.
+//
+// Literals and lists of values are folded into the fingerprint, but SQL that
+// is built dynamically, e.g. with generated table names, can still produce an
+// unbounded number of fingerprints. To bound its memory the collector keeps at
+// most DefaultMaxFingerprints fingerprints, see SetMaxFingerprints. Queries of
+// fingerprints beyond that are recorded under OverflowFingerprint.
+type QueryStatsCollector struct {
+	mux             sync.Mutex
+	buckets         []time.Duration
+	maxFingerprints int
+	stats           map[string]*QueryStats
+}
+
+// NewQueryStatsCollector returns a QueryStatsCollector that counts latencies
+// in buckets with the given ascending upper bounds. If buckets is nil
+// DefaultLatencyBuckets are used.
+func NewQueryStatsCollector(buckets []time.Duration) *QueryStatsCollector {
+	if buckets == nil {
+		buckets = DefaultLatencyBuckets
+	}
+
+	return &QueryStatsCollector{
+		buckets:         buckets,
+		maxFingerprints: DefaultMaxFingerprints,
+		stats:           make(map[string]*QueryStats),
+	}
+}
+
+// SetMaxFingerprints sets the number of fingerprints c keeps before it records
+// queries under OverflowFingerprint. The overflow fingerprint is not counted.
+// n <= 0 removes the limit. Fingerprints that are already recorded are kept.
+func (c *QueryStatsCollector) SetMaxFingerprints(n int) {
+	c.mux.Lock()
+	c.maxFingerprints = n
+	c.mux.Unlock()
+}
+
+// Buckets returns the upper bounds of the latency buckets.
+func (c *QueryStatsCollector) Buckets() []time.Duration {
+	return c.buckets
+}
+
+// Record records an execution of sql that took d and returned or affected
+// rows rows. err is the error of the execution, if any.
+func (c *QueryStatsCollector) Record(sql string, d time.Duration, rows int64, err error) {
+	fingerprint := FingerprintSQL(sql)
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	s, ok := c.stats[fingerprint]
+	if !ok && c.maxFingerprints > 0 && c.fingerprintCount() >= c.maxFingerprints {
+		fingerprint = OverflowFingerprint
+		s, ok = c.stats[fingerprint]
+	}
+	if !ok {
+		s = &QueryStats{Fingerprint: fingerprint, MinTime: d, Buckets: make([]int64, len(c.buckets)+1)}
+		c.stats[fingerprint] = s
+	}
+
+	s.Count++
+	if err != nil {
+		s.Errors++
+	}
+	s.Rows += rows
+	s.TotalTime += d
+	if d < s.MinTime {
+		s.MinTime = d
+	}
+	if d > s.MaxTime {
+		s.MaxTime = d
+	}
+	s.Buckets[sort.Search(len(c.buckets), func(i int) bool { return d <= c.buckets[i] })]++
+}
+
+// fingerprintCount returns the number of recorded fingerprints other than
+// OverflowFingerprint. c.mux must be held.
+func (c *QueryStatsCollector) fingerprintCount() int {
+	if _, ok := c.stats[OverflowFingerprint]; ok {
+		return len(c.stats) - 1
+	}
+	return len(c.stats)
+}
+
+// Stats returns a copy of the statistics of all fingerprints sorted by
+// descending TotalTime.
+func (c *QueryStatsCollector) Stats() []QueryStats {
+	c.mux.Lock()
+	stats := make([]QueryStats, 0, len(c.stats))
+	for _, s := range c.stats {
+		copied := *s
+		copied.Buckets = append([]int64(nil), s.Buckets...)
+		stats = append(stats, copied)
+	}
+	c.mux.Unlock()
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].TotalTime != stats[j].TotalTime {
+			return stats[i].TotalTime > stats[j].TotalTime
+		}
+		return stats[i].Fingerprint < stats[j].Fingerprint
+	})
+
+	return stats
+}
+
+// Reset discards all recorded statistics.
+func (c *QueryStatsCollector) Reset() {
+	c.mux.Lock()
+	c.stats = make(map[string]*QueryStats)
+	c.mux.Unlock()
+}
+
+// QueryStats returns the QueryStatsCollector of c or nil if none is set.
+func (c *Conn) QueryStats() *QueryStatsCollector {
+	return c.config.QueryStats
+}
+
+// QueryStats returns the QueryStatsCollector shared by the connections of p
+// or nil if none is set.
+func (p *ConnPool) QueryStats() *QueryStatsCollector {
+	return p.config.QueryStats
+}
+
+// recordQuery records a query in the QueryStatsCollector of c, if any. sql may
+// be the name of a prepared statement.
+func (c *Conn) recordQuery(sql string, d time.Duration, rows int64, err error) {
+	if c.config.QueryStats == nil {
+		return
+	}
+
+	if ps, ok := c.preparedStatements[sql]; ok {
+		sql = ps.SQL
+	}
+
+	c.config.QueryStats.Record(sql, d, rows, err)
+}
+
+// FingerprintSQL normalizes sql so that queries that differ only in literal
+// values, comments, whitespace or the case of keywords have the same
+// fingerprint. String and numeric literals are replaced with ?, lists of
+// literals or placeholders such as IN (1, 2, 3) and IN ($1, $2) are collapsed
+// to a single ?, the rows of a multi-row VALUES list are collapsed to a single
+// row, comments are removed, whitespace is collapsed and everything
+// outside of quoted identifiers is lowercased.
+func FingerprintSQL(sql string) string {
+	var b bytes.Buffer
+	b.Grow(len(sql))
+
+	// lastValue is true when the last token written is a ? so that lists of
+	// values can be collapsed.
+	lastValue := false
+	pendingSpace := false
+
+	// writeSpace writes a pending space unless it is at the start or follows
+	// an opening parenthesis.
+	writeSpace := func() {
+		if pendingSpace && b.Len() > 0 && b.Bytes()[b.Len()-1] != '(' {
+			b.WriteByte(' ')
+		}
+		pendingSpace = false
+	}
+	writeValue := func() {
+		// Collapse "?, ?" to "?"
+		if lastValue {
+			return
+		}
+		writeSpace()
+		b.WriteByte('?')
+		lastValue = true
+	}
+	writeToken := func(s string) {
+		writeSpace()
+		b.WriteString(s)
+		lastValue = false
+	}
+
+	for i := 0; i < len(sql); {
+		ch := sql[i]
+
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r' || ch == '\f':
+			pendingSpace = true
+			i++
+
+		case ch == '-' && i+1 < len(sql) && sql[i+1] == '-':
+			end := strings.IndexByte(sql[i:], '\n')
+			if end == -1 {
+				i = len(sql)
+			} else {
+				i += end
+			}
+			pendingSpace = true
+
+		case ch == '/' && i+1 < len(sql) && sql[i+1] == '*':
+			end := strings.Index(sql[i+2:], "*/")
+			if end == -1 {
+				i = len(sql)
+			} else {
+				i += end + 4
+			}
+			pendingSpace = true
+
+		case ch == '\'' || ((ch == 'e' || ch == 'E') && i+1 < len(sql) && sql[i+1] == '\'' && !isIdentByte(sql, i-1)):
+			if ch != '\'' {
+				i++
+			}
+			i = skipStringLiteral(sql, i, ch != '\'')
+			writeValue()
+
+		case ch == '"':
+			end := i + 1
+			for end < len(sql) {
+				if sql[end] == '"' {
+					if end+1 < len(sql) && sql[end+1] == '"' {
+						end += 2
+						continue
+					}
+					break
+				}
+				end++
+			}
+			if end < len(sql) {
+				end++
+			}
+			writeToken(sql[i:end])
+			i = end
+
+		case ch == '$' && i+1 < len(sql) && sql[i+1] >= '0' && sql[i+1] <= '9':
+			end := i + 1
+			for end < len(sql) && sql[end] >= '0' && sql[end] <= '9' {
+				end++
+			}
+			writeValue()
+			i = end
+
+		case ch == '$':
+			// dollar quoted string such as $$...$$ or $tag$...$tag$
+			tagEnd := i + 1
+			for tagEnd < len(sql) && isIdentChar(sql[tagEnd]) {
+				tagEnd++
+			}
+			if tagEnd < len(sql) && sql[tagEnd] == '$' {
+				tag := sql[i : tagEnd+1]
+				end := strings.Index(sql[tagEnd+1:], tag)
+				if end == -1 {
+					i = len(sql)
+				} else {
+					i = tagEnd + 1 + end + len(tag)
+				}
+				writeValue()
+			} else {
+				writeToken("$")
+				i++
+			}
+
+		case ch >= '0' && ch <= '9' && !isIdentByte(sql, i-1):
+			end := i
+			for end < len(sql) && (isIdentChar(sql[end]) || sql[end] == '.' ||
+				((sql[end] == '+' || sql[end] == '-') && (sql[end-1] == 'e' || sql[end-1] == 'E'))) {
+				end++
+			}
+			writeValue()
+			i = end
+
+		case isIdentChar(ch):
+			end := i
+			for end < len(sql) && isIdentChar(sql[end]) {
+				end++
+			}
+			writeToken(strings.ToLower(sql[i:end]))
+			i = end
+
+		case ch == ',' && lastValue:
+			// Possibly a list of values. Skip the comma if the next token is
+			// also a value.
+			j := i + 1
+			for j < len(sql) && (sql[j] == ' ' || sql[j] == '\t' || sql[j] == '\n' || sql[j] == '\r') {
+				j++
+			}
+			if j < len(sql) && (sql[j] == '\'' || sql[j] == '$' || (sql[j] >= '0' && sql[j] <= '9')) {
+				i = j
+				pendingSpace = false
+				continue
+			}
+			fallthrough
+
+		case ch == ',':
+			pendingSpace = false
+			writeToken(",")
+			pendingSpace = true
+			i++
+
+		case ch == ')':
+			pendingSpace = false
+			writeToken(")")
+			// Collapse the rows of VALUES (?), (?) to (?)
+			if bytes.HasSuffix(b.Bytes(), []byte("(?), (?)")) {
+				b.Truncate(b.Len() - len(", (?)"))
+			}
+			i++
+
+		default:
+			writeToken(string(ch))
+			i++
+		}
+	}
+
+	return b.String()
+}
+
+// skipStringLiteral returns the index after the string literal that starts
+// with the quote at i. In escape strings a backslash escapes the next byte.
+func skipStringLiteral(sql string, i int, escapes bool) int {
+	i++
+	for i < len(sql) {
+		switch sql[i] {
+		case '\\':
+			if escapes {
+				i += 2
+				continue
+			}
+		case '\'':
+			if i+1 < len(sql) && sql[i+1] == '\'' {
+				i += 2
+				continue
+			}
+			return i + 1
+		}
+		i++
+	}
+	return i
+}
+
+func isIdentChar(ch byte) bool {
+	return ch == '_' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || (ch >= '0' && ch <= '9') || ch >= 0x80
+}
+
+// isIdentByte returns true if sql[i] exists and is part of an identifier.
+func isIdentByte(sql string, i int) bool {
+	return i >= 0 && i < len(sql) && isIdentChar(sql[i])
+}
@@ -0,0 +1,190 @@
+package pgx_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ronaldslc/pgx"
+)
+
+func TestFingerprintSQL(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		sql      string
+		expected string
+	}{
+		{"select 1", "select ?"},
+		{"SELECT  *\n\tFROM foo WHERE id = 42", "select * from foo where id = ?"},
+		{"select * from foo where name = 'bar' and x = -1.5e-3", "select * from foo where name = ? and x = -?"},
+		{"select * from foo where name = 'it''s'", "select * from foo where name = ?"},
+		{`select * from foo where name = E'it\'s'`, "select * from foo where name = ?"},
+		{"select $$a 'b' c$$, $tag$d$tag$", "select ?"},
+		{"select * from foo where id in (1, 2, 3)", "select * from foo where id in (?)"},
+		{"select * from foo where id in ( $1,$2 ,$3 )", "select * from foo where id in (?)"},
+		{"insert into foo(a,b) values($1, $2)", "insert into foo(a, b) values(?)"},
+		{"insert into foo(a,b) values ($1, $2), ($3, $4),($5, $6)", "insert into foo(a, b) values (?)"},
+		{"select f(1), g(2)", "select f(?), g(?)"},
+		{`select "Foo"."Bar" from "Foo"`, `select "Foo"."Bar" from "Foo"`},
+		{"select t1.a -- comment\nfrom t1 /* block */ where a = $1::int4", "select t1.a from t1 where a = ?::int4"},
+	}
+
+	for i, tt := range tests {
+		actual := pgx.FingerprintSQL(tt.sql)
+		if actual != tt.expected {
+			t.Errorf("%d. FingerprintSQL(%q): expected %q, got %q", i, tt.sql, tt.expected, actual)
+		}
+	}
+}
+
+func TestQueryStatsCollector(t *testing.T) {
+	t.Parallel()
+
+	collector := pgx.NewQueryStatsCollector([]time.Duration{time.Millisecond, 10 * time.Millisecond})
+
+	collector.Record("select * from foo where id = 1", 500*time.Microsecond, 1, nil)
+	collector.Record("select * from foo where id = 2", 5*time.Millisecond, 0, nil)
+	collector.Record("SELECT * FROM foo WHERE id = 3", 20*time.Millisecond, 1, nil)
+	collector.Record("select 1/0", time.Millisecond, 0, pgx.PgError{Code: "22012"})
+
+	stats := collector.Stats()
+	if len(stats) != 2 {
+		t.Fatalf("Expected 2 fingerprints, got %d: %v", len(stats), stats)
+	}
+
+	s := stats[0]
+	if s.Fingerprint != "select * from foo where id = ?" {
+		t.Errorf("Expected Fingerprint %q, got %q", "select * from foo where id = ?", s.Fingerprint)
+	}
+	if s.Count != 3 || s.Errors != 0 || s.Rows != 2 {
+		t.Errorf("Expected Count 3, Errors 0 and Rows 2, got %d, %d and %d", s.Count, s.Errors, s.Rows)
+	}
+	if s.TotalTime != 25500*time.Microsecond || s.MinTime != 500*time.Microsecond || s.MaxTime != 20*time.Millisecond {
+		t.Errorf("Unexpected latencies: total %v, min %v, max %v", s.TotalTime, s.MinTime, s.MaxTime)
+	}
+	if s.MeanTime() != 8500*time.Microsecond {
+		t.Errorf("Expected MeanTime 8.5ms, got %v", s.MeanTime())
+	}
+	if len(s.Buckets) != 3 || s.Buckets[0] != 1 || s.Buckets[1] != 1 || s.Buckets[2] != 1 {
+		t.Errorf("Expected Buckets [1 1 1], got %v", s.Buckets)
+	}
+
+	if stats[1].Errors != 1 || stats[1].Buckets[0] != 1 {
+		t.Errorf("Expected 1 error in the first bucket, got %v", stats[1])
+	}
+
+	// Stats returns copies
+	stats[0].Buckets[0] = 42
+	if collector.Stats()[0].Buckets[0] != 1 {
+		t.Error("Stats did not copy Buckets")
+	}
+
+	collector.Reset()
+	if len(collector.Stats()) != 0 {
+		t.Error("Reset did not discard stats")
+	}
+}
+
+func TestQueryStatsCollectorMaxFingerprints(t *testing.T) {
+	t.Parallel()
+
+	collector := pgx.NewQueryStatsCollector(nil)
+	collector.SetMaxFingerprints(2)
+
+	collector.Record("select * from a", time.Millisecond, 0, nil)
+	collector.Record("select * from b", time.Millisecond, 0, nil)
+	collector.Record("select * from c", time.Millisecond, 0, nil)
+	collector.Record("select * from d", time.Millisecond, 0, nil)
+	collector.Record("select * from a", time.Millisecond, 0, nil)
+
+	counts := make(map[string]int64)
+	for _, s := range collector.Stats() {
+		counts[s.Fingerprint] = s.Count
+	}
+	expected := map[string]int64{"select * from a": 2, "select * from b": 1, pgx.OverflowFingerprint: 2}
+	if !reflect.DeepEqual(counts, expected) {
+		t.Errorf("Expected %v, got %v", expected, counts)
+	}
+}
+
+func TestConnQueryStats(t *testing.T) {
+	t.Parallel()
+
+	config := *defaultConnConfig
+	config.QueryStats = pgx.NewQueryStatsCollector(nil)
+
+	conn := mustConnect(t, config)
+	defer closeConn(t, conn)
+
+	if conn.QueryStats() != config.QueryStats {
+		t.Fatal("Expected QueryStats to return the configured collector")
+	}
+
+	for i := 1; i <= 3; i++ {
+		rows, err := conn.Query("select generate_series(1, $1::int4)", i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for rows.Next() {
+		}
+		rows.Close()
+		if rows.Err() != nil {
+			t.Fatal(rows.Err())
+		}
+	}
+
+	if _, err := conn.Prepare("ps", "select 1 where 1 = $1"); err != nil {
+		t.Fatal(err)
+	}
+	var n int32
+	if err := conn.QueryRow("ps", 1).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+
+	mustExec(t, conn, "create temporary table foo(a int4)")
+	mustExec(t, conn, "insert into foo values (1), (2)")
+
+	stats := make(map[string]pgx.QueryStats)
+	for _, s := range conn.QueryStats().Stats() {
+		stats[s.Fingerprint] = s
+	}
+
+	if s := stats["select generate_series(?::int4)"]; s.Count != 3 || s.Rows != 6 {
+		t.Errorf("Expected Count 3 and Rows 6 for generate_series, got %v", s)
+	}
+	if s := stats["select ? where ? = ?"]; s.Count != 1 || s.Rows != 1 {
+		t.Errorf("Expected prepared statement to be recorded by its SQL, got %v", stats)
+	}
+	if s := stats["insert into foo values (?), (?)"]; s.Count != 1 || s.Rows != 2 {
+		t.Errorf("Expected Count 1 and Rows 2 for insert, got %v", s)
+	}
+
+	ensureConnValid(t, conn)
+}
+
+func TestSlowQueryThreshold(t *testing.T) {
+	t.Parallel()
+
+	logger := &testLogger{}
+	config := *defaultConnConfig
+	config.Logger = logger
+	config.LogLevel = pgx.LogLevelWarn
+	config.SlowQueryThreshold = 100 * time.Millisecond
+
+	conn := mustConnect(t, config)
+	defer closeConn(t, conn)
+
+	mustExec(t, conn, "select 1")
+	if len(logger.logs) != 0 {
+		t.Fatalf("Expected fast query not to be logged, got %v", logger.logs)
+	}
+
+	mustExec(t, conn, "select pg_sleep(0.2)")
+	if len(logger.logs) != 1 {
+		t.Fatalf("Expected slow query to be logged, got %v", logger.logs)
+	}
+	if logger.logs[0].lvl != pgx.LogLevelWarn || logger.logs[0].msg != "Exec" {
+		t.Errorf("Expected Exec logged at warn, got %v", logger.logs[0])
+	}
+}
@@ -1,15 +1,19 @@
 package pgx
 
 import (
+	"bytes"
 	"context"
 	"encoding/binary"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
 
 	"github.com/ronaldslc/pgx/pgio"
 	"github.com/ronaldslc/pgx/pgproto3"
+	"github.com/ronaldslc/pgx/pgtype"
 )
 
 const (
@@ -20,6 +24,13 @@ const (
 	initialReplicationResponseTimeout = 5 * time.Second
 )
 
+// ErrReplicationStreamEnded occurs when the server ends the replication
+// stream, for example at the end of a timeline during physical replication.
+// The end of the stream is acknowledged before it is returned, so the
+// connection can be used for further commands such as starting replication
+// again on the next timeline returned by NextTimeline.
+var ErrReplicationStreamEnded = errors.New("replication stream ended by server")
+
 var epochNano int64
 
 func init() {
@@ -171,6 +182,11 @@ func ReplicationConnect(config ConnConfig) (r *ReplicationConn, err error) {
 
 type ReplicationConn struct {
 	c *Conn
+
+	// the timeline following the one the last stream ended at, if any
+	nextTimeline         int64
+	nextTimelineStartLsn uint64
+	hasNextTimeline      bool
 }
 
 // Send standby status to the server, which both acts as a keepalive
@@ -199,6 +215,12 @@ func (rc *ReplicationConn) SendStandbyStatus(k *StandbyStatus) (err error) {
 	return
 }
 
+// ServerVersion returns the server_version reported by the server, for
+// example "10.4".
+func (rc *ReplicationConn) ServerVersion() string {
+	return rc.c.RuntimeParams["server_version"]
+}
+
 func (rc *ReplicationConn) Close() error {
 	return rc.c.Close()
 }
@@ -211,7 +233,7 @@ func (rc *ReplicationConn) CauseOfDeath() error {
 	return rc.c.CauseOfDeath()
 }
 
-func (rc *ReplicationConn) readReplicationMessage() (r *ReplicationMessage, err error) {
+func (rc *ReplicationConn) readReplicationMessage(ctx context.Context) (r *ReplicationMessage, err error) {
 	msg, err := rc.c.rxMsg()
 	if err != nil {
 		return
@@ -221,18 +243,23 @@ func (rc *ReplicationConn) readReplicationMessage() (r *ReplicationMessage, err
 	case *pgproto3.NoticeResponse:
 		pgError := rc.c.rxErrorResponse((*pgproto3.ErrorResponse)(msg))
 		if rc.c.shouldLog(LogLevelInfo) {
-			rc.c.log(LogLevelInfo, pgError.Error(), nil)
+			rc.c.log(ctx, LogLevelInfo, pgError.Error(), nil)
 		}
 	case *pgproto3.ErrorResponse:
 		err = rc.c.rxErrorResponse(msg)
 		if rc.c.shouldLog(LogLevelError) {
-			rc.c.log(LogLevelError, err.Error(), nil)
+			rc.c.log(ctx, LogLevelError, err.Error(), nil)
 		}
 		return
 	case *pgproto3.CopyBothResponse:
 		// This is the tail end of the replication process start,
 		// and can be safely ignored
 		return
+	case *pgproto3.CopyDone:
+		if err = rc.endStream(); err == nil {
+			err = ErrReplicationStreamEnded
+		}
+		return
 	case *pgproto3.CopyData:
 		msgType := msg.Data[0]
 		rp := 1
@@ -266,19 +293,81 @@ func (rc *ReplicationConn) readReplicationMessage() (r *ReplicationMessage, err
 			if rc.c.shouldLog(LogLevelError) {
 				var ld LogData
 				ld.Add("type", msgType)
-				rc.c.log(LogLevelError, "Unexpected data playload message type", ld)
+				rc.c.log(ctx, LogLevelError, "Unexpected data playload message type", ld)
 			}
 		}
 	default:
 		if rc.c.shouldLog(LogLevelError) {
 			var ld LogData
 			ld.Add("type", msg)
-			rc.c.log(LogLevelError, "Unexpected replication message type", ld)
+			rc.c.log(ctx, LogLevelError, "Unexpected replication message type", ld)
 		}
 	}
 	return
 }
 
+// endStream completes the end of a replication stream the server ended with a
+// CopyDone. It replies with a CopyDone and reads the result up to the
+// ReadyForQuery. At the end of a timeline the result has the next timeline and
+// the position it starts at.
+func (rc *ReplicationConn) endStream() error {
+	buf := rc.c.wbuf
+	buf = append(buf, copyDone)
+	buf = pgio.AppendInt32(buf, 4)
+
+	if _, err := rc.c.conn.Write(buf); err != nil {
+		rc.c.die(err)
+		return err
+	}
+
+	var softErr error
+	for {
+		msg, err := rc.c.rxMsg()
+		if err != nil {
+			// The connection can not be resynchronized with the server if
+			// the end of the stream is interrupted.
+			rc.c.die(err)
+			return err
+		}
+
+		switch msg := msg.(type) {
+		case *pgproto3.RowDescription, *pgproto3.CommandComplete:
+		case *pgproto3.DataRow:
+			if len(msg.Values) < 2 {
+				err := errors.New("unexpected end of timeline result")
+				rc.c.die(err)
+				return err
+			}
+			timeline, err := strconv.ParseInt(string(msg.Values[0]), 10, 64)
+			if err != nil {
+				rc.c.die(err)
+				return errors.Wrap(err, "invalid next timeline")
+			}
+			startLsn, err := ParseLSN(string(msg.Values[1]))
+			if err != nil {
+				rc.c.die(err)
+				return err
+			}
+			rc.nextTimeline, rc.nextTimelineStartLsn, rc.hasNextTimeline = timeline, startLsn, true
+		case *pgproto3.ReadyForQuery:
+			rc.c.rxReadyForQuery(msg)
+			return softErr
+		default:
+			if err := rc.c.processContextFreeMsg(msg); err != nil && softErr == nil {
+				softErr = err
+			}
+		}
+	}
+}
+
+// NextTimeline returns the timeline following the one the last replication
+// stream ended at and the WAL position it starts at. ok is false unless
+// WaitForReplicationMessage returned ErrReplicationStreamEnded at the end of a
+// timeline since replication was last started.
+func (rc *ReplicationConn) NextTimeline() (timeline int64, startLsn uint64, ok bool) {
+	return rc.nextTimeline, rc.nextTimelineStartLsn, rc.hasNextTimeline
+}
+
 // Wait for a single replication message.
 //
 // Properly using this requires some knowledge of the postgres replication mechanisms,
@@ -307,7 +396,7 @@ func (rc *ReplicationConn) WaitForReplicationMessage(ctx context.Context) (*Repl
 		}
 	}()
 
-	r, opErr := rc.readReplicationMessage()
+	r, opErr := rc.readReplicationMessage(ctx)
 
 	var err error
 	select {
@@ -364,33 +453,132 @@ func (rc *ReplicationConn) sendReplicationModeQuery(sql string) (*Rows, error) {
 	return rows, rows.err
 }
 
+// IdentifySystemResult is the result of IdentifySystem.
+type IdentifySystemResult struct {
+	SystemID string // unique system identifier of the cluster
+	Timeline int32  // current timeline
+	XLogPos  uint64 // current WAL flush position
+	DBName   string // database connected to, empty for physical replication connections
+}
+
 // Execute the "IDENTIFY_SYSTEM" command as documented here:
 // https://www.postgresql.org/docs/9.5/static/protocol-replication.html
-//
-// This will return (if successful) a result set that has a single row
-// that contains the systemid, current timeline, xlogpos and database
-// name.
-//
-// NOTE: Because this is a replication mode connection, we don't have
-// type names, so the field descriptions in the result will have only
-// OIDs and no DataTypeName values
-func (rc *ReplicationConn) IdentifySystem() (r *Rows, err error) {
-	return rc.sendReplicationModeQuery("IDENTIFY_SYSTEM")
+func (rc *ReplicationConn) IdentifySystem() (*IdentifySystemResult, error) {
+	rows, err := rc.sendReplicationModeQuery("IDENTIFY_SYSTEM")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if rows.Err() != nil {
+			return nil, rows.Err()
+		}
+		return nil, ErrNoRows
+	}
+
+	var systemID, xlogPos, dbName pgtype.Text
+	var timeline pgtype.Int4
+	if err := rows.Scan(&systemID, &timeline, &xlogPos, &dbName); err != nil {
+		return nil, err
+	}
+
+	result := &IdentifySystemResult{
+		SystemID: systemID.String,
+		Timeline: timeline.Int,
+		DBName:   dbName.String,
+	}
+	if result.XLogPos, err = ParseLSN(xlogPos.String); err != nil {
+		return nil, err
+	}
+
+	rows.Close()
+	return result, rows.Err()
+}
+
+// TimelineHistory is the result of TimelineHistory.
+type TimelineHistory struct {
+	FileName string
+	Content  []byte // raw contents of the history file
+	Entries  []TimelineHistoryEntry
+}
+
+// TimelineHistoryEntry is a line of a timeline history file. It records that
+// the server switched from Timeline to the next timeline at SwitchPoint.
+type TimelineHistoryEntry struct {
+	Timeline    int32
+	SwitchPoint uint64
+	Reason      string
+}
+
+// ParseTimelineHistory parses the contents of a timeline history file.
+func ParseTimelineHistory(content []byte) ([]TimelineHistoryEntry, error) {
+	var entries []TimelineHistoryEntry
+
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) < 2 {
+			return nil, errors.Errorf("invalid timeline history line: %q", line)
+		}
+
+		timeline, err := strconv.ParseInt(strings.TrimSpace(fields[0]), 10, 32)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid timeline history line: %q", line)
+		}
+
+		switchPoint, err := ParseLSN(strings.TrimSpace(fields[1]))
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid timeline history line: %q", line)
+		}
+
+		entry := TimelineHistoryEntry{Timeline: int32(timeline), SwitchPoint: switchPoint}
+		if len(fields) == 3 {
+			entry.Reason = strings.TrimSpace(fields[2])
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
 }
 
 // Execute the "TIMELINE_HISTORY" command as documented here:
 // https://www.postgresql.org/docs/9.5/static/protocol-replication.html
 //
-// This will return (if successful) a result set that has a single row
-// that contains the filename of the history file and the content
-// of the history file. If called for timeline 1, typically this will
-// generate an error that the timeline history file does not exist.
-//
-// NOTE: Because this is a replication mode connection, we don't have
-// type names, so the field descriptions in the result will have only
-// OIDs and no DataTypeName values
-func (rc *ReplicationConn) TimelineHistory(timeline int) (r *Rows, err error) {
-	return rc.sendReplicationModeQuery(fmt.Sprintf("TIMELINE_HISTORY %d", timeline))
+// This returns the history file of the timeline. If called for timeline 1,
+// typically this will generate an error that the timeline history file does
+// not exist.
+func (rc *ReplicationConn) TimelineHistory(timeline int) (*TimelineHistory, error) {
+	rows, err := rc.sendReplicationModeQuery(fmt.Sprintf("TIMELINE_HISTORY %d", timeline))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if rows.Err() != nil {
+			return nil, rows.Err()
+		}
+		return nil, ErrNoRows
+	}
+
+	// The content is the raw file even though it is described as bytea.
+	var fileName, content pgtype.GenericText
+	if err := rows.Scan(&fileName, &content); err != nil {
+		return nil, err
+	}
+
+	history := &TimelineHistory{FileName: fileName.String, Content: []byte(content.String)}
+	if history.Entries, err = ParseTimelineHistory(history.Content); err != nil {
+		return nil, err
+	}
+
+	rows.Close()
+	return history, rows.Err()
 }
 
 // Start a replication connection, sending WAL data to the given replication
@@ -416,7 +604,40 @@ func (rc *ReplicationConn) StartReplication(slotName string, startLsn uint64, ti
 		queryString += fmt.Sprintf(" %s", arg)
 	}
 
-	if err = rc.c.sendQuery(queryString); err != nil {
+	return rc.startReplication(queryString)
+}
+
+// StartPhysicalReplication starts streaming the raw WAL from startLsn with a
+// START_REPLICATION PHYSICAL command as documented here:
+// https://www.postgresql.org/docs/current/protocol-replication.html
+//
+// The WAL is received with WaitForReplicationMessage the same as with
+// StartReplication. slotName may be empty to stream without a replication
+// slot. In order to omit the timeline argument pass a -1 for the timeline to
+// stream the current timeline of the server.
+//
+// The server ends the stream when it reaches the end of an older timeline.
+// WaitForReplicationMessage completes the end of the stream and returns
+// ErrReplicationStreamEnded in that case. To follow the timeline switch, call
+// StartPhysicalReplication again on the same connection with the timeline and
+// start position returned by NextTimeline.
+func (rc *ReplicationConn) StartPhysicalReplication(slotName string, startLsn uint64, timeline int64) (err error) {
+	queryString := "START_REPLICATION"
+	if slotName != "" {
+		queryString += fmt.Sprintf(" SLOT %s", slotName)
+	}
+	queryString += fmt.Sprintf(" PHYSICAL %s", FormatLSN(startLsn))
+	if timeline >= 0 {
+		queryString += fmt.Sprintf(" TIMELINE %d", timeline)
+	}
+
+	return rc.startReplication(queryString)
+}
+
+func (rc *ReplicationConn) startReplication(queryString string) (err error) {
+	rc.nextTimeline, rc.nextTimelineStartLsn, rc.hasNextTimeline = 0, 0, false
+
+	if err = rc.c.sendQuery(context.Background(), queryString); err != nil {
 		return
 	}
 
@@ -434,19 +655,177 @@ func (rc *ReplicationConn) StartReplication(slotName string, startLsn uint64, ti
 			var ld LogData
 			ld.Add("msg", r)
 			ld.Add("err", err)
-			rc.c.log(LogLevelError, "Unexpected replication message", ld)
+			rc.c.log(ctx, LogLevelError, "Unexpected replication message", ld)
 		}
 	}
 
 	return
 }
 
+// Snapshot actions of a logical replication slot created with
+// CreateReplicationSlotEx.
+const (
+	SnapshotActionDefault  = iota // server default, which is to export the snapshot
+	SnapshotActionExport          // EXPORT_SNAPSHOT
+	SnapshotActionNoExport        // NOEXPORT_SNAPSHOT
+	SnapshotActionUse             // USE_SNAPSHOT; requires a transaction on the replication connection
+)
+
+// ReplicationSlotOptions are the options of CreateReplicationSlotEx.
+type ReplicationSlotOptions struct {
+	// Temporary slots are dropped at the end of the session or on error.
+	Temporary bool
+
+	// SnapshotAction is one of the SnapshotAction* constants. It only applies
+	// to logical slots.
+	SnapshotAction int
+
+	// ReserveWAL makes a physical slot reserve WAL immediately.
+	ReserveWAL bool
+
+	// TwoPhase enables decoding of prepared transactions (PostgreSQL 15 and
+	// later). Failover synchronizes the slot to standbys (PostgreSQL 17 and
+	// later). Setting either uses the parenthesized option syntax.
+	TwoPhase bool
+	Failover bool
+}
+
+// CreateReplicationSlotResult is the result of CreateReplicationSlotEx.
+type CreateReplicationSlotResult struct {
+	SlotName string
+
+	// ConsistentPoint is the LSN at which the slot became consistent. It is
+	// the earliest position streaming from the slot can start at.
+	ConsistentPoint uint64
+
+	// SnapshotName is the name of the exported snapshot. It is empty unless the
+	// snapshot was exported. It remains valid until the next command on the
+	// replication connection or until the connection is closed and can be used
+	// with SET TRANSACTION SNAPSHOT on a regular connection to copy the initial
+	// table data.
+	SnapshotName string
+
+	// OutputPlugin is empty for physical slots.
+	OutputPlugin string
+}
+
+func (opts *ReplicationSlotOptions) sql(slotName, outputPlugin string) string {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "CREATE_REPLICATION_SLOT %s", slotName)
+	if opts.Temporary {
+		buf.WriteString(" TEMPORARY")
+	}
+
+	if outputPlugin == "" {
+		buf.WriteString(" PHYSICAL")
+	} else {
+		fmt.Fprintf(buf, " LOGICAL %s", outputPlugin)
+	}
+
+	if !opts.TwoPhase && !opts.Failover {
+		if outputPlugin == "" {
+			if opts.ReserveWAL {
+				buf.WriteString(" RESERVE_WAL")
+			}
+		} else {
+			switch opts.SnapshotAction {
+			case SnapshotActionExport:
+				buf.WriteString(" EXPORT_SNAPSHOT")
+			case SnapshotActionNoExport:
+				buf.WriteString(" NOEXPORT_SNAPSHOT")
+			case SnapshotActionUse:
+				buf.WriteString(" USE_SNAPSHOT")
+			}
+		}
+		return buf.String()
+	}
+
+	var options []string
+	if opts.TwoPhase {
+		options = append(options, "TWO_PHASE")
+	}
+	if opts.Failover {
+		options = append(options, "FAILOVER")
+	}
+	if outputPlugin == "" {
+		if opts.ReserveWAL {
+			options = append(options, "RESERVE_WAL")
+		}
+	} else {
+		switch opts.SnapshotAction {
+		case SnapshotActionExport:
+			options = append(options, "SNAPSHOT 'export'")
+		case SnapshotActionNoExport:
+			options = append(options, "SNAPSHOT 'nothing'")
+		case SnapshotActionUse:
+			options = append(options, "SNAPSHOT 'use'")
+		}
+	}
+	fmt.Fprintf(buf, " (%s)", strings.Join(options, ", "))
+
+	return buf.String()
+}
+
 // Create the replication slot, using the given name and output plugin.
 func (rc *ReplicationConn) CreateReplicationSlot(slotName, outputPlugin string) (err error) {
-	_, err = rc.c.Exec(fmt.Sprintf("CREATE_REPLICATION_SLOT %s LOGICAL %s", slotName, outputPlugin))
+	_, err = rc.CreateReplicationSlotEx(slotName, outputPlugin, nil)
+	return
+}
+
+// CreatePhysicalReplicationSlot creates a physical replication slot with the
+// given name. If reserveWAL is true the slot reserves WAL immediately instead
+// of on the first connection that streams from it.
+func (rc *ReplicationConn) CreatePhysicalReplicationSlot(slotName string, reserveWAL bool) (err error) {
+	_, err = rc.CreateReplicationSlotEx(slotName, "", &ReplicationSlotOptions{ReserveWAL: reserveWAL})
 	return
 }
 
+// CreateReplicationSlotEx creates a replication slot with a
+// CREATE_REPLICATION_SLOT command as documented here:
+// https://www.postgresql.org/docs/current/protocol-replication.html
+//
+// A logical slot is created with outputPlugin, or a physical slot if
+// outputPlugin is empty. options may be nil.
+func (rc *ReplicationConn) CreateReplicationSlotEx(slotName, outputPlugin string, options *ReplicationSlotOptions) (*CreateReplicationSlotResult, error) {
+	if options == nil {
+		options = &ReplicationSlotOptions{}
+	}
+
+	rows, err := rc.sendReplicationModeQuery(options.sql(slotName, outputPlugin))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if rows.Err() != nil {
+			return nil, rows.Err()
+		}
+		return nil, ErrNoRows
+	}
+
+	var consistentPoint, snapshotName, outputPluginName *string
+	result := &CreateReplicationSlotResult{}
+	if err := rows.Scan(&result.SlotName, &consistentPoint, &snapshotName, &outputPluginName); err != nil {
+		return nil, err
+	}
+
+	if consistentPoint != nil {
+		if result.ConsistentPoint, err = ParseLSN(*consistentPoint); err != nil {
+			return nil, err
+		}
+	}
+	if snapshotName != nil {
+		result.SnapshotName = *snapshotName
+	}
+	if outputPluginName != nil {
+		result.OutputPlugin = *outputPluginName
+	}
+
+	rows.Close()
+	return result, rows.Err()
+}
+
 // Drop the replication slot for the given name
 func (rc *ReplicationConn) DropReplicationSlot(slotName string) (err error) {
 	_, err = rc.c.Exec(fmt.Sprintf("DROP_REPLICATION_SLOT %s", slotName))
@@ -0,0 +1,91 @@
+package pgx
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ReplicationConsumerConfig is the configuration of ReplicationConn.Consume.
+type ReplicationConsumerConfig struct {
+	SlotName        string   // logical replication slot to stream from
+	PluginArguments []string // passed to StartReplication
+
+	// Checkpoints loads the position to resume from and records the position
+	// of each processed message.
+	Checkpoints CheckpointStore
+
+	// StatusInterval is how often standby status updates are sent. See
+	// ReplicationStreamOptions.
+	StatusInterval time.Duration
+
+	// HandlerSavesCheckpoints is set when Handler saves the checkpoint of
+	// each message itself, for example with TableCheckpointStore.SaveIn in
+	// the transaction of its side effects. Otherwise Consume saves the
+	// checkpoint after Handler returns.
+	HandlerSavesCheckpoints bool
+
+	// Handler processes each WalMessage. The checkpoint of msg is
+	// msg.WalStart. When Handler returns an error Consume stops without
+	// saving the checkpoint of msg.
+	Handler func(ctx context.Context, msg *WalMessage) error
+}
+
+// Consume streams a logical replication slot to config.Handler until ctx is
+// canceled or an error occurs. It resumes from the position loaded from
+// config.Checkpoints and reports each processed position to the server as
+// flushed so the slot advances only past what is durably recorded.
+//
+// Delivery is at least once. The checkpoint is saved after each message,
+// including messages in the middle of a transaction, but the server always
+// decodes whole transactions. After a restart every transaction that was not
+// completely processed is sent again from its beginning, so Handler must be
+// idempotent, for example by ignoring changes of transactions it has
+// already applied.
+//
+// Consume always returns a non-nil error. It returns the context error when
+// ctx is canceled.
+func (rc *ReplicationConn) Consume(ctx context.Context, config ReplicationConsumerConfig) error {
+	if config.Checkpoints == nil {
+		return errors.New("ReplicationConsumerConfig Checkpoints is required")
+	}
+	if config.Handler == nil {
+		return errors.New("ReplicationConsumerConfig Handler is required")
+	}
+
+	startLsn, err := config.Checkpoints.Load()
+	if err != nil {
+		return err
+	}
+
+	if err := rc.StartReplication(config.SlotName, startLsn, -1, config.PluginArguments...); err != nil {
+		return err
+	}
+
+	stream := rc.NewReplicationStream(startLsn, &ReplicationStreamOptions{StatusInterval: config.StatusInterval})
+
+	for {
+		msg, err := stream.Next(ctx)
+		if err != nil {
+			return err
+		}
+
+		if msg.WalMessage == nil {
+			continue
+		}
+
+		if err := config.Handler(ctx, msg.WalMessage); err != nil {
+			return err
+		}
+
+		lsn := msg.WalMessage.WalStart
+		if !config.HandlerSavesCheckpoints {
+			if err := config.Checkpoints.Save(lsn); err != nil {
+				return err
+			}
+		}
+
+		stream.Ack(lsn)
+	}
+}
@@ -0,0 +1,143 @@
+package pgx
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// defaultStatusInterval is the same as the default of the server's
+// wal_receiver_status_interval.
+const defaultStatusInterval = 10 * time.Second
+
+// ReplicationStreamOptions are the options of NewReplicationStream.
+type ReplicationStreamOptions struct {
+	// StatusInterval is how often a standby status update is sent while
+	// waiting for replication messages. It must be less than the server's
+	// wal_sender_timeout. The default is 10 seconds.
+	StatusInterval time.Duration
+}
+
+// ReplicationStream reads a started replication stream and keeps the server
+// informed of the progress of the application. It sends a standby status
+// update every StatusInterval and immediately when the server requests a
+// reply in a heartbeat.
+//
+// The write position reported to the server is the end of the most recent
+// WalMessage received. The flush and apply positions are the highest position
+// passed to Ack, which is what allows the server to advance the slot and
+// remove old WAL.
+type ReplicationStream struct {
+	rc       *ReplicationConn
+	interval time.Duration
+
+	received   uint64
+	lastStatus time.Time
+
+	ackMux sync.Mutex
+	acked  uint64
+}
+
+// NewReplicationStream returns a ReplicationStream for a replication started
+// with StartReplication or StartPhysicalReplication at startLsn. startLsn is
+// reported as flushed until the application calls Ack. options may be nil.
+func (rc *ReplicationConn) NewReplicationStream(startLsn uint64, options *ReplicationStreamOptions) *ReplicationStream {
+	s := &ReplicationStream{
+		rc:       rc,
+		interval: defaultStatusInterval,
+		received: startLsn,
+		acked:    startLsn,
+	}
+
+	if options != nil && options.StatusInterval > 0 {
+		s.interval = options.StatusInterval
+	}
+
+	return s
+}
+
+// Next waits for the next WalMessage or ServerHeartbeat, sending standby
+// status updates as they come due. Heartbeats are answered before they are
+// returned so the caller may ignore them.
+//
+// This returns the context error when there is no replication message before
+// the context is canceled. Next must not be called concurrently.
+func (s *ReplicationStream) Next(ctx context.Context) (*ReplicationMessage, error) {
+	for {
+		if time.Since(s.lastStatus) >= s.interval {
+			if err := s.sendStatus(); err != nil {
+				return nil, err
+			}
+		}
+
+		waitCtx, cancel := context.WithDeadline(ctx, s.lastStatus.Add(s.interval))
+		r, err := s.rc.WaitForReplicationMessage(waitCtx)
+		cancel()
+		if err != nil {
+			if err == context.DeadlineExceeded && ctx.Err() == nil {
+				continue
+			}
+			return nil, err
+		}
+
+		if r == nil {
+			continue
+		}
+
+		if r.WalMessage != nil {
+			end := r.WalMessage.WalStart + uint64(len(r.WalMessage.WalData))
+			if end > s.received {
+				s.received = end
+			}
+		}
+
+		if r.ServerHeartbeat != nil && r.ServerHeartbeat.ReplyRequested == 1 {
+			if err := s.sendStatus(); err != nil {
+				return nil, err
+			}
+		}
+
+		return r, nil
+	}
+}
+
+// Ack reports that the application has durably processed all WAL up to lsn.
+// It is reported to the server as the flush and apply position with the next
+// standby status update. Positions lower than a previous Ack are ignored.
+//
+// Ack is safe to call from another goroutine than the one calling Next.
+func (s *ReplicationStream) Ack(lsn uint64) {
+	s.ackMux.Lock()
+	if lsn > s.acked {
+		s.acked = lsn
+	}
+	s.ackMux.Unlock()
+}
+
+// Acked returns the highest position passed to Ack.
+func (s *ReplicationStream) Acked() uint64 {
+	s.ackMux.Lock()
+	defer s.ackMux.Unlock()
+	return s.acked
+}
+
+func (s *ReplicationStream) sendStatus() error {
+	acked := s.Acked()
+
+	received := s.received
+	if acked > received {
+		received = acked
+	}
+
+	status, err := NewStandbyStatus(acked, acked, received)
+	if err != nil {
+		return err
+	}
+
+	if err := s.rc.SendStandbyStatus(status); err != nil {
+		return err
+	}
+
+	s.lastStatus = time.Now()
+	return nil
+}
@@ -1,8 +1,13 @@
 package pgx_test
 
 import (
+	"archive/tar"
 	"context"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strconv"
 	"strings"
@@ -10,6 +15,8 @@ import (
 	"time"
 
 	"github.com/ronaldslc/pgx"
+	"github.com/ronaldslc/pgx/pgmock"
+	"github.com/ronaldslc/pgx/pgproto3"
 )
 
 // This function uses a postgresql 9.6 specific column
@@ -200,101 +207,701 @@ func TestReplicationConn_DropReplicationSlot(t *testing.T) {
 	}
 }
 
-func TestIdentifySystem(t *testing.T) {
+func TestReplicationConnPhysicalReplication(t *testing.T) {
 	if replicationConnConfig == nil {
 		t.Skip("Skipping due to undefined replicationConnConfig")
 	}
 
-	replicationConn2 := mustReplicationConnect(t, *replicationConnConfig)
-	defer closeReplicationConn(t, replicationConn2)
+	conn := mustConnect(t, *replicationConnConfig)
+	defer func() {
+		conn.Exec("select pg_drop_replication_slot('pgx_physical_test')")
+		closeConn(t, conn)
+	}()
 
-	r, err := replicationConn2.IdentifySystem()
+	replicationConn := mustReplicationConnect(t, *replicationConnConfig)
+	defer closeReplicationConn(t, replicationConn)
+
+	err := replicationConn.CreatePhysicalReplicationSlot("pgx_physical_test", true)
+	if err != nil {
+		t.Fatalf("replication slot create failed: %v", err)
+	}
+
+	var restartLsnString string
+	err = conn.QueryRow("select restart_lsn::text from pg_replication_slots where slot_name='pgx_physical_test'").Scan(&restartLsnString)
 	if err != nil {
-		t.Error(err)
+		t.Fatalf("Failed to get restart_lsn: %v", err)
 	}
-	defer r.Close()
-	for _, fd := range r.FieldDescriptions() {
-		t.Logf("Field: %s of type %v", fd.Name, fd.DataType)
+	restartLsn, err := pgx.ParseLSN(restartLsnString)
+	if err != nil {
+		t.Fatalf("Failed to parse restart_lsn: %v", err)
 	}
 
-	var rowCount int
-	for r.Next() {
-		rowCount++
-		values, err := r.Values()
+	err = replicationConn.StartPhysicalReplication("pgx_physical_test", restartLsn, -1)
+	if err != nil {
+		t.Fatalf("Failed to start replication: %v", err)
+	}
+
+	mustExec(t, conn, "create temporary table physical_replication_test (a integer)")
+
+	ctx, cancelFn := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFn()
+
+	for {
+		message, err := replicationConn.WaitForReplicationMessage(ctx)
 		if err != nil {
-			t.Error(err)
+			t.Fatalf("Replication failed: %v", err)
 		}
-		t.Logf("Row values: %v", values)
+
+		if message != nil && message.WalMessage != nil {
+			if message.WalMessage.WalStart < restartLsn {
+				t.Errorf("Expected WAL to start at or after %s, but it started at %s", pgx.FormatLSN(restartLsn), pgx.FormatLSN(message.WalMessage.WalStart))
+			}
+			break
+		}
+	}
+}
+
+func TestReplicationConnPhysicalReplicationTimelineSwitch(t *testing.T) {
+	t.Parallel()
+
+	script := &pgmock.Script{Steps: replicationMockSteps("14.5")}
+	script.Steps = append(script.Steps,
+		pgmock.ExpectMessage(&pgproto3.Query{String: "START_REPLICATION PHYSICAL 0/1000 TIMELINE 1"}),
+		pgmock.SendMessage(&pgproto3.CopyBothResponse{}),
+		pgmock.SendMessage(&pgproto3.CopyDone{}),
+		pgmock.ExpectMessage(&pgproto3.CopyDone{}),
+		pgmock.SendMessage(&pgproto3.RowDescription{Fields: []pgproto3.FieldDescription{
+			{Name: "next_tli", DataTypeOID: 20, DataTypeSize: 8, TypeModifier: 4294967295},
+			{Name: "next_tli_startpos", DataTypeOID: 25, DataTypeSize: -1, TypeModifier: 4294967295},
+		}}),
+		pgmock.SendMessage(&pgproto3.DataRow{Values: [][]byte{[]byte("2"), []byte("0/1800")}}),
+		pgmock.SendMessage(&pgproto3.CommandComplete{CommandTag: "START_STREAMING"}),
+		pgmock.SendMessage(&pgproto3.ReadyForQuery{TxStatus: 'I'}),
+		pgmock.ExpectMessage(&pgproto3.Query{String: "START_REPLICATION PHYSICAL 0/1800 TIMELINE 2"}),
+		pgmock.SendMessage(&pgproto3.CopyBothResponse{}),
+		pgmock.WaitForClose(),
+	)
+
+	replicationConn, errChan := mockReplicationConnect(t, script)
+
+	if err := replicationConn.StartPhysicalReplication("", 0x1000, 1); err != nil {
+		t.Fatalf("Failed to start replication: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := replicationConn.WaitForReplicationMessage(ctx); err != pgx.ErrReplicationStreamEnded {
+		t.Fatalf("Expected ErrReplicationStreamEnded, got %v", err)
 	}
-	if r.Err() != nil {
-		t.Error(r.Err())
+
+	timeline, startLsn, ok := replicationConn.NextTimeline()
+	if !ok || timeline != 2 || startLsn != 0x1800 {
+		t.Fatalf("Expected next timeline 2 at 0/1800, got %v, %d at %s", ok, timeline, pgx.FormatLSN(startLsn))
 	}
 
-	if rowCount == 0 {
-		t.Errorf("Failed to find any rows: %d", rowCount)
+	if err := replicationConn.StartPhysicalReplication("", startLsn, timeline); err != nil {
+		t.Fatalf("Failed to start replication on the next timeline: %v", err)
+	}
+	if _, _, ok := replicationConn.NextTimeline(); ok {
+		t.Error("Expected no next timeline after replication was started again")
+	}
+
+	closeReplicationConn(t, replicationConn)
+	if err := <-errChan; err != nil {
+		t.Errorf("mock server err: %v", err)
 	}
 }
 
-func getCurrentTimeline(t *testing.T, rc *pgx.ReplicationConn) int {
-	r, err := rc.IdentifySystem()
+func TestReplicationConnBaseBackup(t *testing.T) {
+	if replicationConnConfig == nil {
+		t.Skip("Skipping due to undefined replicationConnConfig")
+	}
+
+	replicationConn := mustReplicationConnect(t, *replicationConnConfig)
+	defer closeReplicationConn(t, replicationConn)
+
+	bb, err := replicationConn.BaseBackup(&pgx.BaseBackupOptions{Label: "pgx test", Progress: true, Fast: true, NoWait: true})
 	if err != nil {
-		t.Error(err)
+		t.Fatalf("BaseBackup failed: %v", err)
 	}
-	defer r.Close()
-	for r.Next() {
-		values, e := r.Values()
-		if e != nil {
-			t.Error(e)
+
+	if len(bb.Tablespaces) == 0 {
+		t.Fatal("Expected at least the main data directory tablespace")
+	}
+
+	var foundPGVersion bool
+	var archives int
+	for bb.Next() {
+		archives++
+		ts := bb.Tablespace()
+		if ts.Size < 0 {
+			t.Errorf("Expected tablespace size with Progress, but got %d", ts.Size)
+		}
+
+		tr := tar.NewReader(bb)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("Failed to read tar archive of tablespace %d: %v", ts.OID, err)
+			}
+			if ts.OID == 0 && hdr.Name == "PG_VERSION" {
+				foundPGVersion = true
+			}
 		}
-		return int(values[1].(int32))
 	}
-	t.Fatal("Failed to read timeline")
-	return -1
+	if bb.Err() != nil {
+		t.Fatalf("BaseBackup streaming failed: %v", bb.Err())
+	}
+
+	if archives != len(bb.Tablespaces) {
+		t.Errorf("Expected %d archives, but got %d", len(bb.Tablespaces), archives)
+	}
+	if !foundPGVersion {
+		t.Error("Expected PG_VERSION in the main data directory archive")
+	}
+	if bb.EndLSN < bb.StartLSN {
+		t.Errorf("Expected EndLSN %s to be at or after StartLSN %s", pgx.FormatLSN(bb.EndLSN), pgx.FormatLSN(bb.StartLSN))
+	}
+
+	// The connection is usable after the backup
+	getCurrentTimeline(t, replicationConn)
 }
 
-func TestGetTimelineHistory(t *testing.T) {
+// replicationMockSteps returns the steps of a mock server reporting
+// serverVersion that accepts a replication connection from pgx.
+func replicationMockSteps(serverVersion string) []pgmock.Step {
+	return []pgmock.Step{
+		pgmock.ExpectAnyMessage(&pgproto3.StartupMessage{ProtocolVersion: pgproto3.ProtocolVersionNumber, Parameters: map[string]string{}}),
+		pgmock.SendMessage(&pgproto3.Authentication{Type: pgproto3.AuthTypeOk}),
+		pgmock.SendMessage(&pgproto3.ParameterStatus{Name: "server_version", Value: serverVersion}),
+		pgmock.SendMessage(&pgproto3.BackendKeyData{ProcessID: 0, SecretKey: 0}),
+		pgmock.SendMessage(&pgproto3.ReadyForQuery{TxStatus: 'I'}),
+	}
+}
+
+func mockReplicationConnect(t *testing.T, script *pgmock.Script) (*pgx.ReplicationConn, chan error) {
+	server, err := pgmock.NewServer(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- server.ServeOne()
+		server.Close()
+	}()
+
+	mockConfig, err := pgx.ParseURI(fmt.Sprintf("postgres://pgx_md5:secret@%s/pgx_test?sslmode=disable", server.Addr()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return mustReplicationConnect(t, mockConfig), errChan
+}
+
+func TestReplicationConnBaseBackupTablespaces(t *testing.T) {
+	t.Parallel()
+
+	script := &pgmock.Script{Steps: replicationMockSteps("14.5")}
+	script.Steps = append(script.Steps,
+		pgmock.ExpectMessage(&pgproto3.Query{String: "BASE_BACKUP"}),
+		pgmock.SendMessage(&pgproto3.RowDescription{Fields: []pgproto3.FieldDescription{
+			{Name: "recptr", DataTypeOID: 25, DataTypeSize: -1, TypeModifier: 4294967295},
+			{Name: "tli", DataTypeOID: 20, DataTypeSize: 8, TypeModifier: 4294967295},
+		}}),
+		pgmock.SendMessage(&pgproto3.DataRow{Values: [][]byte{[]byte("0/2000028"), []byte("1")}}),
+		pgmock.SendMessage(&pgproto3.CommandComplete{CommandTag: "SELECT"}),
+		pgmock.SendMessage(&pgproto3.RowDescription{Fields: []pgproto3.FieldDescription{
+			{Name: "spcoid", DataTypeOID: 26, DataTypeSize: 4, TypeModifier: 4294967295},
+			{Name: "spclocation", DataTypeOID: 25, DataTypeSize: -1, TypeModifier: 4294967295},
+			{Name: "size", DataTypeOID: 20, DataTypeSize: 8, TypeModifier: 4294967295},
+		}}),
+		pgmock.SendMessage(&pgproto3.DataRow{Values: [][]byte{[]byte("16385"), []byte("/mnt/ts1"), nil}}),
+		pgmock.SendMessage(&pgproto3.DataRow{Values: [][]byte{[]byte("16386"), []byte("/mnt/ts2"), nil}}),
+		pgmock.SendMessage(&pgproto3.DataRow{Values: [][]byte{nil, nil, nil}}),
+		pgmock.SendMessage(&pgproto3.CommandComplete{CommandTag: "SELECT"}),
+	)
+	for _, data := range []string{"ts1", "ts2", "main"} {
+		script.Steps = append(script.Steps,
+			pgmock.SendMessage(&pgproto3.CopyOutResponse{}),
+			pgmock.SendMessage(&pgproto3.CopyData{Data: []byte(data)}),
+			pgmock.SendMessage(&pgproto3.CopyDone{}),
+		)
+	}
+	script.Steps = append(script.Steps,
+		pgmock.SendMessage(&pgproto3.RowDescription{Fields: []pgproto3.FieldDescription{
+			{Name: "recptr", DataTypeOID: 25, DataTypeSize: -1, TypeModifier: 4294967295},
+			{Name: "tli", DataTypeOID: 20, DataTypeSize: 8, TypeModifier: 4294967295},
+		}}),
+		pgmock.SendMessage(&pgproto3.DataRow{Values: [][]byte{[]byte("0/2000100"), []byte("1")}}),
+		pgmock.SendMessage(&pgproto3.CommandComplete{CommandTag: "SELECT"}),
+		pgmock.SendMessage(&pgproto3.ReadyForQuery{TxStatus: 'I'}),
+		pgmock.ExpectMessage(&pgproto3.Terminate{}),
+	)
+
+	replicationConn, errChan := mockReplicationConnect(t, script)
+
+	bb, err := replicationConn.BaseBackup(nil)
+	if err != nil {
+		t.Fatalf("BaseBackup failed: %v", err)
+	}
+
+	expected := []pgx.BaseBackupTablespace{
+		{OID: 16385, Location: "/mnt/ts1", Size: -1},
+		{OID: 16386, Location: "/mnt/ts2", Size: -1},
+		{OID: 0, Location: "", Size: -1},
+	}
+	if !reflect.DeepEqual(bb.Tablespaces, expected) {
+		t.Errorf("Expected Tablespaces %v, got %v", expected, bb.Tablespaces)
+	}
+
+	var archives []string
+	for bb.Next() {
+		buf, err := ioutil.ReadAll(bb)
+		if err != nil {
+			t.Fatal(err)
+		}
+		archives = append(archives, fmt.Sprintf("%s:%s", bb.Tablespace().Location, buf))
+	}
+	if bb.Err() != nil {
+		t.Fatalf("BaseBackup streaming failed: %v", bb.Err())
+	}
+
+	expectedArchives := []string{"/mnt/ts1:ts1", "/mnt/ts2:ts2", ":main"}
+	if !reflect.DeepEqual(archives, expectedArchives) {
+		t.Errorf("Expected archives %v, got %v", expectedArchives, archives)
+	}
+	if bb.EndLSN != 0x2000100 {
+		t.Errorf("Expected EndLSN 0/2000100, got %s", pgx.FormatLSN(bb.EndLSN))
+	}
+
+	closeReplicationConn(t, replicationConn)
+	if err := <-errChan; err != nil {
+		t.Errorf("mock server err: %v", err)
+	}
+}
+
+func TestReplicationConnBaseBackupCopyStream(t *testing.T) {
+	t.Parallel()
+
+	progress := []byte{'p', 0, 0, 0, 0, 0, 0, 0x10, 0}
+
+	script := &pgmock.Script{Steps: replicationMockSteps("15.2")}
+	script.Steps = append(script.Steps,
+		pgmock.ExpectMessage(&pgproto3.Query{String: "BASE_BACKUP (LABEL 'pgx', PROGRESS, MANIFEST 'yes')"}),
+		pgmock.SendMessage(&pgproto3.RowDescription{Fields: []pgproto3.FieldDescription{
+			{Name: "recptr", DataTypeOID: 25, DataTypeSize: -1, TypeModifier: 4294967295},
+			{Name: "tli", DataTypeOID: 20, DataTypeSize: 8, TypeModifier: 4294967295},
+		}}),
+		pgmock.SendMessage(&pgproto3.DataRow{Values: [][]byte{[]byte("0/2000028"), []byte("1")}}),
+		pgmock.SendMessage(&pgproto3.CommandComplete{CommandTag: "SELECT"}),
+		pgmock.SendMessage(&pgproto3.RowDescription{Fields: []pgproto3.FieldDescription{
+			{Name: "spcoid", DataTypeOID: 26, DataTypeSize: 4, TypeModifier: 4294967295},
+			{Name: "spclocation", DataTypeOID: 25, DataTypeSize: -1, TypeModifier: 4294967295},
+			{Name: "size", DataTypeOID: 20, DataTypeSize: 8, TypeModifier: 4294967295},
+		}}),
+		pgmock.SendMessage(&pgproto3.DataRow{Values: [][]byte{[]byte("16385"), []byte("/mnt/ts1"), []byte("8")}}),
+		pgmock.SendMessage(&pgproto3.DataRow{Values: [][]byte{nil, nil, []byte("16")}}),
+		pgmock.SendMessage(&pgproto3.CommandComplete{CommandTag: "SELECT"}),
+		pgmock.SendMessage(&pgproto3.CopyOutResponse{}),
+		pgmock.SendMessage(&pgproto3.CopyData{Data: []byte("n16385.tar\x00/mnt/ts1\x00")}),
+		pgmock.SendMessage(&pgproto3.CopyData{Data: []byte("dts1")}),
+		pgmock.SendMessage(&pgproto3.CopyData{Data: []byte("nbase.tar\x00\x00")}),
+		pgmock.SendMessage(&pgproto3.CopyData{Data: []byte("dma")}),
+		pgmock.SendMessage(&pgproto3.CopyData{Data: progress}),
+		pgmock.SendMessage(&pgproto3.CopyData{Data: []byte("din")}),
+		pgmock.SendMessage(&pgproto3.CopyData{Data: []byte("m")}),
+		pgmock.SendMessage(&pgproto3.CopyData{Data: []byte("d{}")}),
+		pgmock.SendMessage(&pgproto3.CopyDone{}),
+		pgmock.SendMessage(&pgproto3.RowDescription{Fields: []pgproto3.FieldDescription{
+			{Name: "recptr", DataTypeOID: 25, DataTypeSize: -1, TypeModifier: 4294967295},
+			{Name: "tli", DataTypeOID: 20, DataTypeSize: 8, TypeModifier: 4294967295},
+		}}),
+		pgmock.SendMessage(&pgproto3.DataRow{Values: [][]byte{[]byte("0/2000100"), []byte("1")}}),
+		pgmock.SendMessage(&pgproto3.CommandComplete{CommandTag: "SELECT"}),
+		pgmock.SendMessage(&pgproto3.ReadyForQuery{TxStatus: 'I'}),
+		pgmock.ExpectMessage(&pgproto3.Terminate{}),
+	)
+
+	replicationConn, errChan := mockReplicationConnect(t, script)
+
+	bb, err := replicationConn.BaseBackup(&pgx.BaseBackupOptions{Label: "pgx", Progress: true, Manifest: true})
+	if err != nil {
+		t.Fatalf("BaseBackup failed: %v", err)
+	}
+
+	var archives []string
+	for bb.Next() {
+		buf, err := ioutil.ReadAll(bb)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bb.IsManifest() {
+			archives = append(archives, fmt.Sprintf("manifest:%s", buf))
+		} else {
+			archives = append(archives, fmt.Sprintf("%s:%s", bb.Tablespace().Location, buf))
+		}
+	}
+	if bb.Err() != nil {
+		t.Fatalf("BaseBackup streaming failed: %v", bb.Err())
+	}
+
+	expectedArchives := []string{"/mnt/ts1:ts1", ":main", "manifest:{}"}
+	if !reflect.DeepEqual(archives, expectedArchives) {
+		t.Errorf("Expected archives %v, got %v", expectedArchives, archives)
+	}
+	if bb.Progress != 0x1000 {
+		t.Errorf("Expected Progress 4096, got %d", bb.Progress)
+	}
+	if bb.EndLSN != 0x2000100 {
+		t.Errorf("Expected EndLSN 0/2000100, got %s", pgx.FormatLSN(bb.EndLSN))
+	}
+
+	closeReplicationConn(t, replicationConn)
+	if err := <-errChan; err != nil {
+		t.Errorf("mock server err: %v", err)
+	}
+}
+
+func TestReplicationConnCreateReplicationSlotEx(t *testing.T) {
 	if replicationConnConfig == nil {
 		t.Skip("Skipping due to undefined replicationConnConfig")
 	}
 
+	conn := mustConnect(t, *replicationConnConfig)
+	defer closeConn(t, conn)
+
 	replicationConn := mustReplicationConnect(t, *replicationConnConfig)
 	defer closeReplicationConn(t, replicationConn)
 
-	timeline := getCurrentTimeline(t, replicationConn)
+	result, err := replicationConn.CreateReplicationSlotEx("pgx_slot_ex_test", "test_decoding", &pgx.ReplicationSlotOptions{
+		Temporary:      true,
+		SnapshotAction: pgx.SnapshotActionExport,
+	})
+	if err != nil {
+		t.Fatalf("replication slot create failed: %v", err)
+	}
+
+	if result.SlotName != "pgx_slot_ex_test" {
+		t.Errorf("Expected slot name pgx_slot_ex_test, but got %s", result.SlotName)
+	}
+	if result.ConsistentPoint == 0 {
+		t.Error("Expected consistent point to be set")
+	}
+	if result.OutputPlugin != "test_decoding" {
+		t.Errorf("Expected output plugin test_decoding, but got %s", result.OutputPlugin)
+	}
+	if result.SnapshotName == "" {
+		t.Fatal("Expected exported snapshot name")
+	}
+
+	// The exported snapshot can be imported until the next command on the
+	// replication connection
+	tx, err := conn.BeginEx(context.Background(), &pgx.TxOptions{IsoLevel: pgx.RepeatableRead})
+	if err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(fmt.Sprintf("set transaction snapshot '%s'", result.SnapshotName)); err != nil {
+		t.Fatalf("Failed to import snapshot: %v", err)
+	}
+
+	result, err = replicationConn.CreateReplicationSlotEx("pgx_physical_ex_test", "", &pgx.ReplicationSlotOptions{Temporary: true, ReserveWAL: true})
+	if err != nil {
+		t.Fatalf("physical replication slot create failed: %v", err)
+	}
+	if result.SlotName != "pgx_physical_ex_test" || result.OutputPlugin != "" || result.SnapshotName != "" {
+		t.Errorf("Unexpected physical slot result: %#v", result)
+	}
+}
+
+func TestReplicationStreamAck(t *testing.T) {
+	if replicationConnConfig == nil {
+		t.Skip("Skipping due to undefined replicationConnConfig")
+	}
 
-	r, err := replicationConn.TimelineHistory(timeline)
+	conn := mustConnect(t, *replicationConnConfig)
+	defer func() {
+		conn.Exec("select pg_drop_replication_slot('pgx_stream_test')")
+		closeConn(t, conn)
+	}()
+
+	replicationConn := mustReplicationConnect(t, *replicationConnConfig)
+	defer closeReplicationConn(t, replicationConn)
+
+	slot, err := replicationConn.CreateReplicationSlotEx("pgx_stream_test", "test_decoding", nil)
 	if err != nil {
-		t.Errorf("%#v", err)
+		t.Fatalf("replication slot create failed: %v", err)
 	}
-	defer r.Close()
 
-	for _, fd := range r.FieldDescriptions() {
-		t.Logf("Field: %s of type %v", fd.Name, fd.DataType)
+	err = replicationConn.StartReplication("pgx_stream_test", 0, -1)
+	if err != nil {
+		t.Fatalf("Failed to start replication: %v", err)
 	}
 
-	var rowCount int
-	for r.Next() {
-		rowCount++
-		values, err := r.Values()
+	stream := replicationConn.NewReplicationStream(slot.ConsistentPoint, &pgx.ReplicationStreamOptions{StatusInterval: 100 * time.Millisecond})
+
+	mustExec(t, conn, "create temporary table replication_stream_test (a integer)")
+	mustExec(t, conn, "insert into replication_stream_test(a) values (1)")
+
+	ctx, cancelFn := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFn()
+
+	var ackedLsn uint64
+	for ackedLsn == 0 {
+		message, err := stream.Next(ctx)
+		if err != nil {
+			t.Fatalf("Replication failed: %v", err)
+		}
+
+		if message.WalMessage != nil && strings.HasPrefix(string(message.WalMessage.WalData), "COMMIT") {
+			ackedLsn = message.WalMessage.WalStart
+			stream.Ack(ackedLsn)
+		}
+	}
+
+	if stream.Acked() != ackedLsn {
+		t.Errorf("Expected Acked to be %s, but it was %s", pgx.FormatLSN(ackedLsn), pgx.FormatLSN(stream.Acked()))
+	}
+
+	// Keep the stream running so a status update is sent after the Ack
+	for {
+		waitCtx, waitCancelFn := context.WithTimeout(ctx, 300*time.Millisecond)
+		_, err := stream.Next(waitCtx)
+		waitCancelFn()
+		if err == context.DeadlineExceeded {
+			break
+		}
 		if err != nil {
-			t.Error(err)
+			t.Fatalf("Replication failed: %v", err)
 		}
-		t.Logf("Row values: %v", values)
 	}
-	if r.Err() != nil {
-		if strings.Contains(r.Err().Error(), "No such file or directory") {
+
+	confirmedLsn, err := pgx.ParseLSN(getConfirmedFlushLsnFor(t, conn, "pgx_stream_test"))
+	if err != nil {
+		t.Fatalf("Failed to parse confirmed_flush_lsn: %v", err)
+	}
+	if confirmedLsn < ackedLsn {
+		t.Errorf("Expected confirmed_flush_lsn to be at least %s, but it was %s", pgx.FormatLSN(ackedLsn), pgx.FormatLSN(confirmedLsn))
+	}
+}
+
+// rawBackendMessage is sent by a pgmock script as is. It can be part of a
+// message to have the message arrive in pieces.
+type rawBackendMessage []byte
+
+func (rawBackendMessage) Backend()                   {}
+func (rawBackendMessage) Decode(data []byte) error   { return nil }
+func (m rawBackendMessage) Encode(dst []byte) []byte { return append(dst, m...) }
+
+type sleepStep time.Duration
+
+func (s sleepStep) Step(*pgproto3.Backend) error {
+	time.Sleep(time.Duration(s))
+	return nil
+}
+
+func TestReplicationStreamStatusDuringPartialMessage(t *testing.T) {
+	t.Parallel()
+
+	walData := []byte{'w'}
+	walData = append(walData, 0, 0, 0, 0, 0, 0, 0x10, 0) // WAL start
+	walData = append(walData, 0, 0, 0, 0, 0, 0, 0x10, 5) // server WAL end
+	walData = append(walData, 0, 0, 0, 0, 0, 0, 0, 0)    // server time
+	walData = append(walData, "hello"...)
+	buf := (&pgproto3.CopyData{Data: walData}).Encode(nil)
+
+	// The message arrives in two pieces with several status intervals between
+	// them. Sending the status updates must not lose the first piece.
+	script := &pgmock.Script{Steps: replicationMockSteps("14.5")}
+	script.Steps = append(script.Steps,
+		pgmock.ExpectMessage(&pgproto3.Query{String: "START_REPLICATION PHYSICAL 0/1000"}),
+		pgmock.SendMessage(&pgproto3.CopyBothResponse{}),
+		sleepStep(100*time.Millisecond),
+		pgmock.SendMessage(rawBackendMessage(buf[:12])),
+		sleepStep(200*time.Millisecond),
+		pgmock.SendMessage(rawBackendMessage(buf[12:])),
+		pgmock.WaitForClose(),
+	)
+
+	replicationConn, errChan := mockReplicationConnect(t, script)
+
+	if err := replicationConn.StartPhysicalReplication("", 0x1000, -1); err != nil {
+		t.Fatalf("Failed to start replication: %v", err)
+	}
+
+	stream := replicationConn.NewReplicationStream(0x1000, &pgx.ReplicationStreamOptions{StatusInterval: 50 * time.Millisecond})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	r, err := stream.Next(ctx)
+	if err != nil {
+		t.Fatalf("Next failed: %v", err)
+	}
+	if r.WalMessage == nil || r.WalMessage.WalStart != 0x1000 || string(r.WalMessage.WalData) != "hello" {
+		t.Errorf("Expected WAL message at 0/1000 with hello, got %v", r)
+	}
+
+	closeReplicationConn(t, replicationConn)
+	if err := <-errChan; err != nil {
+		t.Errorf("mock server err: %v", err)
+	}
+}
+
+func TestReplicationConnConsume(t *testing.T) {
+	if replicationConnConfig == nil {
+		t.Skip("Skipping due to undefined replicationConnConfig")
+	}
+
+	conn := mustConnect(t, *replicationConnConfig)
+	defer func() {
+		conn.Exec("select pg_drop_replication_slot('pgx_consume_test')")
+		closeConn(t, conn)
+	}()
+
+	replicationConn := mustReplicationConnect(t, *replicationConnConfig)
+	defer closeReplicationConn(t, replicationConn)
+
+	if err := replicationConn.CreateReplicationSlot("pgx_consume_test", "test_decoding"); err != nil {
+		t.Fatalf("replication slot create failed: %v", err)
+	}
+
+	dir, err := ioutil.TempDir("", "pgx_consume")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store := pgx.NewFileCheckpointStore(filepath.Join(dir, "checkpoint"))
+
+	mustExec(t, conn, "create temporary table replication_consume_test (a integer)")
+	mustExec(t, conn, "insert into replication_consume_test(a) values (1)")
+
+	ctx, cancelFn := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFn()
+
+	var commitLsn uint64
+	err = replicationConn.Consume(ctx, pgx.ReplicationConsumerConfig{
+		SlotName:       "pgx_consume_test",
+		Checkpoints:    store,
+		StatusInterval: 100 * time.Millisecond,
+		Handler: func(ctx context.Context, msg *pgx.WalMessage) error {
+			if strings.HasPrefix(string(msg.WalData), "COMMIT") {
+				commitLsn = msg.WalStart
+				cancelFn()
+			}
+			return nil
+		},
+	})
+	if err != context.Canceled {
+		t.Fatalf("Expected context.Canceled, but got %v", err)
+	}
+	if commitLsn == 0 {
+		t.Fatal("Expected to consume the commit")
+	}
+
+	lsn, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if lsn != commitLsn {
+		t.Errorf("Expected checkpoint %s, but got %s", pgx.FormatLSN(commitLsn), pgx.FormatLSN(lsn))
+	}
+}
+
+func TestIdentifySystem(t *testing.T) {
+	if replicationConnConfig == nil {
+		t.Skip("Skipping due to undefined replicationConnConfig")
+	}
+
+	replicationConn2 := mustReplicationConnect(t, *replicationConnConfig)
+	defer closeReplicationConn(t, replicationConn2)
+
+	r, err := replicationConn2.IdentifySystem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("System: %#v", r)
+
+	if r.SystemID == "" {
+		t.Error("Expected SystemID to be set")
+	}
+	if r.Timeline < 1 {
+		t.Errorf("Expected Timeline to be at least 1, but it was %d", r.Timeline)
+	}
+	if r.XLogPos == 0 {
+		t.Error("Expected XLogPos to be set")
+	}
+	if r.DBName != replicationConnConfig.Database {
+		t.Errorf("Expected DBName to be %s, but it was %s", replicationConnConfig.Database, r.DBName)
+	}
+}
+
+func getCurrentTimeline(t *testing.T, rc *pgx.ReplicationConn) int {
+	r, err := rc.IdentifySystem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return int(r.Timeline)
+}
+
+func TestGetTimelineHistory(t *testing.T) {
+	if replicationConnConfig == nil {
+		t.Skip("Skipping due to undefined replicationConnConfig")
+	}
+
+	replicationConn := mustReplicationConnect(t, *replicationConnConfig)
+	defer closeReplicationConn(t, replicationConn)
+
+	timeline := getCurrentTimeline(t, replicationConn)
+
+	h, err := replicationConn.TimelineHistory(timeline)
+	if err != nil {
+		if strings.Contains(err.Error(), "No such file or directory") {
 			// This is normal, this means the timeline we're on has no
 			// history, which is the common case in a test db that
 			// has only one timeline
 			return
 		}
-		t.Error(r.Err())
+		t.Fatalf("%#v", err)
+	}
+	t.Logf("History: %#v", h)
+
+	// If we have a timeline history (see above) it records the switch
+	// from each previous timeline
+	if len(h.Entries) == 0 {
+		t.Errorf("Failed to find any history entries in %q", h.Content)
+	}
+}
+
+func TestParseTimelineHistory(t *testing.T) {
+	content := []byte("1\t0/3000098\tno recovery target specified\n\n" +
+		"# comment\n" +
+		"2\t0/5000000\tat restore point \"before_upgrade\"\n")
+
+	entries, err := pgx.ParseTimelineHistory(content)
+	if err != nil {
+		t.Fatal(err)
 	}
 
-	// If we have a timeline history (see above) there should have been
-	// rows emitted
-	if rowCount == 0 {
-		t.Errorf("Failed to find any rows: %d", rowCount)
+	expected := []pgx.TimelineHistoryEntry{
+		{Timeline: 1, SwitchPoint: 0x3000098, Reason: "no recovery target specified"},
+		{Timeline: 2, SwitchPoint: 0x5000000, Reason: `at restore point "before_upgrade"`},
+	}
+	if !reflect.DeepEqual(entries, expected) {
+		t.Errorf("Expected %#v, but got %#v", expected, entries)
+	}
+
+	if _, err := pgx.ParseTimelineHistory([]byte("x\t0/0\treason\n")); err == nil {
+		t.Error("Expected error for invalid timeline")
+	}
+	if _, err := pgx.ParseTimelineHistory([]byte("1\tnot an lsn\treason\n")); err == nil {
+		t.Error("Expected error for invalid switchpoint")
 	}
 }
 
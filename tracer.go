@@ -0,0 +1,101 @@
+package pgx
+
+import (
+	"context"
+)
+
+// Tracer traces the queries of a connection. It is set with
+// ConnConfig.Tracer. Each TraceQueryStart is followed by exactly one
+// TraceQueryEnd, which receives the context returned by TraceQueryStart. This
+// allows a span to be started in TraceQueryStart, stored in the returned
+// context and finished in TraceQueryEnd.
+//
+// Query and Exec are traced, including their Ex variants, QueryRow and the
+// methods of Tx and ConnPool that delegate to them. A Query ends when its Rows
+// are closed.
+//
+// A Tracer can also implement BatchTracer, CopyFromTracer, PrepareTracer and
+// ConnectTracer to trace those operations.
+//
+// The methods of a Tracer are called while the connection is in use and must
+// not call any of its methods.
+type Tracer interface {
+	TraceQueryStart(ctx context.Context, conn *Conn, data TraceQueryStartData) context.Context
+	TraceQueryEnd(ctx context.Context, conn *Conn, data TraceQueryEndData)
+}
+
+type TraceQueryStartData struct {
+	SQL  string
+	Args []interface{}
+}
+
+type TraceQueryEndData struct {
+	CommandTag CommandTag // set for Exec
+	RowCount   int        // number of rows read for Query
+	Err        error
+}
+
+// BatchTracer traces batches from Batch.Send until Batch.Close. See Tracer.
+type BatchTracer interface {
+	TraceBatchStart(ctx context.Context, conn *Conn, data TraceBatchStartData) context.Context
+	TraceBatchEnd(ctx context.Context, conn *Conn, data TraceBatchEndData)
+}
+
+type TraceBatchStartData struct {
+	Queries []TraceQueryStartData
+}
+
+type TraceBatchEndData struct {
+	Err error
+}
+
+// CopyFromTracer traces CopyFrom, CopyFromEx and CopyFromReader. See Tracer.
+type CopyFromTracer interface {
+	TraceCopyFromStart(ctx context.Context, conn *Conn, data TraceCopyFromStartData) context.Context
+	TraceCopyFromEnd(ctx context.Context, conn *Conn, data TraceCopyFromEndData)
+}
+
+type TraceCopyFromStartData struct {
+	TableName   Identifier // set for CopyFrom and CopyFromEx
+	ColumnNames []string   // set for CopyFrom and CopyFromEx
+	SQL         string     // set for CopyFromReader
+}
+
+type TraceCopyFromEndData struct {
+	RowCount int
+	Err      error
+}
+
+// PrepareTracer traces Prepare and PrepareEx. Statements prepared implicitly
+// by Query and Exec are part of the traced query. See Tracer.
+type PrepareTracer interface {
+	TracePrepareStart(ctx context.Context, conn *Conn, data TracePrepareStartData) context.Context
+	TracePrepareEnd(ctx context.Context, conn *Conn, data TracePrepareEndData)
+}
+
+type TracePrepareStartData struct {
+	Name string
+	SQL  string
+}
+
+type TracePrepareEndData struct {
+	Err error
+}
+
+// ConnectTracer traces establishing a connection with Connect or by a
+// ConnPool. TraceConnectStart receives the context passed to ConnectEx or to
+// the ConnPool method that established the connection, such as AcquireEx or
+// QueryEx, or context.Background(). See Tracer.
+type ConnectTracer interface {
+	TraceConnectStart(ctx context.Context, data TraceConnectStartData) context.Context
+	TraceConnectEnd(ctx context.Context, data TraceConnectEndData)
+}
+
+type TraceConnectStartData struct {
+	ConnConfig *ConnConfig
+}
+
+type TraceConnectEndData struct {
+	Conn *Conn // nil if Err is set
+	Err  error
+}
@@ -0,0 +1,219 @@
+package pgx_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/ronaldslc/pgx"
+	"github.com/ronaldslc/pgx/pgmock"
+)
+
+type traceCtxKey struct{}
+
+// testTracer records the trace events it receives. Each start stores the name
+// of the event in the context and the matching end verifies it receives that
+// context.
+type testTracer struct {
+	t      *testing.T
+	events []string
+
+	queryEnd    pgx.TraceQueryEndData
+	copyFromEnd pgx.TraceCopyFromEndData
+
+	connectCtx context.Context // context received by TraceConnectStart
+}
+
+func (tt *testTracer) start(ctx context.Context, event string) context.Context {
+	tt.events = append(tt.events, event+" start")
+	return context.WithValue(ctx, traceCtxKey{}, event)
+}
+
+func (tt *testTracer) end(ctx context.Context, event string) {
+	if v := ctx.Value(traceCtxKey{}); v != event {
+		tt.t.Errorf("%s end received context of %v", event, v)
+	}
+	tt.events = append(tt.events, event+" end")
+}
+
+func (tt *testTracer) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return tt.start(ctx, "query "+data.SQL)
+}
+
+func (tt *testTracer) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	tt.queryEnd = data
+	tt.end(ctx, ctx.Value(traceCtxKey{}).(string))
+}
+
+func (tt *testTracer) TraceBatchStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	return tt.start(ctx, "batch")
+}
+
+func (tt *testTracer) TraceBatchEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchEndData) {
+	tt.end(ctx, "batch")
+}
+
+func (tt *testTracer) TraceCopyFromStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceCopyFromStartData) context.Context {
+	return tt.start(ctx, "copy "+data.TableName.Sanitize())
+}
+
+func (tt *testTracer) TraceCopyFromEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceCopyFromEndData) {
+	tt.copyFromEnd = data
+	tt.end(ctx, ctx.Value(traceCtxKey{}).(string))
+}
+
+func (tt *testTracer) TracePrepareStart(ctx context.Context, conn *pgx.Conn, data pgx.TracePrepareStartData) context.Context {
+	return tt.start(ctx, "prepare "+data.Name)
+}
+
+func (tt *testTracer) TracePrepareEnd(ctx context.Context, conn *pgx.Conn, data pgx.TracePrepareEndData) {
+	tt.end(ctx, ctx.Value(traceCtxKey{}).(string))
+}
+
+func (tt *testTracer) TraceConnectStart(ctx context.Context, data pgx.TraceConnectStartData) context.Context {
+	tt.connectCtx = ctx
+	return tt.start(ctx, "connect")
+}
+
+func (tt *testTracer) TraceConnectEnd(ctx context.Context, data pgx.TraceConnectEndData) {
+	if data.Err != nil || data.Conn == nil {
+		tt.t.Errorf("connect failed: %v", data.Err)
+	}
+	tt.end(ctx, "connect")
+}
+
+func (tt *testTracer) takeEvents() []string {
+	events := tt.events
+	tt.events = nil
+	return events
+}
+
+func expectTraceEvents(t *testing.T, tt *testTracer, expected ...string) {
+	events := tt.takeEvents()
+	if len(events) != len(expected) {
+		t.Fatalf("Expected events %v, got %v", expected, events)
+	}
+	for i := range expected {
+		if events[i] != expected[i] {
+			t.Fatalf("Expected events %v, got %v", expected, events)
+		}
+	}
+}
+
+func TestTracer(t *testing.T) {
+	t.Parallel()
+
+	tt := &testTracer{t: t}
+	config := *defaultConnConfig
+	config.Tracer = tt
+
+	conn := mustConnect(t, config)
+	defer closeConn(t, conn)
+	expectTraceEvents(t, tt, "connect start", "connect end")
+
+	mustExec(t, conn, "create temporary table foo(a int4)")
+	expectTraceEvents(t, tt, "query create temporary table foo(a int4) start", "query create temporary table foo(a int4) end")
+	if tt.queryEnd.CommandTag != "CREATE TABLE" {
+		t.Errorf("Expected CommandTag CREATE TABLE, got %v", tt.queryEnd.CommandTag)
+	}
+
+	rows, err := conn.Query("select generate_series(1, 3)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectTraceEvents(t, tt, "query select generate_series(1, 3) start")
+	for rows.Next() {
+	}
+	rows.Close()
+	expectTraceEvents(t, tt, "query select generate_series(1, 3) end")
+	if tt.queryEnd.RowCount != 3 {
+		t.Errorf("Expected RowCount 3, got %d", tt.queryEnd.RowCount)
+	}
+
+	if _, err := conn.Prepare("ps", "select 1"); err != nil {
+		t.Fatal(err)
+	}
+	expectTraceEvents(t, tt, "prepare ps start", "prepare ps end")
+
+	copyCount, err := conn.CopyFrom(pgx.Identifier{"foo"}, []string{"a"}, pgx.CopyFromRows([][]interface{}{{int32(1)}, {int32(2)}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if copyCount != 2 {
+		t.Fatalf("Expected 2 rows copied, got %d", copyCount)
+	}
+	expectTraceEvents(t, tt, `copy "foo" start`, `copy "foo" end`)
+	if tt.copyFromEnd.RowCount != 2 {
+		t.Errorf("Expected RowCount 2, got %d", tt.copyFromEnd.RowCount)
+	}
+
+	batch := conn.BeginBatch()
+	batch.Queue("select 1", nil, nil, nil)
+	if err := batch.Send(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := batch.ExecResults(); err != nil {
+		t.Fatal(err)
+	}
+	if err := batch.Close(); err != nil {
+		t.Fatal(err)
+	}
+	expectTraceEvents(t, tt, "batch start", "batch end")
+
+	_, err = conn.Exec("select 1/0")
+	if err == nil {
+		t.Fatal("Expected error")
+	}
+	expectTraceEvents(t, tt, "query select 1/0 start", "query select 1/0 end")
+	if tt.queryEnd.Err != err {
+		t.Errorf("Expected trace Err %v, got %v", err, tt.queryEnd.Err)
+	}
+
+	ensureConnValid(t, conn)
+}
+
+func TestTracerConnectEx(t *testing.T) {
+	t.Parallel()
+
+	script := &pgmock.Script{
+		Steps: pgmock.AcceptUnauthenticatedConnRequestSteps(),
+	}
+	script.Steps = append(script.Steps, pgmock.PgxInitSteps()...)
+	script.Steps = append(script.Steps, pgmock.WaitForClose())
+
+	server, err := pgmock.NewServer(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- server.ServeOne()
+	}()
+
+	config, err := pgx.ParseURI(fmt.Sprintf("postgres://pgx_md5:secret@%s/pgx_test?sslmode=disable", server.Addr()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tt := &testTracer{t: t}
+	config.Tracer = tt
+
+	type requestKey struct{}
+	ctx := context.WithValue(context.Background(), requestKey{}, "request")
+
+	conn, err := pgx.ConnectEx(ctx, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tt.connectCtx == nil || tt.connectCtx.Value(requestKey{}) != "request" {
+		t.Errorf("Expected TraceConnectStart to receive the context passed to ConnectEx, got %v", tt.connectCtx)
+	}
+
+	if err := conn.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errChan; err != nil {
+		t.Fatalf("mock server err: %v", err)
+	}
+}
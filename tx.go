@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"time"
 
 	"github.com/pkg/errors"
@@ -241,6 +242,42 @@ func (tx *Tx) CopyFrom(tableName Identifier, columnNames []string, rowSrc CopyFr
 	return tx.conn.CopyFrom(tableName, columnNames, rowSrc)
 }
 
+// CopyFromEx delegates to the underlying *Conn
+func (tx *Tx) CopyFromEx(ctx context.Context, tableName Identifier, columnNames []string, rowSrc CopyFromSource, options *CopyFromOptions) (int, error) {
+	if tx.status != TxStatusInProgress {
+		return 0, ErrTxClosed
+	}
+
+	return tx.conn.CopyFromEx(ctx, tableName, columnNames, rowSrc, options)
+}
+
+// CopyFromReader delegates to the underlying *Conn
+func (tx *Tx) CopyFromReader(ctx context.Context, r io.Reader, sql string) (int, error) {
+	if tx.status != TxStatusInProgress {
+		return 0, ErrTxClosed
+	}
+
+	return tx.conn.CopyFromReader(ctx, r, sql)
+}
+
+// UpsertFrom delegates to the underlying *Conn
+func (tx *Tx) UpsertFrom(ctx context.Context, tableName Identifier, columnNames []string, rowSrc CopyFromSource, options *UpsertOptions) (*UpsertResult, error) {
+	if tx.status != TxStatusInProgress {
+		return nil, ErrTxClosed
+	}
+
+	return tx.conn.UpsertFrom(ctx, tableName, columnNames, rowSrc, options)
+}
+
+// FunctionCall delegates to the underlying *Conn
+func (tx *Tx) FunctionCall(ctx context.Context, fn interface{}, args ...interface{}) *FunctionResult {
+	if tx.status != TxStatusInProgress {
+		return &FunctionResult{conn: tx.conn, err: ErrTxClosed}
+	}
+
+	return tx.conn.FunctionCall(ctx, fn, args...)
+}
+
 // Status returns the status of the transaction from the set of
 // pgx.TxStatus* constants.
 func (tx *Tx) Status() int8 {
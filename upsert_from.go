@@ -0,0 +1,169 @@
+package pgx
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// upsertStagingTableName is the name of the temporary table rows are copied
+// into before being merged into the target table.
+const upsertStagingTableName = "pgx_upsert_staging"
+
+// UpsertOptions contains the settings for UpsertFrom.
+type UpsertOptions struct {
+	// ConflictColumns are the columns of the unique index or constraint used to
+	// detect conflicting rows. They are required unless DoNothing is set.
+	ConflictColumns []string
+
+	// UpdateColumns are the columns that are overwritten when a row conflicts.
+	// If empty all copied columns except ConflictColumns are updated.
+	UpdateColumns []string
+
+	// DoNothing skips conflicting rows instead of updating them.
+	DoNothing bool
+
+	// CopyFromOptions are passed through to CopyFromEx when copying into the
+	// staging table. It may be nil.
+	CopyFromOptions *CopyFromOptions
+}
+
+// UpsertResult is the result of an UpsertFrom.
+type UpsertResult struct {
+	Copied   int64 // rows read from the CopyFromSource
+	Inserted int64 // rows inserted into the target table
+	Updated  int64 // existing rows updated in the target table
+}
+
+func (opts *UpsertOptions) conflictSQL(columnNames []string) (string, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteString("on conflict")
+
+	if len(opts.ConflictColumns) > 0 {
+		buf.WriteString(" (")
+		buf.WriteString(sanitizeColumnList(opts.ConflictColumns))
+		buf.WriteString(")")
+	} else if !opts.DoNothing {
+		return "", errors.New("UpsertOptions ConflictColumns are required unless DoNothing is set")
+	}
+
+	if opts.DoNothing {
+		buf.WriteString(" do nothing")
+		return buf.String(), nil
+	}
+
+	updateColumns := opts.UpdateColumns
+	if len(updateColumns) == 0 {
+		conflictColumns := make(map[string]struct{}, len(opts.ConflictColumns))
+		for _, cn := range opts.ConflictColumns {
+			conflictColumns[cn] = struct{}{}
+		}
+		for _, cn := range columnNames {
+			if _, ok := conflictColumns[cn]; !ok {
+				updateColumns = append(updateColumns, cn)
+			}
+		}
+	}
+	if len(updateColumns) == 0 {
+		return "", errors.New("no columns to update, use UpsertOptions DoNothing instead")
+	}
+
+	buf.WriteString(" do update set ")
+	for i, cn := range updateColumns {
+		if i != 0 {
+			buf.WriteString(", ")
+		}
+		quoted := Identifier{cn}.Sanitize()
+		fmt.Fprintf(buf, "%s = excluded.%s", quoted, quoted)
+	}
+
+	return buf.String(), nil
+}
+
+func sanitizeColumnList(columnNames []string) string {
+	buf := &bytes.Buffer{}
+	for i, cn := range columnNames {
+		if i != 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(Identifier{cn}.Sanitize())
+	}
+	return buf.String()
+}
+
+// UpsertFrom bulk loads rows into tableName, inserting new rows and updating or
+// skipping rows that conflict with existing ones. The rows are first copied
+// with CopyFromEx into a temporary table created with LIKE tableName INCLUDING
+// DEFAULTS and are then merged with INSERT ... SELECT ... ON CONFLICT.
+//
+// If the connection is not already in a transaction UpsertFrom runs in its own
+// transaction so either all rows are merged or none are. When ON CONFLICT DO
+// UPDATE is used the source must not contain more than one row for the same
+// conflict key.
+//
+// UpsertFrom requires PostgreSQL 9.5 or later.
+func (c *Conn) UpsertFrom(ctx context.Context, tableName Identifier, columnNames []string, rowSrc CopyFromSource, options *UpsertOptions) (result *UpsertResult, err error) {
+	if options == nil {
+		options = &UpsertOptions{}
+	}
+
+	conflictSQL, err := options.conflictSQL(columnNames)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.txStatus == 'I' {
+		var tx *Tx
+		tx, err = c.BeginEx(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			if err != nil {
+				tx.Rollback()
+				return
+			}
+			err = tx.CommitEx(ctx)
+			if err != nil {
+				result = nil
+			}
+		}()
+	}
+
+	stagingTableName := Identifier{upsertStagingTableName}
+
+	_, err = c.ExecEx(ctx, fmt.Sprintf("create temporary table %s (like %s including defaults) on commit drop", stagingTableName.Sanitize(), tableName.Sanitize()), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	copyCount, err := c.CopyFromEx(ctx, stagingTableName, columnNames, rowSrc, options.CopyFromOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	quotedColumnNames := sanitizeColumnList(columnNames)
+	upsertSQL := fmt.Sprintf(`with upserted as (
+	insert into %s (%s)
+	select %s from %s
+	%s
+	returning (xmax = 0) as inserted
+)
+select count(*) filter (where inserted), count(*) filter (where not inserted) from upserted`,
+		tableName.Sanitize(), quotedColumnNames, quotedColumnNames, stagingTableName.Sanitize(), conflictSQL)
+
+	result = &UpsertResult{Copied: int64(copyCount)}
+	err = c.QueryRowEx(ctx, upsertSQL, nil).Scan(&result.Inserted, &result.Updated)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = c.ExecEx(ctx, "drop table "+stagingTableName.Sanitize(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,196 @@
+package pgx_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/ronaldslc/pgx"
+)
+
+func TestConnUpsertFromDoUpdate(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnect(t, *defaultConnConfig)
+	defer closeConn(t, conn)
+
+	mustExec(t, conn, `create temporary table foo(
+		id int4 primary key,
+		name text not null,
+		updated_count int4 not null default 0
+	)`)
+	mustExec(t, conn, "insert into foo(id, name) values (1, 'a'), (2, 'b')")
+
+	inputRows := [][]interface{}{
+		{int32(2), "B"},
+		{int32(3), "c"},
+		{int32(4), "d"},
+	}
+
+	result, err := conn.UpsertFrom(context.Background(), pgx.Identifier{"foo"}, []string{"id", "name"}, pgx.CopyFromRows(inputRows), &pgx.UpsertOptions{
+		ConflictColumns: []string{"id"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error for UpsertFrom: %v", err)
+	}
+
+	expectedResult := &pgx.UpsertResult{Copied: 3, Inserted: 2, Updated: 1}
+	if !reflect.DeepEqual(expectedResult, result) {
+		t.Errorf("Expected UpsertFrom to return %v, but got %v", expectedResult, result)
+	}
+
+	rows, err := conn.Query("select id, name from foo order by id")
+	if err != nil {
+		t.Fatalf("Unexpected error for Query: %v", err)
+	}
+
+	var outputRows [][]interface{}
+	for rows.Next() {
+		row, err := rows.Values()
+		if err != nil {
+			t.Errorf("Unexpected error for rows.Values(): %v", err)
+		}
+		outputRows = append(outputRows, row)
+	}
+
+	if rows.Err() != nil {
+		t.Errorf("Unexpected error for rows.Err(): %v", rows.Err())
+	}
+
+	expectedRows := [][]interface{}{
+		{int32(1), "a"},
+		{int32(2), "B"},
+		{int32(3), "c"},
+		{int32(4), "d"},
+	}
+	if !reflect.DeepEqual(expectedRows, outputRows) {
+		t.Errorf("Expected rows and output rows do not equal: %v -> %v", expectedRows, outputRows)
+	}
+
+	// The staging table must be gone so UpsertFrom can be called again
+	_, err = conn.UpsertFrom(context.Background(), pgx.Identifier{"foo"}, []string{"id", "name"}, pgx.CopyFromRows(inputRows), &pgx.UpsertOptions{
+		ConflictColumns: []string{"id"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error for second UpsertFrom: %v", err)
+	}
+
+	ensureConnValid(t, conn)
+}
+
+func TestConnUpsertFromDoNothing(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnect(t, *defaultConnConfig)
+	defer closeConn(t, conn)
+
+	mustExec(t, conn, `create temporary table foo(
+		id int4 primary key,
+		name text not null
+	)`)
+	mustExec(t, conn, "insert into foo(id, name) values (1, 'a')")
+
+	inputRows := [][]interface{}{
+		{int32(1), "A"},
+		{int32(2), "b"},
+	}
+
+	result, err := conn.UpsertFrom(context.Background(), pgx.Identifier{"foo"}, []string{"id", "name"}, pgx.CopyFromRows(inputRows), &pgx.UpsertOptions{
+		DoNothing: true,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error for UpsertFrom: %v", err)
+	}
+
+	expectedResult := &pgx.UpsertResult{Copied: 2, Inserted: 1, Updated: 0}
+	if !reflect.DeepEqual(expectedResult, result) {
+		t.Errorf("Expected UpsertFrom to return %v, but got %v", expectedResult, result)
+	}
+
+	var name string
+	if err := conn.QueryRow("select name from foo where id=1").Scan(&name); err != nil {
+		t.Fatalf("Unexpected error for QueryRow: %v", err)
+	}
+	if name != "a" {
+		t.Errorf("Expected conflicting row to be left unchanged, but name is %v", name)
+	}
+
+	ensureConnValid(t, conn)
+}
+
+func TestConnUpsertFromFailureRollsBack(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnect(t, *defaultConnConfig)
+	defer closeConn(t, conn)
+
+	mustExec(t, conn, `create temporary table foo(
+		id int4 primary key,
+		name text not null
+	)`)
+
+	// Duplicate conflict keys in the source cannot be merged with DO UPDATE
+	inputRows := [][]interface{}{
+		{int32(1), "a"},
+		{int32(1), "b"},
+	}
+
+	_, err := conn.UpsertFrom(context.Background(), pgx.Identifier{"foo"}, []string{"id", "name"}, pgx.CopyFromRows(inputRows), &pgx.UpsertOptions{
+		ConflictColumns: []string{"id"},
+	})
+	if _, ok := err.(pgx.PgError); !ok {
+		t.Errorf("Expected UpsertFrom return pgx.PgError, but instead it returned: %v", err)
+	}
+
+	var n int64
+	if err := conn.QueryRow("select count(*) from foo").Scan(&n); err != nil {
+		t.Fatalf("Unexpected error for QueryRow: %v", err)
+	}
+	if n != 0 {
+		t.Errorf("Expected 0 rows, but got %d", n)
+	}
+
+	ensureConnValid(t, conn)
+}
+
+func TestTxUpsertFrom(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnect(t, *defaultConnConfig)
+	defer closeConn(t, conn)
+
+	mustExec(t, conn, `create temporary table foo(
+		id int4 primary key,
+		name text not null
+	)`)
+
+	tx, err := conn.Begin()
+	if err != nil {
+		t.Fatalf("conn.Begin failed: %v", err)
+	}
+
+	inputRows := [][]interface{}{{int32(1), "a"}}
+
+	for i := 0; i < 2; i++ {
+		_, err = tx.UpsertFrom(context.Background(), pgx.Identifier{"foo"}, []string{"id", "name"}, pgx.CopyFromRows(inputRows), &pgx.UpsertOptions{
+			ConflictColumns: []string{"id"},
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error for UpsertFrom: %v", err)
+		}
+	}
+
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("tx.Rollback failed: %v", err)
+	}
+
+	var n int64
+	if err := conn.QueryRow("select count(*) from foo").Scan(&n); err != nil {
+		t.Fatalf("Unexpected error for QueryRow: %v", err)
+	}
+	if n != 0 {
+		t.Errorf("Expected 0 rows after rollback, but got %d", n)
+	}
+
+	ensureConnValid(t, conn)
+}